@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/cli"
+)
+
+// call runs a plugin on a remote API server and writes its output as JSON to
+// the standard output. The files of the plugin are downloaded to the output
+// directory if it is set.
+func call(args []string) error {
+	fs := flag.NewFlagSet("call", flag.ExitOnError)
+	fs.Usage = commandUsage(fs, "call <plugin> [flags]",
+		"Runs a plugin on a remote API server and prints its output as JSON.")
+	serverURL := fs.String("server", envOr("BROWSERBRO_SERVER_URL", "http://localhost:10001"),
+		"the URL of the API server (env BROWSERBRO_SERVER_URL)")
+	apiKey := fs.String("api-key", os.Getenv("BROWSERBRO_API_KEY"),
+		"the API key (env BROWSERBRO_API_KEY)")
+	timeout := fs.Duration("timeout", 5*time.Minute, "the maximum time to wait for the plugin")
+	paramsFlags := registerParamsFlags(fs)
+	outDir := fs.String("out", "", "the directory to download the files of the plugin to (default: not downloaded)")
+
+	pluginName, args, err := pluginArg(fs, args)
+	if err != nil {
+		return err
+	}
+	_ = fs.Parse(args)
+	params, err := paramsFlags.Params()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	client := &cli.Client{BaseURL: *serverURL, APIKey: *apiKey}
+	output, err := client.Call(ctx, pluginName, params)
+	if err != nil {
+		return err
+	}
+	if *outDir != "" {
+		if err := os.MkdirAll(*outDir, os.ModePerm); err != nil {
+			return err
+		}
+		for _, file := range cli.Files(output) {
+			path, err := client.Download(ctx, file, *outDir)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(os.Stderr, "saved", path)
+		}
+	}
+	return cli.WriteJSON(os.Stdout, pluginName, output)
+}
+
+func envOr(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
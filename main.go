@@ -1,129 +1,115 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"os"
-	"os/signal"
-	"strconv"
-	"syscall"
+	"strings"
 
+	"github.com/bazuker/browserbro/pkg/config"
 	"github.com/bazuker/browserbro/pkg/fs"
-	localFS "github.com/bazuker/browserbro/pkg/fs/local"
-	"github.com/bazuker/browserbro/pkg/manager"
 	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/plugins/bingsearch"
+	"github.com/bazuker/browserbro/pkg/plugins/crawl"
+	"github.com/bazuker/browserbro/pkg/plugins/duckduckgosearch"
+	"github.com/bazuker/browserbro/pkg/plugins/evaluate"
+	"github.com/bazuker/browserbro/pkg/plugins/extract"
 	"github.com/bazuker/browserbro/pkg/plugins/googlesearch"
+	"github.com/bazuker/browserbro/pkg/plugins/metadata"
+	"github.com/bazuker/browserbro/pkg/plugins/readability"
 	"github.com/bazuker/browserbro/pkg/plugins/screenshot"
-	"github.com/gin-gonic/gin"
+	"github.com/bazuker/browserbro/pkg/plugins/script"
+	"github.com/bazuker/browserbro/pkg/plugins/tables"
+	"github.com/bazuker/browserbro/pkg/plugins/visualdiff"
 	"github.com/go-rod/rod"
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 )
 
-type config struct {
-	ServerAddress         string
-	BrowserServerID       int
-	BrowserServiceURL     string
-	BrowserMonitorEnabled bool
-	UserDataDir           string
-	FileStoreBasePath     string
-}
+const usage = `Usage: browserbro <command> [flags]
 
-func main() {
-	zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	gin.SetMode(gin.ReleaseMode)
+Commands:
+  serve             run the API server (default)
+  run <plugin>      run a plugin against the browser without the API server
+  call <plugin>     run a plugin on a remote API server
 
-	cfg := config{
-		ServerAddress:         ":10001",
-		UserDataDir:           "/tmp/rod/user-data/browserBro_userData",
-		FileStoreBasePath:     "/tmp/browserBro_files",
-		BrowserServerID:       1,
-		BrowserServiceURL:     "ws://localhost:7317",
-		BrowserMonitorEnabled: true,
-	}
-	readConfigFromEnvironment(&cfg)
+Run 'browserbro <command> --help' for the flags of a command.
+`
 
-	fileStore, err := localFS.New(localFS.Config{
-		BasePath: cfg.FileStoreBasePath,
-	})
-	if err != nil {
-		log.Fatal().Err(err).Msg("failed to initialize file store")
-		return
+func main() {
+	// The server is run when no command is given, e.g. browserbro --config config.yaml.
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
 	}
 
-	browser := rod.New()
-	allPlugins := initPlugins(browser, fileStore)
-	m, err := manager.New(manager.Config{
-		ServerAddress:         cfg.ServerAddress,
-		FileStore:             fileStore,
-		Browser:               browser,
-		BrowserUserDataDir:    cfg.UserDataDir,
-		BrowserServerID:       cfg.BrowserServerID,
-		BrowserServiceURL:     cfg.BrowserServiceURL,
-		BrowserMonitorEnabled: cfg.BrowserMonitorEnabled,
-		Plugins:               allPlugins,
-	})
-	if err != nil {
-		log.Fatal().Err(err).Msg("failed to initialize manager")
-		return
+	var err error
+	switch command {
+	case "serve":
+		serve(args)
+	case "run":
+		err = run(args)
+	case "call":
+		err = call(args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command '%s'\n\n%s", command, usage)
+		os.Exit(2)
 	}
-
-	log.Info().Msg("running API server on " + cfg.ServerAddress)
-
-	if err := m.Run(); err != nil {
-		log.Fatal().Err(err).Msg("error running the API server")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
 	}
+}
 
-	quit := make(chan os.Signal, 1)
-	// Signal notification for Interrupt (Ctrl+C) and SIGTERM (Termination signal from the OS)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	if err := m.Stop(); err != nil {
-		log.Fatal().Err(err).Msg("error stopping the API server")
+// commandUsage returns the usage function of the command flags.
+func commandUsage(fs *flag.FlagSet, synopsis, description string) func() {
+	return func() {
+		fmt.Fprintf(fs.Output(), "Usage: browserbro %s\n\n%s\n\nFlags:\n", synopsis, description)
+		fs.PrintDefaults()
 	}
 }
 
-func readConfigFromEnvironment(cfg *config) {
-	serverAddress := os.Getenv("BROWSERBRO_SERVER_ADDRESS")
-	if serverAddress != "" {
-		cfg.ServerAddress = serverAddress
-	}
-	browserServerID := os.Getenv("BROWSERBRO_BROWSER_SERVER_ID")
-	if browserServerID != "" {
-		i, err := strconv.Atoi(browserServerID)
-		if err != nil {
-			log.Fatal().Err(err).
-				Msg("failed to parse 'BROWSERBRO_BROWSER_SERVER_ID' environment variable")
-			return
-		}
-		cfg.BrowserServerID = i
-	}
-	browserServiceURL := os.Getenv("BROWSERBRO_BROWSER_SERVICE_URL")
-	if browserServiceURL != "" {
-		cfg.BrowserServiceURL = browserServiceURL
+// loadConfig loads the configuration and sets the log level. It prints the
+// configuration and returns false if --print-config is set.
+func loadConfig(flags *config.Flags) (config.Config, bool, error) {
+	cfg, err := config.Load(flags, os.Getenv)
+	if err != nil {
+		return config.Config{}, false, err
 	}
-	browserMonitorEnabled := os.Getenv("BROWSERBRO_BROWSER_MONITOR_ENABLED")
-	if browserMonitorEnabled != "" {
-		b, err := strconv.ParseBool(browserMonitorEnabled)
+	if flags.PrintConfig {
+		out, err := cfg.Marshal()
 		if err != nil {
-			log.Fatal().Err(err).
-				Msg("failed to parse 'BROWSERBRO_BROWSER_MONITOR_ENABLED' environment variable")
-			return
+			return config.Config{}, false, err
 		}
-		cfg.BrowserMonitorEnabled = b
-	}
-	userDataDir := os.Getenv("BROWSERBRO_BROWSER_USER_DATA_DIR")
-	if userDataDir != "" {
-		cfg.UserDataDir = userDataDir
-	}
-	fileStoreBasePath := os.Getenv("BROWSERBRO_FILE_STORE_BASE_PATH")
-	if fileStoreBasePath != "" {
-		cfg.FileStoreBasePath = fileStoreBasePath
+		_, _ = os.Stdout.Write(out)
+		return cfg, false, nil
 	}
+	level, _ := zerolog.ParseLevel(cfg.Log.Level)
+	zerolog.SetGlobalLevel(level)
+	return cfg, true, nil
 }
 
-func initPlugins(browser *rod.Browser, fileStore fs.FileStore) []plugins.Plugin {
-	return []plugins.Plugin{
-		googlesearch.New(browser),
-		screenshot.New(browser, fileStore),
+// initPlugins creates the plugins that are not disabled in the configuration.
+func initPlugins(browser *rod.Browser, fileStore fs.FileStore, cfg config.Plugins) []plugins.Plugin {
+	allPlugins := make([]plugins.Plugin, 0)
+	add := func(disabled bool, plugin plugins.Plugin) {
+		if !disabled {
+			allPlugins = append(allPlugins, plugin)
+		}
 	}
+	add(cfg.GoogleSearch.Disabled, googlesearch.New(browser, fileStore, cfg.GoogleSearch))
+	add(cfg.BingSearch.Disabled, bingsearch.New(browser, fileStore, cfg.BingSearch))
+	add(cfg.DuckDuckGoSearch.Disabled, duckduckgosearch.New(browser, fileStore, cfg.DuckDuckGoSearch))
+	add(cfg.Screenshot.Disabled, screenshot.New(browser, fileStore, cfg.Screenshot))
+	add(cfg.Script.Disabled, script.New(browser, fileStore, cfg.Script))
+	add(cfg.Evaluate.Disabled, evaluate.New(browser, cfg.Evaluate))
+	add(cfg.Extract.Disabled, extract.New(browser, cfg.Extract))
+	add(cfg.Readability.Disabled, readability.New(browser, fileStore, cfg.Readability))
+	add(cfg.Metadata.Disabled, metadata.New(browser, cfg.Metadata))
+	add(cfg.Tables.Disabled, tables.New(browser, fileStore, cfg.Tables))
+	add(cfg.VisualDiff.Disabled, visualdiff.New(browser, fileStore, cfg.VisualDiff))
+	// The crawler can run any of the other enabled plugins on the crawled pages.
+	add(cfg.Crawl.Disabled, crawl.New(browser, allPlugins, cfg.Crawl))
+	return allPlugins
 }
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+)
+
+// Client calls the plugins of a remote BrowserBro server.
+type Client struct {
+	// BaseURL is the URL of the server, e.g. http://localhost:10001.
+	BaseURL string
+	// APIKey is sent as the bearer token if set.
+	APIKey string
+	// HTTPClient is used for the requests. Default: http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// APIError is the error envelope returned by the server.
+type APIError struct {
+	Status int
+	helper.ErrorBody
+}
+
+func (e *APIError) Error() string {
+	message := fmt.Sprintf("%s: %s", e.Code, e.Message)
+	if e.RequestID != "" {
+		message += " (request " + e.RequestID + ")"
+	}
+	return message
+}
+
+// Call runs the plugin on the server and returns its output.
+func (c *Client) Call(ctx context.Context, plugin string, params Params) (map[string]any, error) {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/api/v1/plugins/"+url.PathEscape(plugin), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result map[string]map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result[plugin], nil
+}
+
+// Download saves the stored file to the directory and returns its path.
+func (c *Client) Download(ctx context.Context, filename, dir string) (string, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/api/v1/files/"+url.PathEscape(filename), nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	path := filepath.Join(dir, filepath.Base(filename))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		_ = file.Close()
+		return "", fmt.Errorf("failed to download '%s': %w", filename, err)
+	}
+	return path, file.Close()
+}
+
+// do sends the request and returns the response if its status is 2xx.
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	apiErr := &APIError{Status: resp.StatusCode}
+	var envelope helper.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil || envelope.Error.Code == "" {
+		apiErr.Code = "http_error"
+		apiErr.Message = resp.Status
+		return nil, apiErr
+	}
+	apiErr.ErrorBody = envelope.Error
+	return nil, apiErr
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/plugins/screenshot", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"code":"unauthorized","message":"invalid API key","requestId":"abc"}}`))
+			return
+		}
+		var params map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+		assert.Equal(t, map[string]any{"urls": []any{"https://go.dev"}}, params)
+		_, _ = w.Write([]byte(`{"screenshot":{"files":["Xk3_a9Qz.screenshot.png"]}}`))
+	})
+	mux.HandleFunc("GET /api/v1/files/Xk3_a9Qz.screenshot.png", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("png"))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient(t *testing.T) {
+	server := testServer(t)
+	ctx := context.Background()
+
+	t.Run("call and download", func(t *testing.T) {
+		client := &Client{BaseURL: server.URL + "/", APIKey: "secret"}
+		output, err := client.Call(ctx, "screenshot", Params{"urls": []any{"https://go.dev"}})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"files": []any{"Xk3_a9Qz.screenshot.png"}}, output)
+
+		dir := t.TempDir()
+		path, err := client.Download(ctx, "Xk3_a9Qz.screenshot.png", dir)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "Xk3_a9Qz.screenshot.png"), path)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "png", string(data))
+	})
+
+	t.Run("error envelope", func(t *testing.T) {
+		client := &Client{BaseURL: server.URL, APIKey: "wrong"}
+		_, err := client.Call(ctx, "screenshot", Params{})
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusUnauthorized, apiErr.Status)
+		assert.Equal(t, "unauthorized", apiErr.Code)
+		assert.EqualError(t, err, "unauthorized: invalid API key (request abc)")
+	})
+
+	t.Run("not an envelope", func(t *testing.T) {
+		client := &Client{BaseURL: server.URL}
+		_, err := client.Call(ctx, "tables", Params{})
+		assert.EqualError(t, err, "http_error: 502 Bad Gateway")
+	})
+}
+
+func TestFiles(t *testing.T) {
+	var output map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"files": ["Xk3_a9Qz.screenshot.png", "Ab-12_cd.screenshot.png"],
+		"baseline": {"file": "Xk3_a9Qz.screenshot.png"},
+		"file": "QQQQQQQQ.readability.md",
+		"url": "https://go.dev/doc.html",
+		"title": "notes.md",
+		"count": 2
+	}`), &output))
+
+	assert.Equal(t, []string{
+		"Ab-12_cd.screenshot.png",
+		"QQQQQQQQ.readability.md",
+		"Xk3_a9Qz.screenshot.png",
+	}, Files(output))
+}
+
+func TestFiles_pluginOutputs(t *testing.T) {
+	var visualDiff, tables map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"passed": false,
+		"baseline": {"version": 1, "file": "visualdiff.home.v1.png"},
+		"current": "Ab-12_cd.visualdiff.current.png",
+		"diff": "Zz9-Yy8_.visualdiff.diff.png"
+	}`), &visualDiff))
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"tables": [{"index": 0, "headers": ["name"], "records": [{"name": "Go"}], "csv": "QQQQQQQQ.tables.csv"}],
+		"xlsx": "RRRRRRRR.tables.xlsx"
+	}`), &tables))
+
+	assert.Equal(t, []string{"Ab-12_cd.visualdiff.current.png", "Zz9-Yy8_.visualdiff.diff.png"}, Files(visualDiff))
+	assert.Equal(t, []string{"QQQQQQQQ.tables.csv", "RRRRRRRR.tables.xlsx"}, Files(tables))
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteJSON(&buf, "metadata", map[string]any{"title": "Go"}))
+	assert.JSONEq(t, `{"metadata": {"title": "Go"}}`, buf.String())
+}
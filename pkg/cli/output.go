@@ -0,0 +1,22 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+)
+
+// Files returns the names of the stored files referenced by the plugin output
+// decoded from JSON, sorted and without duplicates.
+func Files(output any) []string {
+	return helper.FileNames(output)
+}
+
+// WriteJSON writes the plugin output keyed by the plugin name, the same as
+// the response of the plugin endpoint.
+func WriteJSON(w io.Writer, plugin string, output map[string]any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]any{plugin: output})
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Params are the plugin params given on the command line. It implements
+// flag.Value for the repeatable --param key=value flag.
+type Params map[string]any
+
+func (p Params) String() string {
+	if len(p) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(map[string]any(p))
+	return string(data)
+}
+
+// Set adds a key=value param. The value is decoded as JSON if it is valid
+// JSON, e.g. 2, true or ["a","b"], otherwise it is the string as is.
+func (p Params) Set(kv string) error {
+	key, value, ok := strings.Cut(kv, "=")
+	if !ok || key == "" {
+		return errors.New("must be key=value")
+	}
+	var decoded any
+	if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+		decoded = value
+	}
+	p[key] = decoded
+	return nil
+}
+
+// ReadParams decodes a JSON object of params. A value starting with @ is the
+// path to a file with the JSON object, @- reads it from the standard input.
+func ReadParams(value string) (Params, error) {
+	data := []byte(value)
+	if path, ok := strings.CutPrefix(value, "@"); ok {
+		var err error
+		if path == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read params: %w", err)
+		}
+	}
+	params := make(Params)
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, fmt.Errorf("params must be a JSON object: %w", err)
+	}
+	return params, nil
+}
+
+// Merge returns the params with the other params added over them.
+func (p Params) Merge(other Params) Params {
+	merged := make(Params, len(p)+len(other))
+	for k, v := range p {
+		merged[k] = v
+	}
+	for k, v := range other {
+		merged[k] = v
+	}
+	return merged
+}
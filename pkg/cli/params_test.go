@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParams_Set(t *testing.T) {
+	params := make(Params)
+	require.NoError(t, params.Set("query=golang tutorials"))
+	require.NoError(t, params.Set("pages=2"))
+	require.NoError(t, params.Set("dismissConsent=true"))
+	require.NoError(t, params.Set(`urls=["https://go.dev"]`))
+	require.NoError(t, params.Set("script=return a == b"))
+	require.NoError(t, params.Set("empty="))
+
+	assert.Equal(t, Params{
+		"query":          "golang tutorials",
+		"pages":          2.0,
+		"dismissConsent": true,
+		"urls":           []any{"https://go.dev"},
+		"script":         "return a == b",
+		"empty":          "",
+	}, params)
+
+	assert.EqualError(t, params.Set("query"), "must be key=value")
+	assert.EqualError(t, params.Set("=golang"), "must be key=value")
+}
+
+func TestReadParams(t *testing.T) {
+	t.Run("inline", func(t *testing.T) {
+		params, err := ReadParams(`{"query": "golang", "pages": 2}`)
+		require.NoError(t, err)
+		assert.Equal(t, Params{"query": "golang", "pages": 2.0}, params)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "params.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"urls": ["https://go.dev"]}`), 0o600))
+		params, err := ReadParams("@" + path)
+		require.NoError(t, err)
+		assert.Equal(t, Params{"urls": []any{"https://go.dev"}}, params)
+	})
+
+	t.Run("not an object", func(t *testing.T) {
+		_, err := ReadParams(`["golang"]`)
+		require.ErrorContains(t, err, "params must be a JSON object")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := ReadParams("@" + filepath.Join(t.TempDir(), "missing.json"))
+		require.ErrorContains(t, err, "failed to read params")
+	})
+}
+
+func TestParams_Merge(t *testing.T) {
+	base := Params{"query": "golang", "pages": 1.0}
+	merged := base.Merge(Params{"pages": 3.0})
+	assert.Equal(t, Params{"query": "golang", "pages": 3.0}, merged)
+	// The params are not modified.
+	assert.Equal(t, 1.0, base["pages"])
+}
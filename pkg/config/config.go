@@ -0,0 +1,321 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/manager"
+	"github.com/bazuker/browserbro/pkg/manager/auth"
+	"github.com/bazuker/browserbro/pkg/manager/ratelimit"
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/plugins/crawl"
+	"github.com/bazuker/browserbro/pkg/plugins/evaluate"
+	"github.com/bazuker/browserbro/pkg/plugins/script"
+	"github.com/bazuker/browserbro/pkg/tracing"
+	"github.com/gin-contrib/cors"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of the server. It is read from the config file,
+// the environment variables and the command-line flags.
+type Config struct {
+	Server    Server    `yaml:"server"`
+	Browser   Browser   `yaml:"browser"`
+	FileStore FileStore `yaml:"fileStore"`
+	Auth      Auth      `yaml:"auth"`
+	RateLimit RateLimit `yaml:"rateLimit"`
+	Monitors  Monitors  `yaml:"monitors"`
+	Tracing   Tracing   `yaml:"tracing"`
+	Log       Log       `yaml:"log"`
+	Plugins   Plugins   `yaml:"plugins"`
+}
+
+type Server struct {
+	// Address is the address the API server listens on.
+	Address string `yaml:"address"`
+	// RunPoolSize is the maximum number of concurrent plugin runs on the server.
+	RunPoolSize int `yaml:"runPoolSize"`
+	// ShutdownTimeout is how long the server waits for the in-flight runs on shutdown.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	// BatchMaxItems is the maximum number of items of a batch.
+	BatchMaxItems int `yaml:"batchMaxItems"`
+	// BatchConcurrency is the maximum number of items of a batch run at the same time.
+	BatchConcurrency int `yaml:"batchConcurrency"`
+	// TrustedProxies are the IP addresses or CIDR ranges of the proxies trusted
+	// to set the client IP in the X-Forwarded-For header.
+	TrustedProxies []string `yaml:"trustedProxies,omitempty"`
+	// CORS is the cross-origin resource sharing policy of the API.
+	CORS CORS `yaml:"cors"`
+}
+
+type CORS struct {
+	// AllowOrigins are the origins allowed to call the API, e.g.
+	// https://app.example.com, or * for any origin.
+	AllowOrigins []string `yaml:"allowOrigins"`
+	// AllowHeaders are the request headers allowed in the cross-origin
+	// requests, or * for any header.
+	AllowHeaders []string `yaml:"allowHeaders"`
+}
+
+type Browser struct {
+	ServerID       int    `yaml:"serverId"`
+	ServiceURL     string `yaml:"serviceUrl"`
+	UserDataDir    string `yaml:"userDataDir"`
+	MonitorEnabled bool   `yaml:"monitorEnabled"`
+	MonitorAddress string `yaml:"monitorAddress"`
+}
+
+type FileStore struct {
+	BasePath string `yaml:"basePath"`
+}
+
+type Auth struct {
+	// APIKey is a key with all the scopes.
+	APIKey string `yaml:"apiKey"`
+	// APIKeysFile is the path to a JSON file with the keys and their scopes.
+	APIKeysFile string `yaml:"apiKeysFile"`
+}
+
+type RateLimit struct {
+	Rate          float64 `yaml:"rate"`
+	Burst         int     `yaml:"burst"`
+	MaxConcurrent int     `yaml:"maxConcurrent"`
+}
+
+type Monitors struct {
+	// MinInterval is the shortest allowed interval of page monitors.
+	MinInterval time.Duration `yaml:"minInterval"`
+}
+
+type Tracing struct {
+	Exporter string `yaml:"exporter"`
+}
+
+type Log struct {
+	Level string `yaml:"level"`
+}
+
+// Plugins are the configuration sections of the plugins keyed by the plugin names.
+type Plugins struct {
+	GoogleSearch     plugins.Config  `yaml:"googlesearch"`
+	BingSearch       plugins.Config  `yaml:"bingsearch"`
+	DuckDuckGoSearch plugins.Config  `yaml:"duckduckgosearch"`
+	Screenshot       plugins.Config  `yaml:"screenshot"`
+	Script           script.Config   `yaml:"script"`
+	Evaluate         evaluate.Config `yaml:"evaluate"`
+	Extract          plugins.Config  `yaml:"extract"`
+	Readability      plugins.Config  `yaml:"readability"`
+	Metadata         plugins.Config  `yaml:"metadata"`
+	Tables           plugins.Config  `yaml:"tables"`
+	VisualDiff       plugins.Config  `yaml:"visualdiff"`
+	Crawl            crawl.Config    `yaml:"crawl"`
+}
+
+// Default returns the configuration used when nothing is set.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Address:          ":10001",
+			ShutdownTimeout:  30 * time.Second,
+			BatchMaxItems:    20,
+			BatchConcurrency: 4,
+			CORS: CORS{
+				AllowOrigins: []string{"*"},
+				AllowHeaders: []string{"*"},
+			},
+		},
+		Browser: Browser{
+			ServerID:       1,
+			ServiceURL:     "ws://localhost:7317",
+			UserDataDir:    "/tmp/rod/user-data/browserBro_userData",
+			MonitorEnabled: true,
+			MonitorAddress: ":8889",
+		},
+		FileStore: FileStore{
+			BasePath: "/tmp/browserBro_files",
+		},
+		Monitors: Monitors{
+			MinInterval: time.Minute,
+		},
+		Log: Log{
+			Level: zerolog.LevelInfoValue,
+		},
+	}
+}
+
+// Validate returns all the invalid settings of the configuration.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(key string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	notEmpty := func(key, value string) {
+		if value == "" {
+			check(key, errors.New("must not be empty"))
+		}
+	}
+	notNegative := func(key string, negative bool) {
+		if negative {
+			check(key, errors.New("must not be negative"))
+		}
+	}
+
+	notEmpty("server.address", c.Server.Address)
+	notNegative("server.runPoolSize", c.Server.RunPoolSize < 0)
+	notNegative("server.shutdownTimeout", c.Server.ShutdownTimeout < 0)
+	if c.Server.BatchMaxItems <= 0 {
+		check("server.batchMaxItems", errors.New("must be positive"))
+	}
+	if c.Server.BatchConcurrency <= 0 {
+		check("server.batchConcurrency", errors.New("must be positive"))
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				check("server.trustedProxies", fmt.Errorf("'%s' must be an IP address or a CIDR range", proxy))
+			}
+		}
+	}
+	check("server.cors.allowOrigins", validateOrigins(c.Server.CORS.AllowOrigins))
+	if len(c.Server.CORS.AllowHeaders) == 0 {
+		check("server.cors.allowHeaders", errors.New("must not be empty"))
+	}
+	if c.Browser.ServerID <= 0 {
+		check("browser.serverId", errors.New("must be positive"))
+	}
+	if u, err := url.Parse(c.Browser.ServiceURL); err != nil || u.Host == "" {
+		check("browser.serviceUrl", errors.New("must be a URL, e.g. ws://localhost:7317"))
+	}
+	notEmpty("browser.userDataDir", c.Browser.UserDataDir)
+	if c.Browser.MonitorEnabled {
+		notEmpty("browser.monitorAddress", c.Browser.MonitorAddress)
+	}
+	notEmpty("fileStore.basePath", c.FileStore.BasePath)
+	notNegative("rateLimit.rate", c.RateLimit.Rate < 0)
+	notNegative("rateLimit.burst", c.RateLimit.Burst < 0)
+	notNegative("rateLimit.maxConcurrent", c.RateLimit.MaxConcurrent < 0)
+	notNegative("monitors.minInterval", c.Monitors.MinInterval < 0)
+	switch c.Tracing.Exporter {
+	case "", tracing.ExporterOTLP, tracing.ExporterStdout:
+	default:
+		check("tracing.exporter", fmt.Errorf(
+			"must be '%s' or '%s'", tracing.ExporterOTLP, tracing.ExporterStdout,
+		))
+	}
+	if _, err := zerolog.ParseLevel(c.Log.Level); err != nil || c.Log.Level == "" {
+		check("log.level", errors.New("must be one of trace, debug, info, warn, error"))
+	}
+
+	p := c.Plugins
+	check("plugins.googlesearch", p.GoogleSearch.Validate())
+	check("plugins.bingsearch", p.BingSearch.Validate())
+	check("plugins.duckduckgosearch", p.DuckDuckGoSearch.Validate())
+	check("plugins.screenshot", p.Screenshot.Validate())
+	check("plugins.script", p.Script.Validate())
+	check("plugins.evaluate", p.Evaluate.Validate())
+	check("plugins.extract", p.Extract.Validate())
+	check("plugins.readability", p.Readability.Validate())
+	check("plugins.metadata", p.Metadata.Validate())
+	check("plugins.tables", p.Tables.Validate())
+	check("plugins.visualdiff", p.VisualDiff.Validate())
+	check("plugins.crawl", p.Crawl.Validate())
+
+	return errors.Join(errs...)
+}
+
+// validateOrigins checks that the origins are * alone or http(s) origins
+// without a path, as the browsers send them.
+func validateOrigins(origins []string) error {
+	if len(origins) == 0 {
+		return errors.New("must not be empty")
+	}
+	for _, origin := range origins {
+		if origin == "*" {
+			if len(origins) > 1 {
+				return errors.New("'*' must be the only origin")
+			}
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+			return fmt.Errorf("'%s' must be an origin, e.g. https://app.example.com", origin)
+		}
+	}
+	return nil
+}
+
+// Manager returns the manager configuration. The file store, the browser,
+// the plugins and the API keys are not set as they are created by the caller.
+func (c Config) Manager() manager.Config {
+	corsCfg := cors.DefaultConfig()
+	corsCfg.AllowOrigins = c.Server.CORS.AllowOrigins
+	corsCfg.AllowHeaders = c.Server.CORS.AllowHeaders
+	return manager.Config{
+		ServerAddress:         c.Server.Address,
+		ServerCORS:            &corsCfg,
+		BrowserServerID:       c.Browser.ServerID,
+		BrowserServiceURL:     c.Browser.ServiceURL,
+		BrowserUserDataDir:    c.Browser.UserDataDir,
+		BrowserMonitorEnabled: c.Browser.MonitorEnabled,
+		BrowserMonitorAddress: c.Browser.MonitorAddress,
+		RateLimit: ratelimit.Config{
+			Rate:          c.RateLimit.Rate,
+			Burst:         c.RateLimit.Burst,
+			MaxConcurrent: c.RateLimit.MaxConcurrent,
+		},
+		MaxConcurrentRuns:  c.Server.RunPoolSize,
+		MonitorMinInterval: c.Monitors.MinInterval,
+		ShutdownTimeout:    c.Server.ShutdownTimeout,
+		BatchMaxItems:      c.Server.BatchMaxItems,
+		BatchConcurrency:   c.Server.BatchConcurrency,
+		TrustedProxies:     c.Server.TrustedProxies,
+	}
+}
+
+// Keys returns the keys from the keys file and the single key with all the scopes.
+func (a Auth) Keys() ([]auth.Key, error) {
+	keys := make([]auth.Key, 0)
+	if a.APIKeysFile != "" {
+		fileKeys, err := auth.LoadKeys(a.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, fileKeys...)
+	}
+	if a.APIKey != "" {
+		keys = append(keys, auth.Key{
+			Name:   "default",
+			Key:    a.APIKey,
+			Scopes: []string{auth.ScopeAll},
+		})
+	}
+	return keys, nil
+}
+
+// redacted replaces the secrets in the printed configuration.
+const redacted = "REDACTED"
+
+// Marshal encodes the configuration as YAML with the API key redacted.
+func (c Config) Marshal() ([]byte, error) {
+	if c.Auth.APIKey != "" {
+		c.Auth.APIKey = redacted
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseFlags(t *testing.T, args ...string) *Flags {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := RegisterFlags(fs)
+	require.NoError(t, fs.Parse(args))
+	return flags
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string {
+		return vars[key]
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cfg, err := Load(parseFlags(t), env(nil))
+		require.NoError(t, err)
+		assert.Equal(t, Default(), cfg)
+	})
+
+	t.Run("precedence", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+server:
+  address: ":9000"
+  runPoolSize: 4
+  shutdownTimeout: 10s
+rateLimit:
+  rate: 1
+`)
+		flags := parseFlags(t, "--config", path, "--server-address", ":9200", "--shutdown-timeout", "1m30s")
+		cfg, err := Load(flags, env(map[string]string{
+			"BROWSERBRO_SERVER_ADDRESS":             ":9100",
+			"BROWSERBRO_RUN_POOL_SIZE":              "8",
+			"BROWSERBRO_CLIENT_MAX_CONCURRENT_RUNS": "2",
+			"BROWSERBRO_TRUSTED_PROXIES":            "10.0.0.1, 10.1.0.0/16",
+			"BROWSERBRO_CORS_ALLOW_ORIGINS":         "https://app.example.com,http://localhost:3000",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, ":9200", cfg.Server.Address)
+		assert.Equal(t, 8, cfg.Server.RunPoolSize)
+		assert.Equal(t, 2, cfg.RateLimit.MaxConcurrent)
+		assert.Equal(t, []string{"10.0.0.1", "10.1.0.0/16"}, cfg.Server.TrustedProxies)
+		assert.Equal(t, []string{"https://app.example.com", "http://localhost:3000"}, cfg.Server.CORS.AllowOrigins)
+		assert.Equal(t, []string{"*"}, cfg.Server.CORS.AllowHeaders)
+		assert.Equal(t, 90*time.Second, cfg.Server.ShutdownTimeout)
+		assert.Equal(t, 1.0, cfg.RateLimit.Rate)
+		// Not set anywhere.
+		assert.Equal(t, "ws://localhost:7317", cfg.Browser.ServiceURL)
+	})
+
+	t.Run("config file from environment", func(t *testing.T) {
+		path := writeFile(t, "config.json", `{"browser": {"serverId": 3, "monitorEnabled": false}}`)
+		cfg, err := Load(parseFlags(t), env(map[string]string{EnvConfigFile: path}))
+		require.NoError(t, err)
+		assert.Equal(t, 3, cfg.Browser.ServerID)
+		assert.False(t, cfg.Browser.MonitorEnabled)
+	})
+
+	t.Run("plugin sections", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+plugins:
+  tables:
+    disabled: true
+  screenshot:
+    timeout: 20s
+  evaluate:
+    maxScriptTimeout: 1m
+  crawl:
+    maxPages: 50
+`)
+		cfg, err := Load(parseFlags(t, "--config", path), env(nil))
+		require.NoError(t, err)
+		assert.True(t, cfg.Plugins.Tables.Disabled)
+		assert.Equal(t, 20*time.Second, cfg.Plugins.Screenshot.Timeout)
+		assert.Equal(t, time.Minute, cfg.Plugins.Evaluate.MaxScriptTimeout)
+		assert.Equal(t, 50, cfg.Plugins.Crawl.MaxPages)
+	})
+
+	t.Run("boolean flag", func(t *testing.T) {
+		cfg, err := Load(parseFlags(t, "--browser-monitor-enabled=false"), env(nil))
+		require.NoError(t, err)
+		assert.False(t, cfg.Browser.MonitorEnabled)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", "server:\n  adress: \":9000\"\n")
+		_, err := Load(parseFlags(t, "--config", path), env(nil))
+		require.ErrorContains(t, err, "field adress not found")
+	})
+
+	t.Run("invalid environment variable", func(t *testing.T) {
+		_, err := Load(parseFlags(t), env(map[string]string{"BROWSERBRO_RATE_LIMIT": "fast"}))
+		require.EqualError(t, err, "failed to parse 'BROWSERBRO_RATE_LIMIT' environment variable: must be a number")
+	})
+
+	t.Run("invalid flag", func(t *testing.T) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		RegisterFlags(fs)
+		err := fs.Parse([]string{"--browser-server-id", "one"})
+		require.ErrorContains(t, err, "must be an integer")
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+server:
+  address: ""
+  trustedProxies: [proxy]
+  cors:
+    allowOrigins: ["*", "app.example.com/"]
+    allowHeaders: []
+tracing:
+  exporter: jaeger
+plugins:
+  crawl:
+    maxPages: 10
+    defaultMaxPages: 20
+`)
+		_, err := Load(parseFlags(t, "--config", path), env(nil))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "server.address: must not be empty")
+		assert.ErrorContains(t, err, "server.trustedProxies: 'proxy' must be an IP address or a CIDR range")
+		assert.ErrorContains(t, err, "server.cors.allowOrigins: '*' must be the only origin")
+		assert.ErrorContains(t, err, "server.cors.allowHeaders: must not be empty")
+		assert.ErrorContains(t, err, "tracing.exporter: must be 'otlp' or 'stdout'")
+		assert.ErrorContains(t, err, "plugins.crawl: 'defaultMaxPages' must not exceed 'maxPages'")
+	})
+}
+
+func TestValidateOrigins(t *testing.T) {
+	assert.NoError(t, validateOrigins([]string{"*"}))
+	assert.NoError(t, validateOrigins([]string{"https://app.example.com", "http://localhost:3000"}))
+	assert.EqualError(t, validateOrigins(nil), "must not be empty")
+	assert.EqualError(t, validateOrigins([]string{"app.example.com"}), "'app.example.com' must be an origin, e.g. https://app.example.com")
+	assert.EqualError(t, validateOrigins([]string{"https://app.example.com/"}), "'https://app.example.com/' must be an origin, e.g. https://app.example.com")
+}
+
+func TestConfig_Manager(t *testing.T) {
+	cfg := Default()
+	cfg.RateLimit.MaxConcurrent = 2
+	cfg.Server.RunPoolSize = 5
+	cfg.Server.TrustedProxies = []string{"10.0.0.1"}
+	cfg.Server.CORS.AllowOrigins = []string{"https://app.example.com"}
+
+	m := cfg.Manager()
+	assert.Equal(t, ":10001", m.ServerAddress)
+	assert.Equal(t, ":8889", m.BrowserMonitorAddress)
+	assert.Equal(t, 2, m.RateLimit.MaxConcurrent)
+	assert.Equal(t, 5, m.MaxConcurrentRuns)
+	assert.Equal(t, time.Minute, m.MonitorMinInterval)
+	assert.Equal(t, 30*time.Second, m.ShutdownTimeout)
+	assert.Equal(t, 20, m.BatchMaxItems)
+	assert.Equal(t, 4, m.BatchConcurrency)
+	assert.Equal(t, []string{"10.0.0.1"}, m.TrustedProxies)
+	require.NotNil(t, m.ServerCORS)
+	assert.Equal(t, []string{"https://app.example.com"}, m.ServerCORS.AllowOrigins)
+	assert.Equal(t, []string{"*"}, m.ServerCORS.AllowHeaders)
+	assert.NoError(t, m.ServerCORS.Validate())
+}
+
+func TestConfig_Marshal(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.APIKey = "secret"
+
+	out, err := cfg.Marshal()
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "secret")
+	assert.Contains(t, string(out), "apiKey: REDACTED")
+	assert.Contains(t, string(out), "shutdownTimeout: 30s")
+
+	// The printed configuration can be read back.
+	path := writeFile(t, "config.yaml", string(out))
+	cfg.Auth.APIKey = redacted
+	loaded, err := Load(parseFlags(t, "--config", path), env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, cfg, loaded)
+}
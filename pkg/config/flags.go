@@ -0,0 +1,265 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvConfigFile is the environment variable with the path to the config file.
+const EnvConfigFile = "BROWSERBRO_CONFIG_FILE"
+
+// setting is a configuration value that can be set by an environment
+// variable and a command-line flag.
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	set   func(cfg *Config, value string) error
+	// isBool makes the flag accept no value, e.g. --browser-monitor-enabled.
+	isBool bool
+}
+
+var settings = []setting{
+	stringSetting("server-address", "BROWSERBRO_SERVER_ADDRESS",
+		"the address the API server listens on",
+		func(c *Config) *string { return &c.Server.Address }),
+	intSetting("run-pool-size", "BROWSERBRO_RUN_POOL_SIZE",
+		"the maximum number of concurrent plugin runs on the server",
+		func(c *Config) *int { return &c.Server.RunPoolSize }),
+	durationSetting("shutdown-timeout", "BROWSERBRO_SHUTDOWN_TIMEOUT",
+		"how long to wait for the in-flight runs on shutdown",
+		func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout }),
+	intSetting("batch-max-items", "BROWSERBRO_BATCH_MAX_ITEMS",
+		"the maximum number of items of a batch",
+		func(c *Config) *int { return &c.Server.BatchMaxItems }),
+	intSetting("batch-concurrency", "BROWSERBRO_BATCH_CONCURRENCY",
+		"the maximum number of items of a batch run at the same time",
+		func(c *Config) *int { return &c.Server.BatchConcurrency }),
+	listSetting("trusted-proxies", "BROWSERBRO_TRUSTED_PROXIES",
+		"the comma-separated IP addresses or CIDR ranges of the proxies trusted to set X-Forwarded-For",
+		func(c *Config) *[]string { return &c.Server.TrustedProxies }),
+	listSetting("cors-allow-origins", "BROWSERBRO_CORS_ALLOW_ORIGINS",
+		"the comma-separated origins allowed to call the API, or * for any origin",
+		func(c *Config) *[]string { return &c.Server.CORS.AllowOrigins }),
+	listSetting("cors-allow-headers", "BROWSERBRO_CORS_ALLOW_HEADERS",
+		"the comma-separated request headers allowed in the cross-origin requests, or * for any header",
+		func(c *Config) *[]string { return &c.Server.CORS.AllowHeaders }),
+	intSetting("browser-server-id", "BROWSERBRO_BROWSER_SERVER_ID",
+		"the ID of the browser server",
+		func(c *Config) *int { return &c.Browser.ServerID }),
+	stringSetting("browser-service-url", "BROWSERBRO_BROWSER_SERVICE_URL",
+		"the address of the browser server",
+		func(c *Config) *string { return &c.Browser.ServiceURL }),
+	stringSetting("browser-user-data-dir", "BROWSERBRO_BROWSER_USER_DATA_DIR",
+		"the directory of the browser data on the browser server",
+		func(c *Config) *string { return &c.Browser.UserDataDir }),
+	boolSetting("browser-monitor-enabled", "BROWSERBRO_BROWSER_MONITOR_ENABLED",
+		"enable the browser monitor",
+		func(c *Config) *bool { return &c.Browser.MonitorEnabled }),
+	stringSetting("browser-monitor-address", "BROWSERBRO_BROWSER_MONITOR_ADDRESS",
+		"the address the browser monitor listens on",
+		func(c *Config) *string { return &c.Browser.MonitorAddress }),
+	stringSetting("file-store-base-path", "BROWSERBRO_FILE_STORE_BASE_PATH",
+		"the directory of the stored files",
+		func(c *Config) *string { return &c.FileStore.BasePath }),
+	stringSetting("api-key", "BROWSERBRO_API_KEY",
+		"an API key with all the scopes",
+		func(c *Config) *string { return &c.Auth.APIKey }),
+	stringSetting("api-keys-file", "BROWSERBRO_API_KEYS_FILE",
+		"the path to a JSON file with the API keys and their scopes",
+		func(c *Config) *string { return &c.Auth.APIKeysFile }),
+	floatSetting("rate-limit", "BROWSERBRO_RATE_LIMIT",
+		"the number of requests per second allowed per client",
+		func(c *Config) *float64 { return &c.RateLimit.Rate }),
+	intSetting("rate-limit-burst", "BROWSERBRO_RATE_LIMIT_BURST",
+		"the number of requests a client can make at once",
+		func(c *Config) *int { return &c.RateLimit.Burst }),
+	intSetting("client-max-concurrent-runs", "BROWSERBRO_CLIENT_MAX_CONCURRENT_RUNS",
+		"the maximum number of concurrent plugin runs per client",
+		func(c *Config) *int { return &c.RateLimit.MaxConcurrent }),
+	durationSetting("monitor-min-interval", "BROWSERBRO_MONITOR_MIN_INTERVAL",
+		"the shortest allowed interval of page monitors",
+		func(c *Config) *time.Duration { return &c.Monitors.MinInterval }),
+	stringSetting("tracing-exporter", "BROWSERBRO_TRACING_EXPORTER",
+		"the exporter of the traces, otlp or stdout",
+		func(c *Config) *string { return &c.Tracing.Exporter }),
+	stringSetting("log-level", "BROWSERBRO_LOG_LEVEL",
+		"the minimum level of the logs",
+		func(c *Config) *string { return &c.Log.Level }),
+}
+
+func stringSetting(name, env, usage string, field func(*Config) *string) setting {
+	return setting{flag: name, env: env, usage: usage, set: func(cfg *Config, value string) error {
+		*field(cfg) = value
+		return nil
+	}}
+}
+
+// listSetting accepts comma-separated values, e.g. 10.0.0.1,10.1.0.0/16.
+func listSetting(name, env, usage string, field func(*Config) *[]string) setting {
+	return setting{flag: name, env: env, usage: usage, set: func(cfg *Config, value string) error {
+		values := make([]string, 0)
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		*field(cfg) = values
+		return nil
+	}}
+}
+
+func intSetting(name, env, usage string, field func(*Config) *int) setting {
+	return setting{flag: name, env: env, usage: usage, set: func(cfg *Config, value string) error {
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		*field(cfg) = i
+		return nil
+	}}
+}
+
+func floatSetting(name, env, usage string, field func(*Config) *float64) setting {
+	return setting{flag: name, env: env, usage: usage, set: func(cfg *Config, value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		*field(cfg) = f
+		return nil
+	}}
+}
+
+func boolSetting(name, env, usage string, field func(*Config) *bool) setting {
+	return setting{flag: name, env: env, usage: usage, isBool: true, set: func(cfg *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		*field(cfg) = b
+		return nil
+	}}
+}
+
+// durationSetting accepts a number of seconds, as the environment variables
+// always did, or a Go duration, e.g. 1m30s.
+func durationSetting(name, env, usage string, field func(*Config) *time.Duration) setting {
+	return setting{flag: name, env: env, usage: usage, set: func(cfg *Config, value string) error {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			*field(cfg) = time.Duration(seconds) * time.Second
+			return nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New("must be a number of seconds or a duration, e.g. 1m30s")
+		}
+		*field(cfg) = d
+		return nil
+	}}
+}
+
+// Flags are the command-line flags of the configuration.
+type Flags struct {
+	// ConfigFile is the path to the YAML or JSON config file.
+	ConfigFile string
+	// PrintConfig prints the resulting configuration instead of running the server.
+	PrintConfig bool
+
+	values []flagValue
+}
+
+// flagValue is a flag set on the command line. The flags are applied after
+// the file and the environment variables.
+type flagValue struct {
+	setting setting
+	value   string
+}
+
+// RegisterFlags defines the flags of all the settings on the flag set.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	flags := &Flags{}
+	fs.StringVar(&flags.ConfigFile, "config", "",
+		"the path to the YAML or JSON config file (env "+EnvConfigFile+")")
+	fs.BoolVar(&flags.PrintConfig, "print-config", false,
+		"print the configuration and exit")
+	for _, s := range settings {
+		usage := fmt.Sprintf("%s (env %s)", s.usage, s.env)
+		parse := func(value string) error {
+			// The value is checked when the flags are parsed, but applied later.
+			if err := s.set(&Config{}, value); err != nil {
+				return err
+			}
+			flags.values = append(flags.values, flagValue{setting: s, value: value})
+			return nil
+		}
+		if s.isBool {
+			fs.BoolFunc(s.flag, usage, parse)
+		} else {
+			fs.Func(s.flag, usage, parse)
+		}
+	}
+	return flags
+}
+
+// Load reads the configuration. The flags take precedence over the
+// environment variables, which take precedence over the config file, which
+// takes precedence over the defaults. The configuration is validated.
+func Load(flags *Flags, getenv func(string) string) (Config, error) {
+	cfg := Default()
+
+	path := flags.ConfigFile
+	if path == "" {
+		path = getenv(EnvConfigFile)
+	}
+	if path != "" {
+		if err := readFile(path, &cfg); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, s := range settings {
+		value := getenv(s.env)
+		if value == "" {
+			continue
+		}
+		if err := s.set(&cfg, value); err != nil {
+			return Config{}, fmt.Errorf("failed to parse '%s' environment variable: %w", s.env, err)
+		}
+	}
+
+	for _, v := range flags.values {
+		if err := v.setting.set(&cfg, v.value); err != nil {
+			return Config{}, fmt.Errorf("failed to parse '--%s' flag: %w", v.setting.flag, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// readFile decodes the config file over the configuration. JSON files are
+// read as well, as JSON is a subset of YAML. Unknown keys are rejected.
+func readFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file '%s': %w", path, err)
+	}
+	return nil
+}
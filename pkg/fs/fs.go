@@ -2,6 +2,7 @@ package fs
 
 import (
 	"errors"
+	"strings"
 )
 
 type FileStore interface {
@@ -14,3 +15,18 @@ type FileStore interface {
 var (
 	ErrorFileNotFound = errors.New("file not found")
 )
+
+// statePrefix is the prefix of the keys of the server state. The generated
+// file names never start with a dot.
+const statePrefix = ".state."
+
+// StateKey returns the key of the server state object, e.g. the registered
+// monitors. The state objects are not served by the files endpoints.
+func StateKey(name string) string {
+	return statePrefix + name
+}
+
+// IsStateKey reports whether the key belongs to the server state.
+func IsStateKey(key string) bool {
+	return strings.HasPrefix(key, statePrefix)
+}
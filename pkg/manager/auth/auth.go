@@ -0,0 +1,203 @@
+// Package auth authenticates the API requests with API keys and checks the
+// scopes of the keys.
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ScopeAll grants every scope.
+	ScopeAll = "*"
+	// ScopeFilesRead allows downloading the files.
+	ScopeFilesRead = "files:read"
+	// ScopeFilesDelete allows deleting the files.
+	ScopeFilesDelete = "files:delete"
+	// ScopeMonitors allows managing the page monitors.
+	ScopeMonitors = "monitors"
+	// ScopeSchedules allows managing the schedules. Saving a schedule requires
+	// the scopes of its plugin.
+	ScopeSchedules = "schedules"
+	// ScopePipelines allows managing the saved pipelines. Running a pipeline
+	// requires the scopes of its plugins.
+	ScopePipelines = "pipelines"
+	// ScopeMetrics allows scraping the metrics.
+	ScopeMetrics = "metrics"
+
+	// HeaderAPIKey is the header the API key can be sent in instead of the
+	// Authorization header.
+	HeaderAPIKey = "X-API-Key"
+
+	// minKeyLength is the minimum length of an API key.
+	minKeyLength = 16
+)
+
+var scopePattern = regexp.MustCompile(`^(\*|files:(read|delete|\*)|monitors|schedules|pipelines|metrics|plugins:(\*|[a-zA-Z0-9_-]+))$`)
+
+// PluginScope returns the scope required to run the plugin.
+func PluginScope(name string) string {
+	return "plugins:" + name
+}
+
+// Key is an API key with its scopes.
+type Key struct {
+	// Name identifies the key in the logs. It must be unique.
+	Name string `json:"name"`
+	// Key is the secret sent by the clients.
+	Key string `json:"key"`
+	// Scopes are the permissions of the key.
+	Scopes []string `json:"scopes"`
+}
+
+// LoadKeys reads the keys from a JSON file with an array of keys.
+func LoadKeys(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys file: %w", err)
+	}
+	var keys []Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse API keys file: %w", err)
+	}
+	return keys, nil
+}
+
+// Authenticator authenticates the requests. Authentication is disabled when
+// it has no keys.
+type Authenticator struct {
+	// keys are indexed by the SHA-256 hash of the secret, so that the lookup
+	// time does not depend on the secret.
+	keys map[[sha256.Size]byte]Key
+}
+
+func New(keys []Key) (*Authenticator, error) {
+	a := &Authenticator{keys: make(map[[sha256.Size]byte]Key, len(keys))}
+	names := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key.Name == "" {
+			return nil, errors.New("API key name is required")
+		}
+		if names[key.Name] {
+			return nil, fmt.Errorf("API key name '%s' is not unique", key.Name)
+		}
+		names[key.Name] = true
+		if len(key.Key) < minKeyLength {
+			return nil, fmt.Errorf("API key '%s' must be at least %d characters long", key.Name, minKeyLength)
+		}
+		if len(key.Scopes) == 0 {
+			return nil, fmt.Errorf("API key '%s' has no scopes", key.Name)
+		}
+		for _, scope := range key.Scopes {
+			if !scopePattern.MatchString(scope) {
+				return nil, fmt.Errorf("API key '%s' has invalid scope '%s'", key.Name, scope)
+			}
+		}
+		hash := sha256.Sum256([]byte(key.Key))
+		if _, ok := a.keys[hash]; ok {
+			return nil, fmt.Errorf("API key '%s' is not unique", key.Name)
+		}
+		a.keys[hash] = key
+	}
+	return a, nil
+}
+
+// Enabled reports whether the requests are authenticated.
+func (a *Authenticator) Enabled() bool {
+	return len(a.keys) > 0
+}
+
+// KeySession returns the session of the API key with the name, e.g. for the
+// runs started on behalf of the key outside of a request.
+func (a *Authenticator) KeySession(name string) (helper.SessionData, bool) {
+	for _, key := range a.keys {
+		if key.Name == name {
+			return helper.SessionData{UserID: key.Name, Scopes: key.Scopes}, true
+		}
+	}
+	return helper.SessionData{}, false
+}
+
+// Authenticate is a middleware that rejects the requests without a valid API
+// key with 401 Unauthorized and stores the session of the valid ones.
+func (a *Authenticator) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !a.Enabled() {
+			c.Next()
+			return
+		}
+		secret := requestKey(c.Request)
+		if secret == "" {
+			unauthorized(c, "missing API key")
+			return
+		}
+		key, ok := a.keys[sha256.Sum256([]byte(secret))]
+		if !ok {
+			unauthorized(c, "invalid API key")
+			return
+		}
+		c.Set(helper.ContextSession, helper.SessionData{
+			UserID: key.Name,
+			Scopes: key.Scopes,
+		})
+		c.Next()
+	}
+}
+
+// Require is a middleware that rejects the requests whose API key does not
+// grant the scope with 403 Forbidden. It must run after Authenticate.
+func (a *Authenticator) Require(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !a.Enabled() {
+			c.Next()
+			return
+		}
+		session, ok := Session(c)
+		if !ok {
+			unauthorized(c, "missing API key")
+			return
+		}
+		if !session.Allows(scope) {
+			helper.AbortWithError(c, http.StatusForbidden, helper.CodeForbidden,
+				fmt.Sprintf("API key '%s' is missing the '%s' scope", session.UserID, scope))
+			return
+		}
+		c.Next()
+	}
+}
+
+// Session returns the session of an authenticated request.
+func Session(c *gin.Context) (helper.SessionData, bool) {
+	value, ok := c.Get(helper.ContextSession)
+	if !ok {
+		return helper.SessionData{}, false
+	}
+	session, ok := value.(helper.SessionData)
+	return session, ok
+}
+
+// requestKey reads the API key from the X-API-Key header or the bearer token.
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get(HeaderAPIKey); key != "" {
+		return key
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+func unauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="browserbro"`)
+	helper.AbortWithError(c, http.StatusUnauthorized, helper.CodeUnauthorized, message)
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	readerKey = "reader-0123456789abcdef"
+	adminKey  = "admin-0123456789abcdef"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New([]Key{
+		{Name: "reader", Key: readerKey, Scopes: []string{"plugins:*", "files:read"}},
+		{Name: "admin", Key: adminKey, Scopes: []string{"*"}},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		keys []Key
+		err  string
+	}{
+		{"missing name", []Key{{Key: readerKey, Scopes: []string{"*"}}}, "API key name is required"},
+		{"short key", []Key{{Name: "a", Key: "short", Scopes: []string{"*"}}}, "API key 'a' must be at least 16 characters long"},
+		{"no scopes", []Key{{Name: "a", Key: readerKey}}, "API key 'a' has no scopes"},
+		{"invalid scope", []Key{{Name: "a", Key: readerKey, Scopes: []string{"files:write"}}}, "API key 'a' has invalid scope 'files:write'"},
+		{"duplicate name", []Key{
+			{Name: "a", Key: readerKey, Scopes: []string{"*"}},
+			{Name: "a", Key: adminKey, Scopes: []string{"*"}},
+		}, "API key name 'a' is not unique"},
+		{"duplicate key", []Key{
+			{Name: "a", Key: readerKey, Scopes: []string{"*"}},
+			{Name: "b", Key: readerKey, Scopes: []string{"*"}},
+		}, "API key 'b' is not unique"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.keys)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"name":"ci","key":"`+readerKey+`","scopes":["plugins:googlesearch"]}]`), 0600))
+	keys, err := LoadKeys(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Key{{Name: "ci", Key: readerKey, Scopes: []string{"plugins:googlesearch"}}}, keys)
+
+	_, err = LoadKeys(filepath.Join(t.TempDir(), "dne.json"))
+	assert.ErrorContains(t, err, "failed to read API keys file")
+}
+
+func TestAuthenticator(t *testing.T) {
+	a, err := New([]Key{
+		{Name: "reader", Key: readerKey, Scopes: []string{"plugins:*", "files:read"}},
+	})
+	require.NoError(t, err)
+	require.True(t, a.Enabled())
+
+	r := gin.New()
+	r.Use(a.Authenticate())
+	r.GET("/files", a.Require(ScopeFilesRead), func(c *gin.Context) {
+		session, ok := Session(c)
+		require.True(t, ok)
+		c.String(http.StatusOK, session.UserID)
+	})
+	r.DELETE("/files", a.Require(ScopeFilesDelete), func(c *gin.Context) {})
+
+	request := func(method string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/files", nil)
+		req.Header = header
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	resp := request(http.MethodGet, http.Header{})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.JSONEq(t, `{"error":{"code":"unauthorized","message":"missing API key"}}`, resp.Body.String())
+	assert.NotEmpty(t, resp.Header().Get("WWW-Authenticate"))
+
+	resp = request(http.MethodGet, http.Header{"X-Api-Key": {"wrong-0123456789abcdef"}})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.JSONEq(t, `{"error":{"code":"unauthorized","message":"invalid API key"}}`, resp.Body.String())
+
+	resp = request(http.MethodGet, http.Header{"X-Api-Key": {readerKey}})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "reader", resp.Body.String())
+
+	resp = request(http.MethodGet, http.Header{"Authorization": {"Bearer " + readerKey}})
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	resp = request(http.MethodDelete, http.Header{"Authorization": {"bearer " + readerKey}})
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.JSONEq(
+		t,
+		`{"error":{"code":"forbidden","message":"API key 'reader' is missing the 'files:delete' scope"}}`,
+		resp.Body.String(),
+	)
+}
+
+func TestAuthenticatorDisabled(t *testing.T) {
+	a, err := New(nil)
+	require.NoError(t, err)
+	require.False(t, a.Enabled())
+
+	r := gin.New()
+	r.Use(a.Authenticate())
+	r.DELETE("/files", a.Require(ScopeFilesDelete), func(c *gin.Context) {})
+
+	req := httptest.NewRequest(http.MethodDelete, "/files", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
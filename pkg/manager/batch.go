@@ -0,0 +1,164 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/bazuker/browserbro/pkg/manager/ratelimit"
+	pluginsRegistry "github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/gin-gonic/gin"
+)
+
+type batchConfig struct {
+	maxItems    int
+	concurrency int
+}
+
+// batchRequest is the body of a batch. The body can also be the array of the
+// items alone.
+type batchRequest struct {
+	Items []batchItem `json:"items"`
+	// Sequential runs the items one after another in their order.
+	Sequential bool `json:"sequential"`
+	// Concurrency lowers the number of items run at the same time.
+	Concurrency int `json:"concurrency"`
+}
+
+type batchItem struct {
+	Plugin string         `json:"plugin"`
+	Params map[string]any `json:"params"`
+}
+
+// batchResult is the result of a batch item, either the output of the plugin
+// or the error of the run.
+type batchResult struct {
+	Plugin string            `json:"plugin"`
+	Output map[string]any    `json:"output,omitempty"`
+	Error  *helper.ErrorBody `json:"error,omitempty"`
+}
+
+// batchMiddleware decodes and validates the batch and checks that the API key
+// grants the scopes of all the plugins of the batch.
+func (m *Manager) batchMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, err := decodeBatch(c)
+		if err != nil {
+			helper.AbortWithError(c, http.StatusBadRequest, helper.CodeInvalidParams, err.Error())
+			return
+		}
+		if len(req.Items) == 0 {
+			helper.AbortWithError(c, http.StatusBadRequest, helper.CodeInvalidParams, "'items' must not be empty")
+			return
+		}
+		if len(req.Items) > m.batch.maxItems {
+			helper.AbortWithError(c, http.StatusBadRequest, helper.CodeInvalidParams,
+				fmt.Sprintf("'items' must not contain more than %d items", m.batch.maxItems))
+			return
+		}
+		if req.Concurrency < 0 {
+			helper.AbortWithError(c, http.StatusBadRequest, helper.CodeInvalidParams, "'concurrency' must not be negative")
+			return
+		}
+
+		for i, item := range req.Items {
+			if m.plugin(item.Plugin) == nil {
+				helper.AbortWithError(c, http.StatusBadRequest, helper.CodeInvalidParams,
+					fmt.Sprintf("item %d: plugin '%s' is not loaded", i, item.Plugin))
+				return
+			}
+			if item.Params == nil {
+				req.Items[i].Params = make(map[string]any)
+			}
+			if !m.authorizeRun(c, item.Plugin, req.Items[i].Params) {
+				return
+			}
+		}
+		c.Set(helper.ContextBatch, req)
+		c.Next()
+	}
+}
+
+func decodeBatch(c *gin.Context) (batchRequest, error) {
+	var raw json.RawMessage
+	if err := c.ShouldBindJSON(&raw); err != nil {
+		return batchRequest{}, errors.New("invalid request body")
+	}
+	var req batchRequest
+	var err error
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(raw, &req.Items)
+	} else {
+		err = json.Unmarshal(raw, &req)
+	}
+	if err != nil {
+		return batchRequest{}, errors.New("invalid request body")
+	}
+	return req, nil
+}
+
+// batchCost is the sum of the costs of the batch items.
+func (m *Manager) batchCost(c *gin.Context) int {
+	req := c.MustGet(helper.ContextBatch).(batchRequest)
+	cost := 0
+	for _, item := range req.Items {
+		cost += pluginsRegistry.Cost(m.plugin(item.Plugin), item.Params)
+	}
+	return cost
+}
+
+// runBatch runs the items of the batch and responds with their results in the
+// order of the items. A failed item does not fail the batch.
+func (m *Manager) runBatch(c *gin.Context) {
+	req := c.MustGet(helper.ContextBatch).(batchRequest)
+	ctx := c.Request.Context()
+
+	concurrency := m.batch.concurrency
+	if req.Concurrency > 0 {
+		concurrency = min(concurrency, req.Concurrency)
+	}
+	if req.Sequential {
+		concurrency = 1
+	}
+	// The batch holds one run slot of the client, see ConcurrencyLimit, the
+	// other items run at the same time need the free slots of the client.
+	if concurrency > 1 {
+		release, acquired := m.limiter.AcquireUpTo(ratelimit.ClientID(c), concurrency-1)
+		defer release()
+		concurrency = 1 + acquired
+	}
+
+	results := make([]batchResult, len(req.Items))
+	run := func(i int) {
+		item := req.Items[i]
+		results[i].Plugin = item.Plugin
+		output, err := m.runPlugin(ctx, item.Plugin, item.Params)
+		if err != nil {
+			_, body := pluginErrorBody(err)
+			results[i].Error = &body
+			return
+		}
+		results[i].Output = output
+	}
+
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range req.Items {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			run(i)
+		}()
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+	})
+}
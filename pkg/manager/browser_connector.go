@@ -1,11 +1,22 @@
 package manager
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/launcher"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// reconnectInterval is the delay before the first attempt to reconnect to
+	// the browser, doubled after every failed attempt up to maxReconnectInterval.
+	reconnectInterval    = time.Second
+	maxReconnectInterval = 30 * time.Second
 )
 
 type browserConnector struct {
@@ -14,6 +25,11 @@ type browserConnector struct {
 	serviceURL               string
 	userDataDir              string
 	browserMonitoringEnabled bool
+	browserMonitorAddress    string
+
+	// observeReconnect records the attempts to reconnect, if set.
+	observeReconnect func(err error)
+	closed           atomic.Bool
 }
 
 func newBrowserConnector(
@@ -21,6 +37,7 @@ func newBrowserConnector(
 	serverID int,
 	serviceURL, userDataDir string,
 	browserMonitoringEnabled bool,
+	browserMonitorAddress string,
 ) *browserConnector {
 	return &browserConnector{
 		browser:                  browser,
@@ -28,10 +45,74 @@ func newBrowserConnector(
 		serviceURL:               serviceURL,
 		userDataDir:              userDataDir,
 		browserMonitoringEnabled: browserMonitoringEnabled,
+		browserMonitorAddress:    browserMonitorAddress,
+	}
+}
+
+// ConnectBrowser connects the browser of the configuration to the browser
+// service without running the API server, e.g. to run a plugin from the
+// command line. The returned function closes the browser.
+func ConnectBrowser(cfg Config) (func() error, error) {
+	if cfg.Browser == nil {
+		return nil, errors.New("browser is required")
+	}
+	br := newBrowserConnector(
+		cfg.Browser,
+		cfg.BrowserServerID,
+		cfg.BrowserServiceURL,
+		cfg.BrowserUserDataDir,
+		cfg.BrowserMonitorEnabled,
+		cfg.BrowserMonitorAddress,
+	)
+	if err := br.Connect(); err != nil {
+		return nil, err
+	}
+	return br.Close, nil
+}
+
+// Connect connects to the browser and reconnects whenever the connection is
+// lost, e.g. when the browser service restarts, until the browser is closed.
+func (br *browserConnector) Connect() error {
+	if err := br.connect(true); err != nil {
+		return err
 	}
+	go br.watch()
+	return nil
 }
 
-func (br *browserConnector) Connect() (err error) {
+// watch waits for the connection to be lost and reconnects. The events of the
+// browser stop when its connection closes.
+func (br *browserConnector) watch() {
+	for {
+		for range br.browser.Event() {
+		}
+		if br.closed.Load() {
+			return
+		}
+		log.Warn().Msg("lost the connection to the browser, reconnecting")
+		delay := reconnectInterval
+		for {
+			time.Sleep(delay)
+			if br.closed.Load() {
+				return
+			}
+			err := br.connect(false)
+			if br.observeReconnect != nil {
+				br.observeReconnect(err)
+			}
+			if err == nil {
+				log.Info().Msg("reconnected to the browser")
+				break
+			}
+			log.Error().Err(err).Dur("retry_in", delay).Msg("failed to reconnect to the browser")
+			delay = min(2*delay, maxReconnectInterval)
+		}
+	}
+}
+
+// connect launches a browser on the browser service and connects to it. The
+// browser monitor is only started once, it serves the reconnected browser too.
+func (br *browserConnector) connect(serveMonitor bool) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("failed to launch browser: %v", r)
@@ -59,13 +140,24 @@ func (br *browserConnector) Connect() (err error) {
 	}
 	br.browser.MustIncognito()
 
-	if br.browserMonitoringEnabled {
-		launcher.Open(br.browser.ServeMonitor(":8889"))
+	if serveMonitor && br.browserMonitoringEnabled {
+		launcher.Open(br.browser.ServeMonitor(br.browserMonitorAddress))
 	}
 
 	return err
 }
 
+// Close closes the browser and its pages.
+func (br *browserConnector) Close() (err error) {
+	br.closed.Store(true)
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to close browser: %v", r)
+		}
+	}()
+	return br.browser.Close()
+}
+
 func newManagedLauncher(
 	serverID int,
 	serviceURL, userDataDir string,
@@ -7,22 +7,26 @@ import (
 	"github.com/bazuker/browserbro/pkg/fs"
 	"github.com/bazuker/browserbro/pkg/manager/helper"
 	"github.com/gin-gonic/gin"
-	"github.com/rs/zerolog/log"
 )
 
 func Get(c *gin.Context) {
 	filename := c.Param("filename")
+	// The server state is stored next to the files but is not one of them.
+	if fs.IsStateKey(filename) {
+		helper.AbortWithError(c, http.StatusNotFound, helper.CodeNotFound, "file not found")
+		return
+	}
 	fileStoreContext := c.MustGet(helper.ContextFileStore)
 	fileStore := fileStoreContext.(fs.FileStore)
 
 	data, err := fileStore.GetObject(filename)
 	if err != nil {
 		if errors.Is(err, fs.ErrorFileNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+			helper.AbortWithError(c, http.StatusNotFound, helper.CodeNotFound, "file not found")
 			return
 		}
-		log.Error().Err(err).Msg("failed to get file")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		helper.Logger(c.Request.Context()).Error().Err(err).Msg("failed to get file")
+		helper.AbortWithError(c, http.StatusInternalServerError, helper.CodeInternal, "internal server error")
 		return
 	}
 
@@ -31,19 +35,24 @@ func Get(c *gin.Context) {
 
 func Delete(c *gin.Context) {
 	filename := c.Param("filename")
+	// The server state is stored next to the files but is not one of them.
+	if fs.IsStateKey(filename) {
+		helper.AbortWithError(c, http.StatusNotFound, helper.CodeNotFound, "file not found")
+		return
+	}
 	fileStoreContext := c.MustGet(helper.ContextFileStore)
 	fileStore := fileStoreContext.(fs.FileStore)
 
 	err := fileStore.DeleteObject(filename)
 	if err != nil {
 		if errors.Is(err, fs.ErrorFileNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+			helper.AbortWithError(c, http.StatusNotFound, helper.CodeNotFound, "file not found")
 			return
 		}
-		log.Error().Err(err).Msg("failed to delete file")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		helper.Logger(c.Request.Context()).Error().Err(err).Msg("failed to delete file")
+		helper.AbortWithError(c, http.StatusInternalServerError, helper.CodeInternal, "internal server error")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "file deleted"})
+	c.JSON(http.StatusOK, helper.HTTPMessage{Message: "file deleted"})
 }
@@ -18,6 +18,7 @@ func TestGet(t *testing.T) {
 
 		rw := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(rw)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/files/test.txt", nil)
 		c.Set(helper.ContextFileStore, &mock.FileStore{
 			GetObjectFn: func(filename string) ([]byte, error) {
 				getObjectCalled = true
@@ -38,6 +39,7 @@ func TestGet(t *testing.T) {
 
 		rw := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(rw)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/files/test.txt", nil)
 		c.Set(helper.ContextFileStore, &mock.FileStore{
 			GetObjectFn: func(filename string) ([]byte, error) {
 				getObjectCalled = true
@@ -49,7 +51,7 @@ func TestGet(t *testing.T) {
 		Get(c)
 		assert.True(t, getObjectCalled)
 		assert.Equal(t, http.StatusNotFound, rw.Code)
-		assert.JSONEq(t, `{"error":"file not found"}`, rw.Body.String())
+		assert.JSONEq(t, `{"error":{"code":"not_found","message":"file not found"}}`, rw.Body.String())
 	})
 
 	t.Run("handle internal server error", func(t *testing.T) {
@@ -57,6 +59,7 @@ func TestGet(t *testing.T) {
 
 		rw := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(rw)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/files/test.txt", nil)
 		c.Set(helper.ContextFileStore, &mock.FileStore{
 			GetObjectFn: func(filename string) ([]byte, error) {
 				getObjectCalled = true
@@ -68,6 +71,40 @@ func TestGet(t *testing.T) {
 		Get(c)
 		assert.True(t, getObjectCalled)
 		assert.Equal(t, http.StatusInternalServerError, rw.Code)
-		assert.JSONEq(t, `{"error":"internal server error"}`, rw.Body.String())
+		assert.JSONEq(t, `{"error":{"code":"internal_error","message":"internal server error"}}`, rw.Body.String())
+	})
+
+	t.Run("hide the server state", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rw)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/files/.state.monitors.json", nil)
+		c.Set(helper.ContextFileStore, &mock.FileStore{
+			GetObjectFn: func(filename string) ([]byte, error) {
+				t.Fatal("the state must not be read")
+				return nil, nil
+			},
+		})
+		c.Params = []gin.Param{{Key: "filename", Value: fs.StateKey("monitors.json")}}
+		Get(c)
+		assert.Equal(t, http.StatusNotFound, rw.Code)
+		assert.JSONEq(t, `{"error":{"code":"not_found","message":"file not found"}}`, rw.Body.String())
+	})
+}
+
+func TestDelete(t *testing.T) {
+	t.Run("hide the server state", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rw)
+		c.Request = httptest.NewRequest(http.MethodDelete, "/api/v1/files/.state.monitors.json", nil)
+		c.Set(helper.ContextFileStore, &mock.FileStore{
+			DeleteObjectFn: func(filename string) error {
+				t.Fatal("the state must not be deleted")
+				return nil
+			},
+		})
+		c.Params = []gin.Param{{Key: "filename", Value: fs.StateKey("monitors.json")}}
+		Delete(c)
+		assert.Equal(t, http.StatusNotFound, rw.Code)
+		assert.JSONEq(t, `{"error":{"code":"not_found","message":"file not found"}}`, rw.Body.String())
 	})
 }
@@ -0,0 +1,64 @@
+package helper
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// The machine-readable codes of the API errors.
+const (
+	CodeInvalidParams      = "invalid_params"
+	CodeNotFound           = "not_found"
+	CodeConflict           = "conflict"
+	CodeUnauthorized       = "unauthorized"
+	CodeForbidden          = "forbidden"
+	CodeRateLimited        = "rate_limited"
+	CodeConcurrencyLimited = "concurrency_limited"
+	CodeBlocked            = "blocked"
+	CodePluginTimeout      = "plugin_timeout"
+	CodePluginFailed       = "plugin_failed"
+	CodeBrowserUnavailable = "browser_unavailable"
+	CodeShuttingDown       = "shutting_down"
+	CodeCanceled           = "canceled"
+	CodeInternal           = "internal_error"
+)
+
+// HeaderRequestID is the header with the ID of the request. The ID sent by
+// the client is used if it is valid, otherwise a new one is generated.
+const HeaderRequestID = "X-Request-ID"
+
+// ErrorResponse is the response body of all the failed requests.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId,omitempty"`
+	// Details are the additional information about the error, e.g. the bot
+	// wall of a blocked plugin run.
+	Details any `json:"details,omitempty"`
+}
+
+// AbortWithError aborts the request and responds with the error envelope.
+func AbortWithError(c *gin.Context, status int, code, message string) {
+	AbortWithErrorDetails(c, status, code, message, nil)
+}
+
+// AbortWithErrorDetails aborts the request and responds with the error
+// envelope including the details.
+func AbortWithErrorDetails(c *gin.Context, status int, code, message string, details any) {
+	c.AbortWithStatusJSON(status, ErrorResponse{
+		Error: ErrorBody{
+			Code:      code,
+			Message:   message,
+			RequestID: RequestID(c),
+			Details:   details,
+		},
+	})
+}
+
+// RequestID returns the ID of the request, if any.
+func RequestID(c *gin.Context) string {
+	return c.GetString(ContextRequestID)
+}
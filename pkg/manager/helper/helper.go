@@ -1,21 +1,53 @@
 package helper
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 )
 
 const (
 	ContextFileStore = "fileStore"
+	ContextSession   = "session"
+	ContextParams    = "params"
+	ContextRequestID = "requestID"
+	ContextBatch     = "batch"
+	ContextPipeline  = "pipeline"
+	ContextSchedule  = "schedule"
 )
 
 type HTTPMessage struct {
 	Message string `json:"message"`
 }
 
+// SessionData describes the authenticated client of a request.
 type SessionData struct {
-	UserID      string
-	AccessLevel string
+	// UserID is the name of the API key.
+	UserID string
+	// Scopes are the permissions of the API key, e.g. "plugins:*" or "files:read".
+	Scopes []string
+}
+
+// Allows reports whether the scopes grant the required scope. The "*" scope
+// grants everything and a scope ending with ":*" grants all the scopes with
+// its prefix.
+func (s SessionData) Allows(required string) bool {
+	for _, scope := range s.Scopes {
+		if scope == "*" || scope == required {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(scope, "*"); ok && strings.HasSuffix(prefix, ":") &&
+			strings.HasPrefix(required, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 func GenerateRandomString(length int) string {
@@ -23,3 +55,58 @@ func GenerateRandomString(length int) string {
 	_, _ = rand.Read(b)
 	return base64.URLEncoding.EncodeToString(b)
 }
+
+// fileNamePattern matches the names returned by NewFileName.
+var fileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{8}(\.[a-z0-9]+){2,}$`)
+
+// NewFileName returns a unique name for a file stored by a plugin, made of a
+// random prefix and the dot-separated parts, e.g. "Xk3_a9Qz.screenshot.png"
+// for NewFileName("screenshot", "png"). The parts are lowercase letters and digits.
+func NewFileName(parts ...string) string {
+	return GenerateRandomString(6) + "." + strings.Join(parts, ".")
+}
+
+// IsFileName reports whether the name was returned by NewFileName.
+func IsFileName(name string) bool {
+	return fileNamePattern.MatchString(name)
+}
+
+// FileNames returns the names of the stored files referenced by the plugin
+// output decoded from JSON, sorted and without duplicates.
+func FileNames(output any) []string {
+	seen := make(map[string]bool)
+	var walk func(value any)
+	walk = func(value any) {
+		switch v := value.(type) {
+		case string:
+			if IsFileName(v) {
+				seen[v] = true
+			}
+		case []any:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]any:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(output)
+
+	files := make([]string, 0, len(seen))
+	for file := range seen {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Logger returns the logger of the context, which includes the request ID of
+// the API requests, or the global logger if the context has none.
+func Logger(ctx context.Context) *zerolog.Logger {
+	if logger := zerolog.Ctx(ctx); logger.GetLevel() != zerolog.Disabled {
+		return logger
+	}
+	return &log.Logger
+}
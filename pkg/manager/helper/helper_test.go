@@ -3,6 +3,7 @@ package helper
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -15,3 +16,32 @@ func TestGenerateRandomString(t *testing.T) {
 		values[key] = true
 	}
 }
+
+func TestNewFileName(t *testing.T) {
+	for _, name := range []string{
+		NewFileName("screenshot", "png"),
+		NewFileName("visualdiff", "diff", "png"),
+		NewFileName("tables", "xlsx"),
+	} {
+		assert.True(t, IsFileName(name), name)
+	}
+	assert.False(t, IsFileName("notes.md"))
+	assert.False(t, IsFileName("Xk3_a9Qz.png"))
+	assert.False(t, IsFileName(".state.monitors.json"))
+	assert.False(t, IsFileName("https://go.dev/doc.html"))
+}
+
+func TestSessionData_Allows(t *testing.T) {
+	session := SessionData{Scopes: []string{"plugins:*", "files:read"}}
+	assert.True(t, session.Allows("plugins:screenshot"))
+	assert.True(t, session.Allows("files:read"))
+	assert.False(t, session.Allows("files:delete"))
+	assert.False(t, session.Allows("monitors"))
+
+	session = SessionData{Scopes: []string{"plugins:crawl"}}
+	assert.True(t, session.Allows("plugins:crawl"))
+	assert.False(t, session.Allows("plugins:crawler"))
+
+	assert.True(t, SessionData{Scopes: []string{"*"}}.Allows("files:delete"))
+	assert.False(t, SessionData{}.Allows("files:read"))
+}
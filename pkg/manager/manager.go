@@ -1,20 +1,37 @@
 package manager
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/bazuker/browserbro/pkg/fs"
 	"github.com/bazuker/browserbro/pkg/fs/local"
+	"github.com/bazuker/browserbro/pkg/manager/auth"
 	fsEndpoints "github.com/bazuker/browserbro/pkg/manager/fs"
 	"github.com/bazuker/browserbro/pkg/manager/healthcheck"
 	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/bazuker/browserbro/pkg/manager/metrics"
+	"github.com/bazuker/browserbro/pkg/manager/monitor"
+	"github.com/bazuker/browserbro/pkg/manager/pipeline"
+	"github.com/bazuker/browserbro/pkg/manager/ratelimit"
+	"github.com/bazuker/browserbro/pkg/manager/scheduler"
 	pluginsRegistry "github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/plugins/botwall"
+	"github.com/bazuker/browserbro/pkg/tracing"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Manager is an HTTP server controller.
@@ -24,11 +41,32 @@ type Manager struct {
 	fileStore        fs.FileStore
 	cors             cors.Config
 	plugins          []pluginsRegistry.Plugin
+	monitors         *monitor.Service
+	schedules        *scheduler.Service
+	pipelines        *pipeline.Service
+	auth             *auth.Authenticator
+	limiter          *ratelimit.Limiter
+	metrics          *metrics.Metrics
+	browser          *rod.Browser
 	browserConnector connector
+	browserConnected atomic.Bool
+	// runPool limits the number of concurrent plugin runs. Nil means unlimited.
+	runPool chan struct{}
+
+	shutdownTimeout time.Duration
+	batch           batchConfig
+	// runsMu guards stopping, so that no run is added to runs after Stop starts waiting.
+	runsMu   sync.Mutex
+	stopping bool
+	runs     sync.WaitGroup
+	// runsCtx is canceled to abort the plugin runs left at the shutdown deadline.
+	runsCtx    context.Context
+	cancelRuns context.CancelFunc
 }
 
 type connector interface {
 	Connect() error
+	Close() error
 }
 
 type Config struct {
@@ -50,8 +88,34 @@ type Config struct {
 	BrowserUserDataDir string
 	// BrowserMonitorEnabled enables the browser monitor.
 	BrowserMonitorEnabled bool
+	// BrowserMonitorAddress is the HTTP address of the browser monitor. Default: ":8889".
+	BrowserMonitorAddress string
 	// Plugins is a list of plugins to load.
 	Plugins []pluginsRegistry.Plugin
+	// APIKeys are the keys accepted by the API. Authentication is disabled if empty.
+	APIKeys []auth.Key
+	// RateLimit limits the request rate and the concurrent plugin runs per client.
+	// Nothing is limited by default.
+	RateLimit ratelimit.Config
+	// MaxConcurrentRuns is the size of the run pool shared by all the plugin runs.
+	// The runs over the limit wait for a free slot. Unlimited if zero.
+	MaxConcurrentRuns int
+	// Metrics collects the Prometheus metrics. A new instance is created if nil.
+	Metrics *metrics.Metrics
+	// MonitorMinInterval is the shortest allowed interval of page monitors. Default: 1 minute.
+	MonitorMinInterval time.Duration
+	// ShutdownTimeout is how long Stop waits for the in-flight plugin runs and
+	// monitor checks before canceling them. Default: 30 seconds.
+	ShutdownTimeout time.Duration
+	// BatchMaxItems is the maximum number of items of a batch. Default: 20.
+	BatchMaxItems int
+	// BatchConcurrency is the maximum number of items of a batch run at the
+	// same time. Default: 4.
+	BatchConcurrency int
+	// TrustedProxies are the IP addresses or CIDR ranges of the proxies trusted
+	// to set the client IP in the X-Forwarded-For header. The clients are
+	// identified by the address of the connection if empty.
+	TrustedProxies []string
 }
 
 func DefaultManagerConfig() (Config, error) {
@@ -66,6 +130,7 @@ func DefaultManagerConfig() (Config, error) {
 		BrowserServerID:       1,
 		BrowserServiceURL:     "ws://localhost:7317",
 		BrowserMonitorEnabled: true,
+		BrowserMonitorAddress: ":8889",
 	}, nil
 }
 
@@ -97,47 +162,143 @@ func New(cfg Config) (*Manager, error) {
 	if cfg.BrowserUserDataDir == "" {
 		cfg.BrowserUserDataDir = "/tmp/rod/user-data/browserBro_userData"
 	}
+	if cfg.BrowserMonitorAddress == "" {
+		cfg.BrowserMonitorAddress = ":8889"
+	}
 	if cfg.Router == nil {
 		cfg.Router = gin.New()
 	}
+	if err := cfg.Router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = metrics.New()
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = 30 * time.Second
+	}
+	if cfg.BatchMaxItems <= 0 {
+		cfg.BatchMaxItems = 20
+	}
+	if cfg.BatchConcurrency <= 0 {
+		cfg.BatchConcurrency = 4
+	}
+
+	authenticator, err := auth.New(cfg.APIKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	checker := &poolChecker{checker: monitor.NewBrowserChecker(cfg.Browser)}
+	monitors, err := monitor.New(monitor.Config{
+		FileStore:   cfg.FileStore,
+		Checker:     checker,
+		MinInterval: cfg.MonitorMinInterval,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	connector := newBrowserConnector(
+		cfg.Browser,
+		cfg.BrowserServerID,
+		cfg.BrowserServiceURL,
+		cfg.BrowserUserDataDir,
+		cfg.BrowserMonitorEnabled,
+		cfg.BrowserMonitorAddress,
+	)
+	connector.observeReconnect = cfg.Metrics.ObserveBrowserReconnect
 
-	return &Manager{
+	m := &Manager{
 		router:    cfg.Router,
 		fileStore: cfg.FileStore,
 		cors:      *cfg.ServerCORS,
 		plugins:   cfg.Plugins,
-		browserConnector: newBrowserConnector(
-			cfg.Browser,
-			cfg.BrowserServerID,
-			cfg.BrowserServiceURL,
-			cfg.BrowserUserDataDir,
-			cfg.BrowserMonitorEnabled,
-		),
+		monitors:  monitors,
+		auth:      authenticator,
+		limiter:   ratelimit.New(cfg.RateLimit),
+		metrics:   cfg.Metrics,
+		browser:   cfg.Browser,
 		server: &http.Server{
 			Addr:    cfg.ServerAddress,
 			Handler: cfg.Router,
 		},
-	}, nil
+		shutdownTimeout: cfg.ShutdownTimeout,
+		batch: batchConfig{
+			maxItems:    cfg.BatchMaxItems,
+			concurrency: cfg.BatchConcurrency,
+		},
+		browserConnector: connector,
+	}
+	m.runsCtx, m.cancelRuns = context.WithCancel(context.Background())
+	checker.manager = m
+
+	if cfg.MaxConcurrentRuns > 0 {
+		m.runPool = make(chan struct{}, cfg.MaxConcurrentRuns)
+	}
+	if err := m.metrics.RegisterOpenPages(m.openPages); err != nil {
+		return nil, err
+	}
+
+	pluginNames := make([]string, 0, len(cfg.Plugins))
+	for _, plugin := range cfg.Plugins {
+		pluginNames = append(pluginNames, plugin.Name())
+		// The nested runs are limited, measured and traced like the other runs.
+		if nester, ok := plugin.(pluginsRegistry.Nester); ok {
+			nester.SetRunFunc(m.runPlugin)
+		}
+	}
+	m.schedules, err = scheduler.New(scheduler.Config{
+		FileStore: cfg.FileStore,
+		Run:       m.runScheduled,
+		Plugins:   pluginNames,
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.pipelines, err = pipeline.New(pipeline.Config{
+		FileStore: cfg.FileStore,
+		Run:       m.runPipelineStep,
+		Plugins:   pluginNames,
+		DescribeError: func(err error) helper.ErrorBody {
+			_, body := pluginErrorBody(err)
+			return body
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
 }
 
 func (m *Manager) Run() error {
+	m.router.Use(requestIDMiddleware())
 	m.router.Use(loggerMiddleware(&log.Logger))
-	m.router.Use(gin.Recovery())
+	m.router.Use(m.metrics.Middleware())
+	m.router.Use(tracing.Middleware())
+	m.router.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+		helper.AbortWithError(c, http.StatusInternalServerError, helper.CodeInternal, "internal server error")
+	}))
 	m.router.Use(cors.New(m.cors))
 
 	m.router.NoRoute(func(c *gin.Context) {
-		c.JSON(
-			http.StatusNotFound,
-			helper.HTTPMessage{Message: "route not found"},
-		)
+		helper.AbortWithError(c, http.StatusNotFound, helper.CodeNotFound, "route not found")
 	})
 
+	m.router.GET("/metrics", m.auth.Authenticate(), m.auth.Require(auth.ScopeMetrics), m.metrics.Handler())
+
 	api := m.router.Group("/api")
 	v1 := api.Group("/v1")
 	v1.GET("/health", healthcheck.Healthcheck)
 
-	pluginsGroup := v1.Group("/plugins")
-	pluginsGroup.GET("", func(c *gin.Context) {
+	if !m.auth.Enabled() {
+		log.Warn().Msg("no API keys configured, the API is not authenticated")
+	}
+	protected := v1.Group("", m.auth.Authenticate())
+
+	pluginsGroup := protected.Group("/plugins")
+	pluginsGroup.GET("", m.limiter.RateLimit(nil), func(c *gin.Context) {
 		pluginNames := make([]string, 0, len(m.plugins))
 		for _, plugin := range m.plugins {
 			pluginNames = append(pluginNames, plugin.Name())
@@ -150,14 +311,40 @@ func (m *Manager) Run() error {
 		return err
 	}
 
-	fsGroup := v1.Group("/files")
+	protected.POST(
+		"/batch",
+		m.batchMiddleware(),
+		m.limiter.RateLimit(m.batchCost),
+		m.limiter.ConcurrencyLimit(),
+		m.runBatch,
+	)
+
+	fsGroup := protected.Group("/files", m.limiter.RateLimit(nil))
 	fsGroup.Use(contextMiddleware(m.fileStore))
-	fsGroup.GET("/:filename", fsEndpoints.Get)
-	fsGroup.DELETE("/:filename", fsEndpoints.Delete)
+	fsGroup.GET("/:filename", m.auth.Require(auth.ScopeFilesRead), m.metrics.ServedBytes(), fsEndpoints.Get)
+	fsGroup.DELETE("/:filename", m.auth.Require(auth.ScopeFilesDelete), fsEndpoints.Delete)
+
+	monitor.Register(
+		protected.Group("/monitors", m.auth.Require(auth.ScopeMonitors), m.limiter.RateLimit(nil)),
+		m.monitors,
+		monitor.Middlewares{Check: []gin.HandlerFunc{m.limiter.ConcurrencyLimit()}},
+	)
+	scheduler.Register(
+		protected.Group("/schedules", m.auth.Require(auth.ScopeSchedules), m.limiter.RateLimit(nil)),
+		m.schedules,
+		scheduler.Middlewares{Save: []gin.HandlerFunc{m.authorizeSchedule()}},
+	)
+	pipeline.Register(protected.Group("/pipelines"), m.pipelines, pipeline.Middlewares{
+		Manage: []gin.HandlerFunc{m.auth.Require(auth.ScopePipelines), m.limiter.RateLimit(nil)},
+		Run:    []gin.HandlerFunc{m.pipelineClient(), m.limiter.ConcurrencyLimit()},
+	})
 
 	if err := m.browserConnector.Connect(); err != nil {
 		return err
 	}
+	m.browserConnected.Store(true)
+	m.monitors.Start()
+	m.schedules.Start()
 
 	go func() {
 		if err := m.server.ListenAndServe(); err != nil &&
@@ -169,8 +356,56 @@ func (m *Manager) Run() error {
 	return nil
 }
 
+// Stop gracefully shuts down the server. It stops accepting requests and
+// scheduling new runs and checks, then waits for the in-flight ones and the
+// monitor webhooks up to the shutdown timeout. The runs left are canceled.
+// Finally, the browser is closed along with its pages.
 func (m *Manager) Stop() error {
-	return m.server.Close()
+	m.runsMu.Lock()
+	m.stopping = true
+	m.runsMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
+	defer cancel()
+
+	var services sync.WaitGroup
+	services.Add(2)
+	go func() {
+		defer services.Done()
+		m.monitors.Stop(ctx)
+	}()
+	go func() {
+		defer services.Done()
+		m.schedules.Stop(ctx)
+	}()
+
+	serverErr := m.server.Shutdown(ctx)
+
+	runsDone := make(chan struct{})
+	go func() {
+		m.runs.Wait()
+		close(runsDone)
+	}()
+	select {
+	case <-runsDone:
+	case <-ctx.Done():
+		log.Warn().Dur("timeout", m.shutdownTimeout).Msg("canceling the plugin runs in progress")
+		m.cancelRuns()
+		<-runsDone
+	}
+	services.Wait()
+	m.cancelRuns()
+
+	if serverErr != nil {
+		// The requests did not complete in time, drop their connections.
+		serverErr = m.server.Close()
+	}
+	if m.browserConnected.Swap(false) {
+		if err := m.browserConnector.Close(); err != nil {
+			log.Error().Err(err).Msg("failed to close the browser")
+		}
+	}
+	return serverErr
 }
 
 func (m *Manager) loadPlugins(pluginsGroup *gin.RouterGroup) error {
@@ -178,30 +413,255 @@ func (m *Manager) loadPlugins(pluginsGroup *gin.RouterGroup) error {
 
 	for _, plugin := range m.plugins {
 		name := plugin.Name()
-		pluginsGroup.POST(fmt.Sprintf("/%s", name), func(c *gin.Context) {
-			var params map[string]any
-			if err := c.ShouldBindJSON(&params); err != nil {
-				c.JSON(
-					http.StatusBadRequest,
-					helper.HTTPMessage{Message: "invalid request body"},
-				)
-				return
-			}
-			results, err := plugin.Run(params)
-			if err != nil {
-				c.JSON(
-					http.StatusInternalServerError,
-					helper.HTTPMessage{Message: err.Error()},
-				)
-				return
-			}
-			c.JSON(http.StatusOK, gin.H{
-				name: results,
-			})
-		})
+		pluginsGroup.POST(
+			fmt.Sprintf("/%s", name),
+			m.auth.Require(auth.PluginScope(name)),
+			paramsMiddleware(),
+			func(c *gin.Context) {
+				if m.authorizeRun(c, name, c.MustGet(helper.ContextParams).(map[string]any)) {
+					c.Next()
+				}
+			},
+			m.limiter.RateLimit(func(c *gin.Context) int {
+				return pluginsRegistry.Cost(plugin, c.MustGet(helper.ContextParams).(map[string]any))
+			}),
+			m.limiter.ConcurrencyLimit(),
+			func(c *gin.Context) {
+				params := c.MustGet(helper.ContextParams).(map[string]any)
+				results, err := m.runPlugin(c.Request.Context(), name, params)
+				if err != nil {
+					pluginError(c, err)
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{
+					name: results,
+				})
+			},
+		)
 
 		log.Info().Str("name", name).Msg("plugin loaded")
 	}
 
 	return nil
 }
+
+// plugin returns the loaded plugin with the name, if any.
+func (m *Manager) plugin(name string) pluginsRegistry.Plugin {
+	for _, p := range m.plugins {
+		if p.Name() == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// authorizeRun checks that the API key grants the scope of the plugin and the
+// scopes of the plugins it runs with the params. Otherwise, it aborts the
+// request and returns false.
+func (m *Manager) authorizeRun(c *gin.Context, name string, params map[string]any) bool {
+	session, _ := auth.Session(c)
+	if err := m.checkScopes(session, name, params); err != nil {
+		helper.AbortWithError(c, http.StatusForbidden, helper.CodeForbidden, err.Error())
+		return false
+	}
+	return true
+}
+
+// checkScopes returns a *scopeError if the session does not grant the scope of
+// the plugin, the scopes of the plugins it runs with the params or the other
+// scopes the run requires.
+func (m *Manager) checkScopes(session helper.SessionData, name string, params map[string]any) error {
+	if !m.auth.Enabled() {
+		return nil
+	}
+	plugin := m.plugin(name)
+	scopes := []string{auth.PluginScope(name)}
+	for _, nested := range pluginsRegistry.Nested(plugin, params) {
+		scopes = append(scopes, auth.PluginScope(nested))
+	}
+	scopes = append(scopes, pluginsRegistry.Scopes(plugin, params)...)
+	for _, scope := range scopes {
+		if !session.Allows(scope) {
+			return &scopeError{userID: session.UserID, scope: scope}
+		}
+	}
+	return nil
+}
+
+// scopeError is returned for the plugin runs the API key has no scope for.
+type scopeError struct {
+	userID string
+	scope  string
+}
+
+func (e *scopeError) Error() string {
+	return fmt.Sprintf("API key '%s' is missing the '%s' scope", e.userID, e.scope)
+}
+
+// runSlotKey marks the contexts of the runs that hold a slot of the run pool.
+type runSlotKey struct{}
+
+// runPlugin runs the plugin with the params. It is the code path shared by the
+// plugin endpoints and the scheduled runs.
+func (m *Manager) runPlugin(ctx context.Context, name string, params map[string]any) (output map[string]any, err error) {
+	plugin := m.plugin(name)
+	if plugin == nil {
+		return nil, fmt.Errorf("plugin '%s' is not loaded", name)
+	}
+	err = m.runInPool(ctx, name, "plugin "+name, []attribute.KeyValue{
+		attribute.String("plugin.name", name),
+	}, func(ctx context.Context) (err error) {
+		// The scheduled runs are not covered by the recovery middleware.
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("plugin '%s' panicked: %v", name, r)
+			}
+		}()
+		output, err = plugin.Run(ctx, params)
+		return err
+	})
+	return output, err
+}
+
+// runInPool runs a plugin or a monitor check in the browser: the run takes a
+// slot of the run pool, is waited for and canceled on shutdown, traced in the
+// span and measured under the name.
+func (m *Manager) runInPool(
+	ctx context.Context,
+	name, spanName string,
+	attrs []attribute.KeyValue,
+	run func(ctx context.Context) error,
+) (err error) {
+	if !m.browserConnected.Load() {
+		return errBrowserUnavailable
+	}
+
+	m.runsMu.Lock()
+	if m.stopping {
+		m.runsMu.Unlock()
+		return errShuttingDown
+	}
+	m.runs.Add(1)
+	m.runsMu.Unlock()
+	defer m.runs.Done()
+
+	// The run is canceled by the client or at the shutdown deadline, whichever comes first.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(m.runsCtx, cancel)()
+
+	// The nested runs share the slot of the run that started them, a nested run
+	// waiting for a free slot could otherwise wait for its own parent.
+	if m.runPool != nil && ctx.Value(runSlotKey{}) == nil {
+		waitStart := time.Now()
+		m.runPool <- struct{}{}
+		m.metrics.ObserveRunPoolWait(time.Since(waitStart))
+		defer func() { <-m.runPool }()
+		ctx = context.WithValue(ctx, runSlotKey{}, true)
+	}
+
+	ctx, span := tracing.Start(ctx, spanName, trace.WithAttributes(attrs...))
+	start := time.Now()
+	defer func() {
+		tracing.End(span, err)
+		m.metrics.ObservePluginRun(name, time.Since(start), err)
+		logger := helper.Logger(ctx)
+		event := logger.Debug()
+		if err != nil {
+			event = logger.Warn().Err(err)
+		}
+		event.Str("plugin", name).Dur("duration", time.Since(start)).Msg("plugin run completed")
+	}()
+
+	return run(ctx)
+}
+
+// openPages returns the number of open browser pages.
+func (m *Manager) openPages() (count float64) {
+	if !m.browserConnected.Load() {
+		return 0
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			count = 0
+		}
+	}()
+	targets, err := proto.TargetGetTargets{}.Call(m.browser)
+	if err != nil {
+		return 0
+	}
+	for _, target := range targets.TargetInfos {
+		if target.Type == proto.TargetTargetInfoTypePage {
+			count++
+		}
+	}
+	return count
+}
+
+// statusClientClosedRequest is the non-standard status of the requests whose
+// plugin run was canceled, mostly read by the logs as the client is gone.
+const statusClientClosedRequest = 499
+
+var (
+	// errBrowserUnavailable is returned by the plugin runs while the browser is not connected.
+	errBrowserUnavailable = errors.New("browser is unavailable")
+	// errShuttingDown is returned by the plugin runs started after Stop.
+	errShuttingDown = errors.New("server is shutting down")
+)
+
+// blockedDetails are the details of the error of a plugin run that hit a bot wall.
+type blockedDetails struct {
+	Blocker    string `json:"blocker"`
+	URL        string `json:"url"`
+	Screenshot string `json:"screenshot,omitempty"`
+}
+
+// pluginError responds with the error of a failed plugin run.
+func pluginError(c *gin.Context, err error) {
+	status, body := pluginErrorBody(err)
+	helper.AbortWithErrorDetails(c, status, body.Code, body.Message, body.Details)
+}
+
+// pluginErrorBody returns the status and the error of a failed plugin run.
+func pluginErrorBody(err error) (int, helper.ErrorBody) {
+	var (
+		paramErr *pluginsRegistry.ParamError
+		blocked  *botwall.BlockedError
+		scopeErr *scopeError
+		limitErr *ratelimit.Error
+	)
+	switch {
+	case errors.As(err, &paramErr):
+		return http.StatusBadRequest, helper.ErrorBody{Code: helper.CodeInvalidParams, Message: err.Error()}
+	case errors.As(err, &scopeErr):
+		return http.StatusForbidden, helper.ErrorBody{Code: helper.CodeForbidden, Message: err.Error()}
+	case errors.As(err, &limitErr):
+		return limitErr.Status, helper.ErrorBody{Code: limitErr.Code, Message: err.Error()}
+	case errors.As(err, &blocked):
+		return http.StatusBadGateway, helper.ErrorBody{
+			Code:    helper.CodeBlocked,
+			Message: err.Error(),
+			Details: blockedDetails{
+				Blocker:    blocked.Blocker,
+				URL:        blocked.URL,
+				Screenshot: blocked.Screenshot,
+			},
+		}
+	case errors.Is(err, errShuttingDown):
+		return http.StatusServiceUnavailable, helper.ErrorBody{Code: helper.CodeShuttingDown, Message: err.Error()}
+	case browserUnavailable(err):
+		return http.StatusServiceUnavailable, helper.ErrorBody{Code: helper.CodeBrowserUnavailable, Message: err.Error()}
+	case metrics.RunResult(err) == metrics.ResultTimeout:
+		return http.StatusGatewayTimeout, helper.ErrorBody{Code: helper.CodePluginTimeout, Message: err.Error()}
+	case metrics.RunResult(err) == metrics.ResultCanceled:
+		return statusClientClosedRequest, helper.ErrorBody{Code: helper.CodeCanceled, Message: err.Error()}
+	default:
+		return http.StatusInternalServerError, helper.ErrorBody{Code: helper.CodePluginFailed, Message: err.Error()}
+	}
+}
+
+// browserUnavailable reports whether the plugin run failed because the
+// browser is not connected or the connection to it was closed.
+func browserUnavailable(err error) bool {
+	return errors.Is(err, errBrowserUnavailable) || errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed)
+}
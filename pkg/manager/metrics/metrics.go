@@ -0,0 +1,204 @@
+// Package metrics collects the Prometheus metrics of the server.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/fs"
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "browserbro"
+
+const (
+	// ResultSuccess is the result of a successful plugin run.
+	ResultSuccess = "success"
+	// ResultFailure is the result of a failed plugin run.
+	ResultFailure = "failure"
+	// ResultTimeout is the result of a plugin run that ran out of time.
+	ResultTimeout = "timeout"
+	// ResultCanceled is the result of a plugin run canceled by the client or
+	// at the shutdown deadline.
+	ResultCanceled = "canceled"
+)
+
+// Metrics holds the collectors in a registry of its own.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests      *prometheus.CounterVec
+	httpDuration      *prometheus.HistogramVec
+	pluginRuns        *prometheus.CounterVec
+	pluginDuration    *prometheus.HistogramVec
+	runPoolWait       prometheus.Histogram
+	fileBytesWritten  prometheus.Counter
+	fileBytesServed   prometheus.Counter
+	browserReconnects *prometheus.CounterVec
+}
+
+// New creates the collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of HTTP requests by route, method and status code.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+		}, []string{"route", "method", "status"}),
+		pluginRuns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "plugin_runs_total",
+			Help:      "Number of plugin runs by plugin and result: success, failure or timeout.",
+		}, []string{"plugin", "result"}),
+		pluginDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "plugin_run_duration_seconds",
+			Help:      "Duration of plugin runs by plugin.",
+			Buckets:   []float64{0.5, 1, 2.5, 5, 10, 15, 30, 60, 120, 300},
+		}, []string{"plugin"}),
+		runPoolWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "run_pool_wait_seconds",
+			Help:      "Time plugin runs waited for a free slot in the run pool.",
+			Buckets:   []float64{0.001, 0.01, 0.1, 0.5, 1, 5, 10, 30, 60},
+		}),
+		fileBytesWritten: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "file_store_written_bytes_total",
+			Help:      "Number of bytes written to the file store.",
+		}),
+		fileBytesServed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "files_served_bytes_total",
+			Help:      "Number of bytes served by the Files API.",
+		}),
+		browserReconnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "browser_reconnects_total",
+			Help:      "Number of attempts to reconnect to the browser after the connection was lost, by result: success or failure.",
+		}, []string{"result"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.pluginRuns,
+		m.pluginDuration,
+		m.runPoolWait,
+		m.fileBytesWritten,
+		m.fileBytesServed,
+		m.browserReconnects,
+	)
+	return m
+}
+
+// RegisterOpenPages registers the gauge of the open browser pages. The
+// function is called when the metrics are scraped.
+func (m *Metrics) RegisterOpenPages(openPages func() float64) error {
+	return m.registry.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "browser_open_pages",
+		Help:      "Number of open browser pages.",
+	}, openPages))
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
+
+// Middleware counts the requests and measures their duration. The requests
+// that match no route are reported with the "unmatched" route to keep the
+// number of labels bounded.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		m.httpRequests.WithLabelValues(route, c.Request.Method, status).Inc()
+		m.httpDuration.WithLabelValues(route, c.Request.Method, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ServedBytes is a middleware that counts the bytes of the served files.
+func (m *Metrics) ServedBytes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if c.Writer.Status() == http.StatusOK && c.Writer.Size() > 0 {
+			m.fileBytesServed.Add(float64(c.Writer.Size()))
+		}
+	}
+}
+
+// ObservePluginRun records the duration and the result of a plugin run.
+func (m *Metrics) ObservePluginRun(plugin string, duration time.Duration, err error) {
+	m.pluginDuration.WithLabelValues(plugin).Observe(duration.Seconds())
+	m.pluginRuns.WithLabelValues(plugin, RunResult(err)).Inc()
+}
+
+// ObserveRunPoolWait records the time a plugin run waited for a slot.
+func (m *Metrics) ObserveRunPoolWait(duration time.Duration) {
+	m.runPoolWait.Observe(duration.Seconds())
+}
+
+// ObserveBrowserReconnect records an attempt to reconnect to the browser.
+func (m *Metrics) ObserveBrowserReconnect(err error) {
+	if err != nil {
+		m.browserReconnects.WithLabelValues(ResultFailure).Inc()
+		return
+	}
+	m.browserReconnects.WithLabelValues(ResultSuccess).Inc()
+}
+
+// RunResult classifies the error of a plugin run. The plugins cancel the page
+// context with plugins.ErrTimeLimit when they run out of time.
+func RunResult(err error) string {
+	switch {
+	case err == nil:
+		return ResultSuccess
+	case errors.Is(err, plugins.ErrTimeLimit) || errors.Is(err, context.DeadlineExceeded):
+		return ResultTimeout
+	case errors.Is(err, context.Canceled):
+		return ResultCanceled
+	default:
+		return ResultFailure
+	}
+}
+
+// FileStore wraps the file store to count the written bytes.
+func (m *Metrics) FileStore(store fs.FileStore) fs.FileStore {
+	return &fileStore{FileStore: store, written: m.fileBytesWritten}
+}
+
+type fileStore struct {
+	fs.FileStore
+	written prometheus.Counter
+}
+
+func (s *fileStore) PutObject(object []byte, key string) error {
+	if err := s.FileStore.PutObject(object, key); err != nil {
+		return err
+	}
+	s.written.Add(float64(len(object)))
+	return nil
+}
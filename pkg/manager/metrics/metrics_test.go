@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/fs/mock"
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunResult(t *testing.T) {
+	assert.Equal(t, ResultSuccess, RunResult(nil))
+	assert.Equal(t, ResultFailure, RunResult(errors.New("failed")))
+	assert.Equal(t, ResultTimeout, RunResult(fmt.Errorf("%w: failed to navigate: %w", plugins.ErrTimeLimit, context.Canceled)))
+	assert.Equal(t, ResultTimeout, RunResult(context.DeadlineExceeded))
+	assert.Equal(t, ResultCanceled, RunResult(fmt.Errorf("failed to navigate: %w", context.Canceled)))
+}
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	require.NoError(t, m.RegisterOpenPages(func() float64 { return 3 }))
+
+	r := gin.New()
+	r.Use(m.Middleware())
+	r.GET("/metrics", m.Handler())
+	r.GET("/files/:filename", m.ServedBytes(), func(c *gin.Context) {
+		c.String(http.StatusOK, "hello")
+	})
+
+	request := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	request("/files/a.txt")
+	request("/files/b.txt")
+	request("/dne")
+
+	m.ObservePluginRun("screenshot", 2*time.Second, nil)
+	m.ObservePluginRun("screenshot", 15*time.Second, plugins.ErrTimeLimit)
+	m.ObserveRunPoolWait(time.Second)
+	m.ObserveBrowserReconnect(errors.New("connection refused"))
+	m.ObserveBrowserReconnect(nil)
+
+	store := m.FileStore(&mock.FileStore{})
+	require.NoError(t, store.PutObject([]byte("1234"), "a.txt"))
+	failing := m.FileStore(&mock.FileStore{
+		PutObjectFn: func(object []byte, key string) error { return errors.New("disk full") },
+	})
+	require.Error(t, failing.PutObject([]byte("1234"), "b.txt"))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("/files/:filename", "GET", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("unmatched", "GET", "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.pluginRuns.WithLabelValues("screenshot", ResultSuccess)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.pluginRuns.WithLabelValues("screenshot", ResultTimeout)))
+	assert.Equal(t, 10.0, testutil.ToFloat64(m.fileBytesServed))
+	assert.Equal(t, 4.0, testutil.ToFloat64(m.fileBytesWritten))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.browserReconnects.WithLabelValues(ResultSuccess)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.browserReconnects.WithLabelValues(ResultFailure)))
+
+	body := request("/metrics").Body.String()
+	for _, line := range []string{
+		`browserbro_browser_open_pages 3`,
+		`browserbro_plugin_run_duration_seconds_count{plugin="screenshot"} 2`,
+		`browserbro_run_pool_wait_seconds_count 1`,
+		`go_goroutines`,
+	} {
+		assert.True(t, strings.Contains(body, line), line)
+	}
+}
@@ -1,12 +1,15 @@
 package manager
 
 import (
+	"net/http"
+	"regexp"
 	"time"
 
 	"github.com/bazuker/browserbro/pkg/fs"
 	"github.com/bazuker/browserbro/pkg/manager/helper"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 )
 
 func loggerMiddleware(logger *zerolog.Logger) gin.HandlerFunc {
@@ -42,7 +45,8 @@ func loggerMiddleware(logger *zerolog.Logger) gin.HandlerFunc {
 			logEvent = logger.Info()
 		}
 
-		logEvent.Str("ip", param.ClientIP).
+		logEvent.Str("request_id", helper.RequestID(c)).
+			Str("ip", param.ClientIP).
 			Str("method", param.Method).
 			Int("status_code", param.StatusCode).
 			Str("path", param.Path).
@@ -51,6 +55,27 @@ func loggerMiddleware(logger *zerolog.Logger) gin.HandlerFunc {
 	}
 }
 
+// requestIDPattern matches the request IDs accepted from the clients.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:/+=-]{1,128}$`)
+
+// requestIDMiddleware assigns an ID to the request, honoring the one sent by
+// the client, returns it in the response headers and adds it to the logger of
+// the request context.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(helper.HeaderRequestID)
+		if !requestIDPattern.MatchString(id) {
+			id = helper.GenerateRandomString(12)
+		}
+		c.Set(helper.ContextRequestID, id)
+		c.Header(helper.HeaderRequestID, id)
+
+		logger := log.With().Str("request_id", id).Logger()
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context()))
+		c.Next()
+	}
+}
+
 func contextMiddleware(
 	fileStore fs.FileStore,
 ) gin.HandlerFunc {
@@ -59,3 +84,17 @@ func contextMiddleware(
 		c.Next()
 	}
 }
+
+// paramsMiddleware decodes the plugin params from the request body, so that
+// the following middlewares can read them.
+func paramsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var params map[string]any
+		if err := c.ShouldBindJSON(&params); err != nil {
+			helper.AbortWithError(c, http.StatusBadRequest, helper.CodeInvalidParams, "invalid request body")
+			return
+		}
+		c.Set(helper.ContextParams, params)
+		c.Next()
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/bazuker/browserbro/pkg/fs/mock"
 	"github.com/bazuker/browserbro/pkg/manager/helper"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -53,6 +55,31 @@ func Test_loggerMiddleware(t *testing.T) {
 	assert.NotEmpty(t, logData.IP)
 }
 
+func Test_requestIDMiddleware(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	previous := log.Logger
+	log.Logger = zerolog.New(buffer)
+	defer func() { log.Logger = previous }()
+
+	r := gin.New()
+	r.Use(requestIDMiddleware())
+	r.GET("/example", func(c *gin.Context) {
+		helper.Logger(c.Request.Context()).Info().Msg("plugin log")
+	})
+
+	resp := performRequest(r, http.MethodGet, "/example", nil)
+	assert.Equal(t, testRequestID, resp.Header().Get(helper.HeaderRequestID))
+	assert.Contains(t, buffer.String(), `"request_id":"test-request"`)
+
+	req := httptest.NewRequest(http.MethodGet, "/example", nil)
+	req.Header.Set(helper.HeaderRequestID, "invalid id")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	id := w.Header().Get(helper.HeaderRequestID)
+	assert.NotEqual(t, "invalid id", id)
+	assert.Len(t, id, 16)
+}
+
 func Test_contextMiddleware(t *testing.T) {
 	var endpointCalled bool
 	r := gin.New()
@@ -0,0 +1,105 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/go-rod/stealth"
+)
+
+// Snapshot is the captured content of a watched page.
+type Snapshot struct {
+	Content []byte
+	// Extension is the file extension of the content, e.g. "txt".
+	Extension string
+}
+
+// Checker captures the watched content of a page. The check is aborted when
+// the context is canceled.
+type Checker interface {
+	Check(ctx context.Context, m Monitor) (Snapshot, error)
+}
+
+// BrowserChecker captures the pages with the browser.
+type BrowserChecker struct {
+	browser       *rod.Browser
+	maxTimePerRun time.Duration
+}
+
+func NewBrowserChecker(browser *rod.Browser) *BrowserChecker {
+	return &BrowserChecker{
+		browser:       browser,
+		maxTimePerRun: 30 * time.Second,
+	}
+}
+
+func (c *BrowserChecker) Check(ctx context.Context, m Monitor) (snapshot Snapshot, err error) {
+	var stealthPage *rod.Page
+	stealthPage, err = stealth.Page(c.browser)
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to create stealth page: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.maxTimePerRun)
+	defer cancel()
+	page := stealthPage.Context(ctx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to complete: %v", r)
+		}
+		_ = page.Close()
+	}()
+
+	err = page.Navigate(m.URL)
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to navigate to the page '%s': %w", m.URL, err)
+	}
+	err = page.WaitLoad()
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to wait for page to load: %w", err)
+	}
+	err = page.WaitStable(time.Second)
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to wait for page to stabilize: %w", err)
+	}
+
+	el, err := page.Element("body")
+	if m.Selector != "" {
+		el, err = page.Element(m.Selector)
+	}
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to find the element '%s': %w", m.Selector, err)
+	}
+
+	switch m.Mode {
+	case ModeHTML:
+		content, err := el.HTML()
+		if err != nil {
+			return snapshot, fmt.Errorf("failed to get HTML: %w", err)
+		}
+		return Snapshot{Content: []byte(content), Extension: "html"}, nil
+	case ModeScreenshot:
+		var content []byte
+		if m.Selector != "" {
+			content, err = el.Screenshot(proto.PageCaptureScreenshotFormatPng, 0)
+		} else {
+			content, err = page.Screenshot(true, &proto.PageCaptureScreenshot{
+				Format: proto.PageCaptureScreenshotFormatPng,
+			})
+		}
+		if err != nil {
+			return snapshot, fmt.Errorf("failed to take screenshot: %w", err)
+		}
+		return Snapshot{Content: content, Extension: "png"}, nil
+	default:
+		content, err := el.Text()
+		if err != nil {
+			return snapshot, fmt.Errorf("failed to get text: %w", err)
+		}
+		return Snapshot{Content: []byte(content), Extension: "txt"}, nil
+	}
+}
@@ -0,0 +1,58 @@
+package monitor
+
+import (
+	"strings"
+)
+
+// maxDiffLines limits the size of the compared texts. Longer texts are
+// reported as completely replaced.
+const maxDiffLines = 2000
+
+// diffLines returns a line diff of the texts. Removed lines are prefixed with
+// "-", added lines with "+" and unchanged lines are omitted.
+func diffLines(before, after string) string {
+	a := strings.Split(before, "\n")
+	b := strings.Split(after, "\n")
+
+	var sb strings.Builder
+	if len(a) > maxDiffLines || len(b) > maxDiffLines {
+		for _, line := range a {
+			sb.WriteString("-" + line + "\n")
+		}
+		for _, line := range b {
+			sb.WriteString("+" + line + "\n")
+		}
+		return sb.String()
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			sb.WriteString("-" + a[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+" + b[j] + "\n")
+			j++
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,105 @@
+package monitor
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/gin-gonic/gin"
+)
+
+// Middlewares are added to the monitors endpoints.
+type Middlewares struct {
+	// Check run before the immediate checks.
+	Check []gin.HandlerFunc
+}
+
+// Register adds the monitors endpoints to the router group.
+func Register(group *gin.RouterGroup, service *Service, middlewares Middlewares) {
+	h := &handlers{service: service}
+	group.GET("", h.list)
+	group.POST("", h.create)
+	group.GET("/:id", h.get)
+	group.PUT("/:id", h.update)
+	group.DELETE("/:id", h.delete)
+	group.POST("/:id/check", append(append([]gin.HandlerFunc{}, middlewares.Check...), h.check)...)
+}
+
+type handlers struct {
+	service *Service
+}
+
+func (h *handlers) list(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"monitors": h.service.List()})
+}
+
+func (h *handlers) create(c *gin.Context) {
+	var spec Spec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		helper.AbortWithError(c, http.StatusBadRequest, helper.CodeInvalidParams, "invalid request body")
+		return
+	}
+	m, err := h.service.Create(spec)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, m)
+}
+
+func (h *handlers) get(c *gin.Context) {
+	m, err := h.service.Get(c.Param("id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+func (h *handlers) update(c *gin.Context) {
+	var spec Spec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		helper.AbortWithError(c, http.StatusBadRequest, helper.CodeInvalidParams, "invalid request body")
+		return
+	}
+	m, err := h.service.Update(c.Param("id"), spec)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+func (h *handlers) delete(c *gin.Context) {
+	if err := h.service.Delete(c.Param("id")); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, helper.HTTPMessage{Message: "monitor deleted"})
+}
+
+func (h *handlers) check(c *gin.Context) {
+	m, err := h.service.CheckNow(c.Param("id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+func writeError(c *gin.Context, err error) {
+	var validationErr *ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		helper.AbortWithError(c, http.StatusBadRequest, helper.CodeInvalidParams, err.Error())
+	case errors.Is(err, ErrNotFound):
+		helper.AbortWithError(c, http.StatusNotFound, helper.CodeNotFound, err.Error())
+	case errors.Is(err, ErrBusy):
+		helper.AbortWithError(c, http.StatusConflict, helper.CodeConflict, err.Error())
+	case errors.Is(err, ErrStopped):
+		helper.AbortWithError(c, http.StatusServiceUnavailable, helper.CodeShuttingDown, err.Error())
+	default:
+		helper.Logger(c.Request.Context()).Error().Err(err).Msg("monitor request failed")
+		helper.AbortWithError(c, http.StatusInternalServerError, helper.CodeInternal, "internal server error")
+	}
+}
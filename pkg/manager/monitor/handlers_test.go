@@ -0,0 +1,78 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlers(t *testing.T) {
+	s, err := New(Config{FileStore: newMemoryStore().fileStore(), Checker: &mockChecker{content: "content"}})
+	require.NoError(t, err)
+	router := gin.New()
+	Register(router.Group("/monitors"), s, Middlewares{Check: []gin.HandlerFunc{
+		func(c *gin.Context) { c.Header("X-Check", "limited") },
+	}})
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	resp := request(http.MethodPost, "/monitors", `{"url":"https://example.com/","interval":10,"webhook":"https://hooks.example.com/"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.JSONEq(t, `{"error":{"code":"invalid_params","message":"'interval' must be at least 60 seconds"}}`, resp.Body.String())
+
+	resp = request(http.MethodPost, "/monitors", `[`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.JSONEq(t, `{"error":{"code":"invalid_params","message":"invalid request body"}}`, resp.Body.String())
+
+	resp = request(http.MethodPost, "/monitors", `{"url":"https://example.com/","interval":60,"mode":"html","webhook":"https://hooks.example.com/"}`)
+	require.Equal(t, http.StatusCreated, resp.Code)
+	var created Monitor
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, ModeHTML, created.Mode)
+
+	resp = request(http.MethodGet, "/monitors", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var list struct {
+		Monitors []Monitor `json:"monitors"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &list))
+	require.Len(t, list.Monitors, 1)
+	assert.Equal(t, created.ID, list.Monitors[0].ID)
+
+	resp = request(http.MethodGet, "/monitors/"+created.ID, "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	resp = request(http.MethodPut, "/monitors/"+created.ID, `{"url":"https://example.com/","interval":300,"webhook":"https://hooks.example.com/"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var updated Monitor
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &updated))
+	assert.Equal(t, 300, updated.Interval)
+
+	resp = request(http.MethodPost, "/monitors/"+created.ID+"/check", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "limited", resp.Header().Get("X-Check"))
+	var checked Monitor
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &checked))
+	assert.NotNil(t, checked.LastCheckedAt)
+	assert.NotEmpty(t, checked.Snapshot)
+
+	resp = request(http.MethodDelete, "/monitors/"+created.ID, "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"message":"monitor deleted"}`, resp.Body.String())
+
+	resp = request(http.MethodGet, "/monitors/"+created.ID, "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.JSONEq(t, `{"error":{"code":"not_found","message":"monitor not found"}}`, resp.Body.String())
+}
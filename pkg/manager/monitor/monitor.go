@@ -0,0 +1,105 @@
+// Package monitor watches pages for changes. Every monitor is checked on its
+// own interval and a webhook is called with the diff when the content changes.
+package monitor
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+)
+
+const (
+	// ModeText compares the visible text.
+	ModeText = "text"
+	// ModeHTML compares the HTML markup.
+	ModeHTML = "html"
+	// ModeScreenshot compares the hash of a screenshot.
+	ModeScreenshot = "screenshot"
+)
+
+// Spec is the user-defined part of a monitor.
+type Spec struct {
+	// URL is the address of the watched page.
+	URL string `json:"url"`
+	// Selector is the CSS selector of the watched element. The whole page is
+	// watched if it is empty.
+	Selector string `json:"selector,omitempty"`
+	// Interval is the number of seconds between two checks.
+	Interval int `json:"interval"`
+	// Mode is one of ModeText, ModeHTML and ModeScreenshot. Default: ModeText.
+	Mode string `json:"mode"`
+	// Webhook is the URL the change events are posted to.
+	Webhook string `json:"webhook"`
+}
+
+// Monitor is a registered monitor with the state of its checks.
+type Monitor struct {
+	Spec
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// LastCheckedAt is the time of the last check, nil before the first check.
+	LastCheckedAt *time.Time `json:"lastCheckedAt,omitempty"`
+	// LastChangedAt is the time of the last detected change.
+	LastChangedAt *time.Time `json:"lastChangedAt,omitempty"`
+	// LastError is the error of the last check or webhook call.
+	LastError string `json:"lastError,omitempty"`
+	// Hash is the SHA-256 hash of the last snapshot.
+	Hash string `json:"hash,omitempty"`
+	// Snapshot is the file ID of the last snapshot in the file store.
+	Snapshot string `json:"snapshot,omitempty"`
+	// Snapshots are the file IDs of the kept snapshots, the oldest first.
+	Snapshots []string `json:"snapshots,omitempty"`
+}
+
+// ValidationError is returned for invalid monitor specs.
+type ValidationError struct {
+	msg string
+}
+
+func (e *ValidationError) Error() string {
+	return e.msg
+}
+
+func invalid(format string, args ...any) error {
+	return &ValidationError{msg: fmt.Sprintf(format, args...)}
+}
+
+// validate checks the spec and fills in the defaults.
+func (s *Spec) validate(minInterval time.Duration) error {
+	if !isHTTPURL(s.URL) {
+		return invalid("'url' must be an absolute HTTP(S) URL")
+	}
+	if s.Selector != "" {
+		if _, err := cascadia.ParseGroup(s.Selector); err != nil {
+			return invalid("'selector' is not a valid CSS selector: %v", err)
+		}
+	}
+	if time.Duration(s.Interval)*time.Second < minInterval {
+		return invalid("'interval' must be at least %d seconds", int(minInterval.Seconds()))
+	}
+	switch s.Mode {
+	case "":
+		s.Mode = ModeText
+	case ModeText, ModeHTML, ModeScreenshot:
+	default:
+		return invalid("'mode' must be one of '%s', '%s' and '%s'", ModeText, ModeHTML, ModeScreenshot)
+	}
+	if !isHTTPURL(s.Webhook) {
+		return invalid("'webhook' must be an absolute HTTP(S) URL")
+	}
+	return nil
+}
+
+// due reports whether the monitor should be checked at the time.
+func (m *Monitor) due(now time.Time) bool {
+	return m.LastCheckedAt == nil || !now.Before(m.LastCheckedAt.Add(time.Duration(m.Interval)*time.Second))
+}
+
+func isHTTPURL(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,359 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/fs"
+	"github.com/bazuker/browserbro/pkg/fs/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecValidate(t *testing.T) {
+	valid := Spec{
+		URL:      "https://example.com/",
+		Interval: 60,
+		Webhook:  "https://hooks.example.com/",
+	}
+
+	spec := valid
+	require.NoError(t, spec.validate(time.Minute))
+	assert.Equal(t, ModeText, spec.Mode)
+
+	tests := []struct {
+		name   string
+		modify func(s *Spec)
+		err    string
+	}{
+		{"invalid url", func(s *Spec) { s.URL = "example.com" }, "'url' must be an absolute HTTP(S) URL"},
+		{"invalid selector", func(s *Spec) { s.Selector = "div[" }, "'selector' is not a valid CSS selector"},
+		{"short interval", func(s *Spec) { s.Interval = 10 }, "'interval' must be at least 60 seconds"},
+		{"invalid mode", func(s *Spec) { s.Mode = "pdf" }, "'mode' must be one of"},
+		{"invalid webhook", func(s *Spec) { s.Webhook = "ftp://example.com/" }, "'webhook' must be an absolute HTTP(S) URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := valid
+			tt.modify(&spec)
+			err := spec.validate(time.Minute)
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestMonitorDue(t *testing.T) {
+	now := time.Now()
+	m := Monitor{Spec: Spec{Interval: 60}}
+	assert.True(t, m.due(now))
+
+	checked := now.Add(-30 * time.Second)
+	m.LastCheckedAt = &checked
+	assert.False(t, m.due(now))
+	assert.True(t, m.due(now.Add(30*time.Second)))
+}
+
+func TestDiffLines(t *testing.T) {
+	assert.Empty(t, diffLines("a\nb", "a\nb"))
+	assert.Equal(t, "-b\n+c\n", diffLines("a\nb\nd", "a\nc\nd"))
+	assert.Equal(t, "+b\n", diffLines("a", "a\nb"))
+	assert.Equal(t, "-a\n", diffLines("a\nb", "b"))
+}
+
+func TestService(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []Event
+	)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	defer webhook.Close()
+
+	store := newMemoryStore()
+	checker := &mockChecker{content: "price: 10"}
+	s, err := New(Config{FileStore: store.fileStore(), Checker: checker})
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	m, err := s.Create(Spec{URL: "https://example.com/", Interval: 60, Webhook: webhook.URL})
+	require.NoError(t, err)
+	assert.Equal(t, ModeText, m.Mode)
+	assert.Len(t, s.List(), 1)
+
+	t.Run("first check stores a snapshot", func(t *testing.T) {
+		m, err := s.CheckNow(m.ID)
+		require.NoError(t, err)
+		assert.NotEmpty(t, m.Hash)
+		assert.Equal(t, "price: 10", string(store.get(m.Snapshot)))
+		assert.Nil(t, m.LastChangedAt)
+		assert.Empty(t, events)
+	})
+
+	t.Run("unchanged content", func(t *testing.T) {
+		before, _ := s.Get(m.ID)
+		now = now.Add(time.Minute)
+		after, err := s.CheckNow(m.ID)
+		require.NoError(t, err)
+		assert.Equal(t, before.Snapshot, after.Snapshot)
+		assert.Equal(t, now, *after.LastCheckedAt)
+		assert.Empty(t, events)
+	})
+
+	t.Run("changed content notifies the webhook", func(t *testing.T) {
+		before, _ := s.Get(m.ID)
+		checker.content = "price: 12"
+		now = now.Add(time.Minute)
+		after, err := s.CheckNow(m.ID)
+		require.NoError(t, err)
+		assert.NotEqual(t, before.Hash, after.Hash)
+		assert.Equal(t, now, *after.LastChangedAt)
+
+		require.Len(t, events, 1)
+		event := events[0]
+		assert.Equal(t, EventChanged, event.Event)
+		assert.Equal(t, m.ID, event.Monitor.ID)
+		assert.Equal(t, before.Snapshot, event.Previous.File)
+		assert.Equal(t, after.Snapshot, event.Current.File)
+		assert.Equal(t, "-price: 10\n+price: 12\n", event.Diff)
+	})
+
+	t.Run("check error", func(t *testing.T) {
+		checker.err = errors.New("failed to navigate")
+		after, err := s.CheckNow(m.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "failed to navigate", after.LastError)
+		checker.err = nil
+	})
+
+	t.Run("monitors are persisted", func(t *testing.T) {
+		assert.NotEmpty(t, store.get(fs.StateKey("monitors.json")))
+		loaded, err := New(Config{FileStore: store.fileStore(), Checker: checker})
+		require.NoError(t, err)
+		expected, _ := s.Get(m.ID)
+		actual, err := loaded.Get(m.ID)
+		require.NoError(t, err)
+		assert.Equal(t, expected.Hash, actual.Hash)
+		assert.Equal(t, expected.LastError, actual.LastError)
+	})
+
+	t.Run("update resets the state when the content changes", func(t *testing.T) {
+		updated, err := s.Update(m.ID, Spec{URL: "https://example.com/", Interval: 120, Webhook: webhook.URL})
+		require.NoError(t, err)
+		assert.Equal(t, 120, updated.Interval)
+		assert.NotEmpty(t, updated.Hash)
+
+		updated, err = s.Update(m.ID, Spec{URL: "https://example.com/", Selector: "#price", Interval: 120, Webhook: webhook.URL})
+		require.NoError(t, err)
+		assert.Empty(t, updated.Hash)
+		assert.Nil(t, updated.LastCheckedAt)
+		assert.Equal(t, m.CreatedAt, updated.CreatedAt)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, s.Delete(m.ID))
+		_, err := s.Get(m.ID)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.ErrorIs(t, s.Delete(m.ID), ErrNotFound)
+		assert.Empty(t, s.List())
+	})
+}
+
+func TestServiceSnapshots(t *testing.T) {
+	store := newMemoryStore()
+	checker := &mockChecker{}
+	s, err := New(Config{FileStore: store.fileStore(), Checker: checker, SnapshotHistory: 2})
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	m, err := s.Create(Spec{URL: "https://example.com/", Interval: 60, Webhook: "http://127.0.0.1:1/"})
+	require.NoError(t, err)
+	var files []string
+	for _, content := range []string{"a", "b", "c"} {
+		checker.content = content
+		now = now.Add(time.Minute)
+		m, err = s.CheckNow(m.ID)
+		require.NoError(t, err)
+		files = append(files, m.Snapshot)
+	}
+
+	// Only the last snapshots are kept.
+	assert.Equal(t, files[1:], m.Snapshots)
+	assert.Nil(t, store.get(files[0]))
+	assert.Equal(t, "c", string(store.get(files[2])))
+
+	// The snapshots of the replaced content are removed.
+	_, err = s.Update(m.ID, Spec{URL: "https://example.com/", Selector: "#price", Interval: 60, Webhook: "http://127.0.0.1:1/"})
+	require.NoError(t, err)
+	assert.Nil(t, store.get(files[1]))
+	assert.Nil(t, store.get(files[2]))
+
+	checker.content = "d"
+	m, err = s.CheckNow(m.ID)
+	require.NoError(t, err)
+	require.NoError(t, s.Delete(m.ID))
+	assert.Nil(t, store.get(m.Snapshot))
+}
+
+func TestServiceSchedule(t *testing.T) {
+	checker := &mockChecker{content: "content"}
+	s, err := New(Config{
+		FileStore:    newMemoryStore().fileStore(),
+		Checker:      checker,
+		PollInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	m, err := s.Create(Spec{URL: "https://example.com/", Interval: 60, Webhook: "https://hooks.example.com/"})
+	require.NoError(t, err)
+
+	s.Start()
+	require.Eventually(t, func() bool {
+		m, _ := s.Get(m.ID)
+		return m.LastCheckedAt != nil
+	}, time.Second, 10*time.Millisecond)
+	s.Stop(context.Background())
+
+	// The monitor is not due again before its interval.
+	assert.Equal(t, 1, checker.calls())
+}
+
+func TestServiceStop(t *testing.T) {
+	checker := &mockChecker{block: true}
+	s, err := New(Config{
+		FileStore:    newMemoryStore().fileStore(),
+		Checker:      checker,
+		PollInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	m, err := s.Create(Spec{URL: "https://example.com/", Interval: 60, Webhook: "https://hooks.example.com/"})
+	require.NoError(t, err)
+
+	s.Start()
+	require.Eventually(t, func() bool { return checker.calls() == 1 }, time.Second, 10*time.Millisecond)
+
+	// The check that does not complete before the deadline is canceled.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	s.Stop(ctx)
+	m, err = s.Get(m.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "context canceled", m.LastError)
+}
+
+func TestServiceStop_CheckNow(t *testing.T) {
+	checker := &mockChecker{block: true}
+	s, err := New(Config{FileStore: newMemoryStore().fileStore(), Checker: checker})
+	require.NoError(t, err)
+	m, err := s.Create(Spec{URL: "https://example.com/", Interval: 60, Webhook: "https://hooks.example.com/"})
+	require.NoError(t, err)
+
+	checked := make(chan Monitor)
+	go func() {
+		m, _ := s.CheckNow(m.ID)
+		checked <- m
+	}()
+	require.Eventually(t, func() bool { return checker.calls() == 1 }, time.Second, 10*time.Millisecond)
+
+	// Stop waits for the immediate check and cancels it at the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	s.Stop(ctx)
+	stopped, err := s.Get(m.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "context canceled", stopped.LastError)
+	assert.Equal(t, "context canceled", (<-checked).LastError)
+
+	_, err = s.CheckNow(m.ID)
+	assert.ErrorIs(t, err, ErrStopped)
+}
+
+type mockChecker struct {
+	mu      sync.Mutex
+	content string
+	err     error
+	count   int
+	// block makes the checks wait until the context is canceled.
+	block bool
+}
+
+func (c *mockChecker) Check(ctx context.Context, m Monitor) (Snapshot, error) {
+	c.mu.Lock()
+	c.count++
+	block := c.block
+	c.mu.Unlock()
+	if block {
+		<-ctx.Done()
+		return Snapshot{}, ctx.Err()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return Snapshot{}, c.err
+	}
+	return Snapshot{Content: []byte(c.content), Extension: "txt"}, nil
+}
+
+func (c *mockChecker) calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.count
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{objects: make(map[string][]byte)}
+}
+
+func (s *memoryStore) get(key string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects[key]
+}
+
+func (s *memoryStore) fileStore() *mock.FileStore {
+	return &mock.FileStore{
+		PutObjectFn: func(object []byte, key string) error {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.objects[key] = object
+			return nil
+		},
+		GetObjectFn: func(key string) ([]byte, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			object, ok := s.objects[key]
+			if !ok {
+				return nil, fs.ErrorFileNotFound
+			}
+			return object, nil
+		},
+		DeleteObjectFn: func(key string) error {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if _, ok := s.objects[key]; !ok {
+				return fs.ErrorFileNotFound
+			}
+			delete(s.objects, key)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,445 @@
+package monitor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/fs"
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/rs/zerolog/log"
+)
+
+// monitorsKey is the file store key of the registered monitors.
+var monitorsKey = fs.StateKey("monitors.json")
+
+var (
+	ErrNotFound = errors.New("monitor not found")
+	ErrBusy     = errors.New("monitor is being checked")
+	ErrStopped  = errors.New("monitors are stopped")
+)
+
+type Config struct {
+	// FileStore stores the monitors and the snapshots (required).
+	FileStore fs.FileStore
+	// Checker captures the watched pages (required).
+	Checker Checker
+	// HTTPClient calls the webhooks.
+	HTTPClient *http.Client
+	// MinInterval is the shortest allowed check interval. Default: 1 minute.
+	MinInterval time.Duration
+	// Concurrency is the maximum number of simultaneous checks. Default: 2.
+	Concurrency int
+	// PollInterval is how often the monitors are looked through for due checks. Default: 1 second.
+	PollInterval time.Duration
+	// SnapshotHistory is the number of snapshots kept per monitor, at least 2
+	// so that the previous snapshot of a change event exists. Default: 10.
+	SnapshotHistory int
+}
+
+// Service keeps the registered monitors and checks them on schedule.
+type Service struct {
+	fileStore    fs.FileStore
+	checker      Checker
+	client       *http.Client
+	minInterval  time.Duration
+	pollInterval time.Duration
+	history      int
+	now          func() time.Time
+
+	mu       sync.Mutex
+	monitors map[string]*Monitor
+	running  map[string]bool
+	stopped  bool
+	slots    chan struct{}
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	// checksCtx is canceled to abort the running checks on shutdown.
+	checksCtx    context.Context
+	cancelChecks context.CancelFunc
+}
+
+func New(cfg Config) (*Service, error) {
+	if cfg.FileStore == nil {
+		return nil, errors.New("file store is required")
+	}
+	if cfg.Checker == nil {
+		return nil, errors.New("checker is required")
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.MinInterval <= 0 {
+		cfg.MinInterval = time.Minute
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 2
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.SnapshotHistory <= 0 {
+		cfg.SnapshotHistory = 10
+	}
+
+	s := &Service{
+		fileStore:    cfg.FileStore,
+		checker:      cfg.Checker,
+		client:       cfg.HTTPClient,
+		minInterval:  cfg.MinInterval,
+		pollInterval: cfg.PollInterval,
+		history:      max(cfg.SnapshotHistory, 2),
+		now:          time.Now,
+		monitors:     make(map[string]*Monitor),
+		running:      make(map[string]bool),
+		slots:        make(chan struct{}, cfg.Concurrency),
+	}
+	s.checksCtx, s.cancelChecks = context.WithCancel(context.Background())
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// List returns the monitors in the order of creation.
+func (s *Service) List() []Monitor {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	monitors := make([]Monitor, 0, len(s.monitors))
+	for _, m := range s.monitors {
+		monitors = append(monitors, *m)
+	}
+	sort.Slice(monitors, func(i, j int) bool {
+		if monitors[i].CreatedAt.Equal(monitors[j].CreatedAt) {
+			return monitors[i].ID < monitors[j].ID
+		}
+		return monitors[i].CreatedAt.Before(monitors[j].CreatedAt)
+	})
+	return monitors
+}
+
+func (s *Service) Get(id string) (Monitor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.monitors[id]
+	if !ok {
+		return Monitor{}, ErrNotFound
+	}
+	return *m, nil
+}
+
+// Create registers a monitor. It is checked for the first time on the next poll.
+func (s *Service) Create(spec Spec) (Monitor, error) {
+	if err := spec.validate(s.minInterval); err != nil {
+		return Monitor{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now().UTC()
+	m := &Monitor{
+		Spec:      spec,
+		ID:        helper.GenerateRandomString(6),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.monitors[m.ID] = m
+	if err := s.save(); err != nil {
+		delete(s.monitors, m.ID)
+		return Monitor{}, err
+	}
+	return *m, nil
+}
+
+// Update replaces the spec of the monitor. The check state is reset if the
+// watched content changes, i.e. the URL, the selector or the mode.
+func (s *Service) Update(id string, spec Spec) (Monitor, error) {
+	if err := spec.validate(s.minInterval); err != nil {
+		return Monitor{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.monitors[id]
+	if !ok {
+		return Monitor{}, ErrNotFound
+	}
+	previous := *m
+	reset := spec.URL != m.URL || spec.Selector != m.Selector || spec.Mode != m.Mode
+	if reset {
+		*m = Monitor{ID: m.ID, CreatedAt: m.CreatedAt}
+	}
+	m.Spec = spec
+	m.UpdatedAt = s.now().UTC()
+	if err := s.save(); err != nil {
+		*m = previous
+		return Monitor{}, err
+	}
+	if reset {
+		s.deleteSnapshots(previous.Snapshots)
+	}
+	return *m, nil
+}
+
+func (s *Service) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.monitors[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.monitors, id)
+	if err := s.save(); err != nil {
+		s.monitors[id] = m
+		return err
+	}
+	s.deleteSnapshots(m.Snapshots)
+	return nil
+}
+
+// CheckNow checks the monitor immediately and returns its updated state. The
+// check waits for a free slot like the scheduled checks and Stop waits for it.
+func (s *Service) CheckNow(id string) (Monitor, error) {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return Monitor{}, ErrStopped
+	}
+	m, ok := s.monitors[id]
+	if !ok {
+		s.mu.Unlock()
+		return Monitor{}, ErrNotFound
+	}
+	if s.running[id] {
+		s.mu.Unlock()
+		return Monitor{}, ErrBusy
+	}
+	s.running[id] = true
+	snapshot := *m
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	func() {
+		defer s.wg.Done()
+		s.slots <- struct{}{}
+		defer func() { <-s.slots }()
+		s.check(snapshot)
+	}()
+	return s.Get(id)
+}
+
+// Start checks the due monitors in the background until Stop is called.
+func (s *Service) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.checkDue()
+			}
+		}
+	}()
+}
+
+// Stop stops scheduling checks and waits for the running checks and their
+// webhooks to complete. The checks still running when the context is done are
+// canceled, the webhooks of the completed ones are still sent.
+func (s *Service) Stop(ctx context.Context) {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.cancelChecks()
+		<-done
+	}
+}
+
+// checkDue starts the checks of the due monitors that are not being checked.
+func (s *Service) checkDue() {
+	s.mu.Lock()
+	now := s.now()
+	due := make([]Monitor, 0)
+	for id, m := range s.monitors {
+		if !s.running[id] && m.due(now) {
+			s.running[id] = true
+			due = append(due, *m)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, m := range due {
+		s.wg.Add(1)
+		go func(m Monitor) {
+			defer s.wg.Done()
+			s.slots <- struct{}{}
+			defer func() { <-s.slots }()
+			s.check(m)
+		}(m)
+	}
+}
+
+// check captures the monitored content, stores the changed snapshots and
+// notifies the webhook about the changes. The monitor is marked as running
+// during the check, so its snapshots are read and written outside the lock.
+func (s *Service) check(m Monitor) {
+	snapshot, checkErr := s.checker.Check(s.checksCtx, m)
+	now := s.now().UTC()
+
+	var (
+		hash, file string
+		previous   []byte
+		saveErr    error
+	)
+	// The monitor holds the hash and the snapshot of the previous check.
+	if checkErr == nil {
+		if hash = hashContent(snapshot.Content); hash != m.Hash {
+			file = fmt.Sprintf("monitor.%s.%d.%s", m.ID, now.UnixNano(), snapshot.Extension)
+			saveErr = s.fileStore.PutObject(snapshot.Content, file)
+			if saveErr == nil && m.Hash != "" && m.Mode != ModeScreenshot {
+				previous, _ = s.fileStore.GetObject(m.Snapshot)
+			}
+		}
+	}
+
+	s.mu.Lock()
+	delete(s.running, m.ID)
+	current, ok := s.monitors[m.ID]
+	// The result is stale if the monitor was deleted or updated during the check.
+	if !ok || !current.UpdatedAt.Equal(m.UpdatedAt) {
+		s.mu.Unlock()
+		if file != "" && saveErr == nil {
+			s.deleteSnapshots([]string{file})
+		}
+		return
+	}
+
+	var (
+		event  *Event
+		excess []string
+	)
+	current.LastCheckedAt = &now
+	current.LastError = ""
+	switch {
+	case checkErr != nil:
+		current.LastError = checkErr.Error()
+	case file == "":
+		// The content did not change.
+	case saveErr != nil:
+		current.LastError = fmt.Sprintf("failed to save snapshot: %v", saveErr)
+	default:
+		if current.Hash != "" {
+			event = &Event{
+				Event:     EventChanged,
+				CheckedAt: now,
+				Previous:  SnapshotState{Hash: current.Hash, File: current.Snapshot},
+				Current:   SnapshotState{Hash: hash, File: file},
+			}
+			if previous != nil {
+				event.Diff = diffLines(string(previous), string(snapshot.Content))
+			}
+			current.LastChangedAt = &now
+		}
+		current.Hash = hash
+		current.Snapshot = file
+		current.Snapshots = append(current.Snapshots, file)
+		if n := len(current.Snapshots) - s.history; n > 0 {
+			excess = current.Snapshots[:n]
+			current.Snapshots = current.Snapshots[n:]
+		}
+	}
+	if event != nil {
+		event.Monitor = *current
+	}
+	s.saveOrLog()
+	s.mu.Unlock()
+
+	s.deleteSnapshots(excess)
+	if event == nil {
+		return
+	}
+	if err := notify(s.client, *event); err != nil {
+		s.mu.Lock()
+		if current, ok := s.monitors[m.ID]; ok {
+			current.LastError = err.Error()
+			s.saveOrLog()
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *Service) load() error {
+	data, err := s.fileStore.GetObject(monitorsKey)
+	if errors.Is(err, fs.ErrorFileNotFound) || (err == nil && len(data) == 0) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load monitors: %w", err)
+	}
+	var monitors []*Monitor
+	if err := json.Unmarshal(data, &monitors); err != nil {
+		return fmt.Errorf("failed to load monitors: %w", err)
+	}
+	for _, m := range monitors {
+		s.monitors[m.ID] = m
+	}
+	return nil
+}
+
+// save persists the monitors. The caller must hold the lock.
+func (s *Service) save() error {
+	monitors := make([]*Monitor, 0, len(s.monitors))
+	for _, m := range s.monitors {
+		monitors = append(monitors, m)
+	}
+	data, err := json.Marshal(monitors)
+	if err != nil {
+		return fmt.Errorf("failed to save monitors: %w", err)
+	}
+	if err := s.fileStore.PutObject(data, monitorsKey); err != nil {
+		return fmt.Errorf("failed to save monitors: %w", err)
+	}
+	return nil
+}
+
+// deleteSnapshots removes the snapshot files. The errors are logged, a
+// leftover snapshot is only a wasted file.
+func (s *Service) deleteSnapshots(files []string) {
+	for _, file := range files {
+		if err := s.fileStore.DeleteObject(file); err != nil && !errors.Is(err, fs.ErrorFileNotFound) {
+			log.Error().Err(err).Str("file", file).Msg("failed to delete monitor snapshot")
+		}
+	}
+}
+
+func (s *Service) saveOrLog() {
+	if err := s.save(); err != nil {
+		log.Error().Err(err).Msg("failed to save monitors")
+	}
+}
+
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
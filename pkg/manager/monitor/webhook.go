@@ -0,0 +1,56 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// EventChanged is the type of the events sent when the watched content changes.
+const EventChanged = "monitor.changed"
+
+// Event is the body of the webhook requests.
+type Event struct {
+	Event     string        `json:"event"`
+	CheckedAt time.Time     `json:"checkedAt"`
+	Monitor   Monitor       `json:"monitor"`
+	Previous  SnapshotState `json:"previous"`
+	Current   SnapshotState `json:"current"`
+	// Diff is the line diff of the text and HTML snapshots.
+	Diff string `json:"diff,omitempty"`
+}
+
+// SnapshotState identifies a stored snapshot.
+type SnapshotState struct {
+	Hash string `json:"hash"`
+	// File is the file ID of the snapshot in the file store.
+	File string `json:"file"`
+}
+
+// notify posts the event to the webhook of the monitor.
+func notify(client *http.Client, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, event.Monitor.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "BrowserBro")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bazuker/browserbro/pkg/manager/monitor"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// monitorRunName is the name the monitor checks are measured under, like the
+// plugin runs.
+const monitorRunName = "monitor"
+
+// poolChecker runs the monitor checks through the run pool of the manager,
+// the same way as the plugin runs.
+type poolChecker struct {
+	manager *Manager
+	checker monitor.Checker
+}
+
+func (c *poolChecker) Check(ctx context.Context, m monitor.Monitor) (snapshot monitor.Snapshot, err error) {
+	err = c.manager.runInPool(ctx, monitorRunName, "monitor "+m.ID, []attribute.KeyValue{
+		attribute.String("monitor.id", m.ID),
+	}, func(ctx context.Context) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("monitor '%s' check panicked: %v", m.ID, r)
+			}
+		}()
+		snapshot, err = c.checker.Check(ctx, m)
+		return err
+	})
+	return snapshot, err
+}
@@ -0,0 +1,51 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bazuker/browserbro/pkg/fs/mock"
+	"github.com/bazuker/browserbro/pkg/manager/monitor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolChecker(t *testing.T) {
+	m, err := New(Config{
+		ServerAddress:     ":0",
+		FileStore:         &mock.FileStore{},
+		MaxConcurrentRuns: 1,
+	})
+	require.NoError(t, err)
+	m.browserConnector = &mockConnector{}
+
+	checker := &poolChecker{manager: m, checker: checkerFunc(func(ctx context.Context, mon monitor.Monitor) (monitor.Snapshot, error) {
+		// The check holds the only slot of the run pool.
+		assert.Len(t, m.runPool, 1)
+		return monitor.Snapshot{Content: []byte(mon.URL), Extension: "txt"}, nil
+	})}
+	_, err = checker.Check(context.Background(), monitor.Monitor{ID: "abc"})
+	assert.ErrorIs(t, err, errBrowserUnavailable)
+
+	require.NoError(t, m.Run())
+	defer func() {
+		require.NoError(t, m.Stop())
+	}()
+
+	snapshot, err := checker.Check(context.Background(), monitor.Monitor{ID: "abc", Spec: monitor.Spec{URL: "https://go.dev/"}})
+	require.NoError(t, err)
+	assert.Equal(t, "https://go.dev/", string(snapshot.Content))
+
+	// The checks are measured like the plugin runs.
+	w := httptest.NewRecorder()
+	m.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, w.Body.String(), `browserbro_plugin_runs_total{plugin="monitor",result="success"} 1`)
+}
+
+type checkerFunc func(ctx context.Context, m monitor.Monitor) (monitor.Snapshot, error)
+
+func (f checkerFunc) Check(ctx context.Context, m monitor.Monitor) (monitor.Snapshot, error) {
+	return f(ctx, m)
+}
@@ -0,0 +1,148 @@
+package pipeline
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/gin-gonic/gin"
+)
+
+// Middlewares are added to the pipelines endpoints.
+type Middlewares struct {
+	// Manage run before the endpoints that list, save and delete the pipelines.
+	Manage []gin.HandlerFunc
+	// Run run before the pipeline runs, once the pipeline is in the context.
+	Run []gin.HandlerFunc
+}
+
+// RunRequest is the pipeline run in the context of a run request.
+type RunRequest struct {
+	// Name is empty for ad hoc runs.
+	Name  string
+	Spec  Spec
+	Input map[string]any
+}
+
+// RunRequestFromContext returns the pipeline of the run request.
+func RunRequestFromContext(c *gin.Context) RunRequest {
+	return c.MustGet(helper.ContextPipeline).(RunRequest)
+}
+
+// Register adds the pipelines endpoints to the router group. The run
+// middlewares see the pipeline in the context, see RunRequestFromContext.
+func Register(group *gin.RouterGroup, service *Service, middlewares Middlewares) {
+	h := &handlers{service: service}
+	manage := func(handler gin.HandlerFunc) []gin.HandlerFunc {
+		return append(append([]gin.HandlerFunc{}, middlewares.Manage...), handler)
+	}
+	run := func(bind gin.HandlerFunc) []gin.HandlerFunc {
+		handlers := append([]gin.HandlerFunc{bind}, middlewares.Run...)
+		return append(handlers, h.run)
+	}
+	group.POST("/run", run(h.bindAdHoc)...)
+	group.POST("/:name/run", run(h.bindSaved)...)
+	group.GET("", manage(h.list)...)
+	group.GET("/:name", manage(h.get)...)
+	group.PUT("/:name", manage(h.save)...)
+	group.DELETE("/:name", manage(h.delete)...)
+}
+
+type handlers struct {
+	service *Service
+}
+
+func (h *handlers) list(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"pipelines": h.service.List()})
+}
+
+func (h *handlers) get(c *gin.Context) {
+	p, err := h.service.Get(c.Param("name"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+func (h *handlers) save(c *gin.Context) {
+	var spec Spec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		helper.AbortWithError(c, http.StatusBadRequest, helper.CodeInvalidParams, "invalid request body")
+		return
+	}
+	p, created, err := h.service.Save(c.Param("name"), spec)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, p)
+}
+
+func (h *handlers) delete(c *gin.Context) {
+	if err := h.service.Delete(c.Param("name")); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, helper.HTTPMessage{Message: "pipeline deleted"})
+}
+
+// bindAdHoc validates the pipeline of the request body.
+func (h *handlers) bindAdHoc(c *gin.Context) {
+	var body struct {
+		Spec
+		Input map[string]any `json:"input"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		helper.AbortWithError(c, http.StatusBadRequest, helper.CodeInvalidParams, "invalid request body")
+		return
+	}
+	if err := h.service.Validate(&body.Spec); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Set(helper.ContextPipeline, RunRequest{Spec: body.Spec, Input: body.Input})
+	c.Next()
+}
+
+// bindSaved looks up the saved pipeline. The request body is optional.
+func (h *handlers) bindSaved(c *gin.Context) {
+	p, err := h.service.Get(c.Param("name"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	var body struct {
+		Input map[string]any `json:"input"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			helper.AbortWithError(c, http.StatusBadRequest, helper.CodeInvalidParams, "invalid request body")
+			return
+		}
+	}
+	c.Set(helper.ContextPipeline, RunRequest{Name: p.Name, Spec: p.Spec, Input: body.Input})
+	c.Next()
+}
+
+func (h *handlers) run(c *gin.Context) {
+	req := RunRequestFromContext(c)
+	c.JSON(http.StatusOK, h.service.Run(c.Request.Context(), req.Name, req.Spec, req.Input))
+}
+
+func writeError(c *gin.Context, err error) {
+	var validationErr *ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		helper.AbortWithError(c, http.StatusBadRequest, helper.CodeInvalidParams, err.Error())
+	case errors.Is(err, ErrNotFound):
+		helper.AbortWithError(c, http.StatusNotFound, helper.CodeNotFound, err.Error())
+	default:
+		helper.Logger(c.Request.Context()).Error().Err(err).Msg("pipeline request failed")
+		helper.AbortWithError(c, http.StatusInternalServerError, helper.CodeInternal, "internal server error")
+	}
+}
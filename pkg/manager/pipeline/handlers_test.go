@@ -0,0 +1,102 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlers(t *testing.T) {
+	s, err := New(Config{
+		FileStore: newMemoryStore().fileStore(),
+		Plugins:   []string{"googlesearch", "screenshot"},
+		Run: func(ctx context.Context, plugin string, params map[string]any) (map[string]any, error) {
+			if plugin == "googlesearch" {
+				return map[string]any{"all": []any{map[string]any{"link": "https://go.dev"}}}, nil
+			}
+			return map[string]any{"urls": params["urls"]}, nil
+		},
+	})
+	require.NoError(t, err)
+	var runMiddlewareSteps int
+	router := gin.New()
+	Register(router.Group("/pipelines"), s, Middlewares{
+		Run: []gin.HandlerFunc{func(c *gin.Context) {
+			runMiddlewareSteps = len(RunRequestFromContext(c).Spec.Steps)
+		}},
+	})
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	steps := `[
+		{"id": "search", "plugin": "googlesearch", "params": {"query": {"$path": "$.query"}}},
+		{"plugin": "screenshot", "params": {"urls": {"$path": "$.all[*].link"}}}
+	]`
+	spec := `{"steps": ` + steps + `}`
+	wantResult := `{
+		"steps": [
+			{"id": "search", "plugin": "googlesearch", "output": {"all": [{"link": "https://go.dev"}]}},
+			{"id": "screenshot", "plugin": "screenshot", "output": {"urls": ["https://go.dev"]}}
+		],
+		"result": {"urls": ["https://go.dev"]}
+	}`
+
+	resp := request(http.MethodPost, "/pipelines/run", `{"input": {"query": "golang"}, "steps": [{"plugin": "dne"}]}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.JSONEq(t, `{"error":{"code":"invalid_params","message":"step 0: plugin 'dne' is not loaded"}}`, resp.Body.String())
+
+	resp = request(http.MethodPost, "/pipelines/run", `{"input": {"query": "golang"}, "steps": `+steps+`}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, wantResult, resp.Body.String())
+	assert.Equal(t, 2, runMiddlewareSteps)
+
+	resp = request(http.MethodPost, "/pipelines/search/run", "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.JSONEq(t, `{"error":{"code":"not_found","message":"pipeline not found"}}`, resp.Body.String())
+
+	resp = request(http.MethodPut, "/pipelines/search", spec)
+	require.Equal(t, http.StatusCreated, resp.Code)
+	resp = request(http.MethodPut, "/pipelines/search", spec)
+	require.Equal(t, http.StatusOK, resp.Code)
+	var saved Pipeline
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &saved))
+	assert.Equal(t, "search", saved.Name)
+	assert.Equal(t, "screenshot", saved.Steps[1].ID)
+
+	resp = request(http.MethodGet, "/pipelines", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var list struct {
+		Pipelines []Pipeline `json:"pipelines"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &list))
+	require.Len(t, list.Pipelines, 1)
+
+	resp = request(http.MethodGet, "/pipelines/search", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	resp = request(http.MethodPost, "/pipelines/search/run", `{"input": {"query": "golang"}}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, wantResult, resp.Body.String())
+
+	resp = request(http.MethodPost, "/pipelines/search/run", `[`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp = request(http.MethodDelete, "/pipelines/search", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"message":"pipeline deleted"}`, resp.Body.String())
+
+	resp = request(http.MethodGet, "/pipelines/search", "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
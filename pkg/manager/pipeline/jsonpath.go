@@ -0,0 +1,218 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Path is a JSONPath expression. The supported subset is the root $, the
+// child keys .key, ['key'] and ["key"], the array indexes [0] and [-1], the
+// slices [start:end] and the wildcards .* and [*].
+type Path struct {
+	expr     string
+	segments []segment
+}
+
+type segmentKind int
+
+const (
+	segmentKey segmentKind = iota
+	segmentIndex
+	segmentWildcard
+	segmentSlice
+)
+
+type segment struct {
+	kind  segmentKind
+	key   string
+	index int
+	// start and end are the bounds of a slice, nil if omitted.
+	start, end *int
+}
+
+// ParsePath parses the JSONPath expression.
+func ParsePath(expr string) (*Path, error) {
+	rest, ok := strings.CutPrefix(expr, "$")
+	if !ok {
+		return nil, fmt.Errorf("'%s' must start with $", expr)
+	}
+	p := &Path{expr: expr}
+	for rest != "" {
+		var (
+			seg segment
+			err error
+		)
+		switch rest[0] {
+		case '.':
+			seg, rest, err = parseDot(rest[1:])
+		case '[':
+			seg, rest, err = parseBracket(rest[1:])
+		default:
+			err = fmt.Errorf("unexpected '%c'", rest[0])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a valid path: %w", expr, err)
+		}
+		p.segments = append(p.segments, seg)
+	}
+	return p, nil
+}
+
+func parseDot(rest string) (segment, string, error) {
+	if strings.HasPrefix(rest, ".") {
+		return segment{}, "", fmt.Errorf("recursive descent is not supported")
+	}
+	if strings.HasPrefix(rest, "*") {
+		return segment{kind: segmentWildcard}, rest[1:], nil
+	}
+	end := strings.IndexAny(rest, ".[")
+	if end == -1 {
+		end = len(rest)
+	}
+	if end == 0 {
+		return segment{}, "", fmt.Errorf("missing key after '.'")
+	}
+	return segment{kind: segmentKey, key: rest[:end]}, rest[end:], nil
+}
+
+func parseBracket(rest string) (segment, string, error) {
+	if rest != "" && (rest[0] == '\'' || rest[0] == '"') {
+		quote := rest[0]
+		end := strings.IndexByte(rest[1:], quote)
+		if end == -1 || !strings.HasPrefix(rest[end+2:], "]") {
+			return segment{}, "", fmt.Errorf("unterminated key")
+		}
+		return segment{kind: segmentKey, key: rest[1 : end+1]}, rest[end+3:], nil
+	}
+	end := strings.IndexByte(rest, ']')
+	if end == -1 {
+		return segment{}, "", fmt.Errorf("missing ']'")
+	}
+	inner, rest := strings.TrimSpace(rest[:end]), rest[end+1:]
+	if inner == "*" {
+		return segment{kind: segmentWildcard}, rest, nil
+	}
+	if from, to, ok := strings.Cut(inner, ":"); ok {
+		seg := segment{kind: segmentSlice}
+		var err error
+		if seg.start, err = parseBound(from); err != nil {
+			return segment{}, "", err
+		}
+		if seg.end, err = parseBound(to); err != nil {
+			return segment{}, "", err
+		}
+		return seg, rest, nil
+	}
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return segment{}, "", fmt.Errorf("'%s' is not an index", inner)
+	}
+	return segment{kind: segmentIndex, index: index}, rest, nil
+}
+
+func parseBound(s string) (*int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, fmt.Errorf("'%s' is not a slice bound", s)
+	}
+	return &i, nil
+}
+
+func (p *Path) String() string {
+	return p.expr
+}
+
+// Definite reports whether the path selects a single value, i.e. it has no
+// wildcards or slices.
+func (p *Path) Definite() bool {
+	for _, seg := range p.segments {
+		if seg.kind == segmentWildcard || seg.kind == segmentSlice {
+			return false
+		}
+	}
+	return true
+}
+
+// Eval selects the values of the path in the decoded JSON document. A definite
+// path returns the value it selects and fails if there is none. Other paths
+// return the array of the selected values, which may be empty.
+func (p *Path) Eval(doc any) (any, error) {
+	nodes := []any{doc}
+	for _, seg := range p.segments {
+		next := make([]any, 0, len(nodes))
+		for _, node := range nodes {
+			next = seg.apply(node, next)
+		}
+		nodes = next
+	}
+	if !p.Definite() {
+		return nodes, nil
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("'%s' matched nothing", p.expr)
+	}
+	return nodes[0], nil
+}
+
+// apply appends the children of the node selected by the segment.
+func (seg segment) apply(node any, out []any) []any {
+	switch seg.kind {
+	case segmentKey:
+		if obj, ok := node.(map[string]any); ok {
+			if value, ok := obj[seg.key]; ok {
+				out = append(out, value)
+			}
+		}
+	case segmentIndex:
+		if arr, ok := node.([]any); ok {
+			i := seg.index
+			if i < 0 {
+				i += len(arr)
+			}
+			if i >= 0 && i < len(arr) {
+				out = append(out, arr[i])
+			}
+		}
+	case segmentWildcard:
+		switch v := node.(type) {
+		case []any:
+			out = append(out, v...)
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				out = append(out, v[key])
+			}
+		}
+	case segmentSlice:
+		if arr, ok := node.([]any); ok {
+			start, end := bound(seg.start, 0, len(arr)), bound(seg.end, len(arr), len(arr))
+			if start < end {
+				out = append(out, arr[start:end]...)
+			}
+		}
+	}
+	return out
+}
+
+// bound resolves a slice bound against the array length, counting the
+// negative bounds from the end.
+func bound(b *int, defaultValue, length int) int {
+	if b == nil {
+		return defaultValue
+	}
+	i := *b
+	if i < 0 {
+		i += length
+	}
+	return min(max(i, 0), length)
+}
@@ -0,0 +1,76 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPath(t *testing.T) {
+	var doc any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"query": "golang",
+		"all": [
+			{"link": "https://go.dev", "title": "Go"},
+			{"link": "https://pkg.go.dev", "title": "Packages"},
+			{"link": "https://go.dev/blog", "title": "Blog"}
+		],
+		"stats": {"b": 2, "a": 1},
+		"odd key": true
+	}`), &doc))
+
+	tests := []struct {
+		expr string
+		want any
+	}{
+		{"$", doc},
+		{"$.query", "golang"},
+		{"$['query']", "golang"},
+		{`$["odd key"]`, true},
+		{"$.all[0].link", "https://go.dev"},
+		{"$.all[-1].title", "Blog"},
+		{"$.all[*].link", []any{"https://go.dev", "https://pkg.go.dev", "https://go.dev/blog"}},
+		{"$.all.*.title", []any{"Go", "Packages", "Blog"}},
+		{"$.all[:2].title", []any{"Go", "Packages"}},
+		{"$.all[1:].title", []any{"Packages", "Blog"}},
+		{"$.all[-2:-1].title", []any{"Packages"}},
+		{"$.stats[*]", []any{float64(1), float64(2)}},
+		{"$.missing[*]", []any{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			path, err := ParsePath(tt.expr)
+			require.NoError(t, err)
+			got, err := path.Eval(doc)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	path, err := ParsePath("$.all[5].link")
+	require.NoError(t, err)
+	_, err = path.Eval(doc)
+	assert.EqualError(t, err, "'$.all[5].link' matched nothing")
+}
+
+func TestParsePath_Invalid(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"all[0]", "'all[0]' must start with $"},
+		{"$..link", "'$..link' is not a valid path: recursive descent is not supported"},
+		{"$.", "'$.' is not a valid path: missing key after '.'"},
+		{"$[0", "'$[0' is not a valid path: missing ']'"},
+		{"$['key]", "'$['key]' is not a valid path: unterminated key"},
+		{"$[a]", "'$[a]' is not a valid path: 'a' is not an index"},
+		{"$[1:x]", "'$[1:x]' is not a valid path: 'x' is not a slice bound"},
+		{"$all", "'$all' is not a valid path: unexpected 'a'"},
+	}
+	for _, tt := range tests {
+		_, err := ParsePath(tt.expr)
+		assert.EqualError(t, err, tt.err, tt.expr)
+	}
+}
@@ -0,0 +1,207 @@
+// Package pipeline runs plugins one after another, feeding the outputs of the
+// previous steps into the params of the next ones, and keeps the pipelines
+// saved by name.
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+)
+
+const (
+	// InputStep is the name the steps use to reference the input of the run.
+	InputStep = "input"
+
+	// refKey is the key of a param that references the output of a step, e.g.
+	// {"$path": "$.all[*].link", "step": "search"}.
+	refKey     = "$path"
+	refStepKey = "step"
+
+	// maxSteps is the maximum number of steps of a pipeline.
+	maxSteps = 10
+)
+
+// namePattern matches the names of the pipelines and the IDs of the steps.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Spec is the user-defined part of a pipeline.
+type Spec struct {
+	Steps []Step `json:"steps"`
+}
+
+// Step runs the plugin with the params. A param can reference the output of a
+// previous step, or the input of the run, with an object like
+// {"$path": "$.all[*].link", "step": "search"}. The step defaults to the
+// previous one, or to the input for the first step.
+type Step struct {
+	// ID names the step in the references and the results. Default: the plugin name.
+	ID     string         `json:"id,omitempty"`
+	Plugin string         `json:"plugin"`
+	Params map[string]any `json:"params"`
+}
+
+// Pipeline is a saved pipeline.
+type Pipeline struct {
+	Spec
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Result is the result of a pipeline run.
+type Result struct {
+	// Steps are the results of the steps that ran, in their order. The steps
+	// after a failed one do not run.
+	Steps []StepResult `json:"steps"`
+	// Result is the output of the last step, nil if a step failed.
+	Result map[string]any `json:"result,omitempty"`
+	// FailedStep is the ID of the failed step, if any.
+	FailedStep string `json:"failedStep,omitempty"`
+}
+
+// StepResult is either the output of the step or the error of its run.
+type StepResult struct {
+	ID     string            `json:"id"`
+	Plugin string            `json:"plugin"`
+	Output map[string]any    `json:"output,omitempty"`
+	Error  *helper.ErrorBody `json:"error,omitempty"`
+}
+
+// ValidationError is returned for invalid pipeline specs.
+type ValidationError struct {
+	msg string
+}
+
+func (e *ValidationError) Error() string {
+	return e.msg
+}
+
+func invalid(format string, args ...any) error {
+	return &ValidationError{msg: fmt.Sprintf(format, args...)}
+}
+
+// ref is a reference to the output of a step.
+type ref struct {
+	step string
+	path *Path
+}
+
+// validate checks the spec against the loaded plugins, sets the default step
+// IDs and checks that the references point to the previous steps.
+func (s *Spec) validate(plugins map[string]bool) error {
+	if len(s.Steps) == 0 {
+		return invalid("'steps' must not be empty")
+	}
+	if len(s.Steps) > maxSteps {
+		return invalid("'steps' must not contain more than %d steps", maxSteps)
+	}
+	seen := map[string]bool{InputStep: true}
+	previous := InputStep
+	for i := range s.Steps {
+		step := &s.Steps[i]
+		if !plugins[step.Plugin] {
+			return invalid("step %d: plugin '%s' is not loaded", i, step.Plugin)
+		}
+		if step.ID == "" {
+			step.ID = step.Plugin
+		}
+		if !namePattern.MatchString(step.ID) {
+			return invalid("step %d: 'id' must be up to 64 letters, digits, '_' or '-'", i)
+		}
+		if seen[step.ID] {
+			return invalid("step %d: id '%s' is not unique, set the 'id' of the step", i, step.ID)
+		}
+		if step.Params == nil {
+			step.Params = make(map[string]any)
+		}
+		err := walkRefs(step.Params, previous, func(r ref) error {
+			if !seen[r.step] {
+				return fmt.Errorf("step '%s' is not a previous step", r.step)
+			}
+			return nil
+		})
+		if err != nil {
+			return invalid("step '%s': %v", step.ID, err)
+		}
+		seen[step.ID] = true
+		previous = step.ID
+	}
+	return nil
+}
+
+// parseRef returns the reference if the value is a reference object.
+func parseRef(value any, previous string) (ref, bool, error) {
+	obj, ok := value.(map[string]any)
+	if !ok {
+		return ref{}, false, nil
+	}
+	rawPath, ok := obj[refKey]
+	if !ok {
+		return ref{}, false, nil
+	}
+	expr, ok := rawPath.(string)
+	if !ok {
+		return ref{}, true, fmt.Errorf("'%s' must be a string", refKey)
+	}
+	r := ref{step: previous}
+	for key, v := range obj {
+		switch key {
+		case refKey:
+		case refStepKey:
+			if r.step, ok = v.(string); !ok {
+				return ref{}, true, fmt.Errorf("'%s' of a reference must be a string", refStepKey)
+			}
+		default:
+			return ref{}, true, fmt.Errorf("unknown reference key '%s'", key)
+		}
+	}
+	path, err := ParsePath(expr)
+	if err != nil {
+		return ref{}, true, err
+	}
+	r.path = path
+	return r, true, nil
+}
+
+// walkRefs calls fn for every reference in the params.
+func walkRefs(value any, previous string, fn func(r ref) error) error {
+	_, err := resolve(value, previous, func(r ref) (any, error) {
+		return nil, fn(r)
+	})
+	return err
+}
+
+// resolve returns the value with the references replaced by the values
+// returned by fn.
+func resolve(value any, previous string, fn func(r ref) (any, error)) (any, error) {
+	r, isRef, err := parseRef(value, previous)
+	if err != nil {
+		return nil, err
+	}
+	if isRef {
+		return fn(r)
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		resolved := make(map[string]any, len(v))
+		for key, item := range v {
+			if resolved[key], err = resolve(item, previous, fn); err != nil {
+				return nil, err
+			}
+		}
+		return resolved, nil
+	case []any:
+		resolved := make([]any, len(v))
+		for i, item := range v {
+			if resolved[i], err = resolve(item, previous, fn); err != nil {
+				return nil, err
+			}
+		}
+		return resolved, nil
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,228 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/bazuker/browserbro/pkg/fs"
+	"github.com/bazuker/browserbro/pkg/fs/mock"
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecValidate(t *testing.T) {
+	plugins := map[string]bool{"googlesearch": true, "screenshot": true}
+
+	spec := Spec{Steps: []Step{
+		{Plugin: "googlesearch", Params: map[string]any{"query": map[string]any{"$path": "$.query"}}},
+		{Plugin: "screenshot", Params: map[string]any{"urls": map[string]any{"$path": "$.all[*].link"}}},
+		{ID: "again", Plugin: "screenshot", Params: map[string]any{
+			"urls": []any{map[string]any{"$path": "$.all[0].link", "step": "googlesearch"}},
+		}},
+	}}
+	require.NoError(t, spec.validate(plugins))
+	assert.Equal(t, "googlesearch", spec.Steps[0].ID)
+	assert.Equal(t, "screenshot", spec.Steps[1].ID)
+
+	tests := []struct {
+		name  string
+		steps []Step
+		err   string
+	}{
+		{"no steps", nil, "'steps' must not be empty"},
+		{"too many steps", make([]Step, maxSteps+1), "'steps' must not contain more than 10 steps"},
+		{"unknown plugin", []Step{{Plugin: "dne"}}, "step 0: plugin 'dne' is not loaded"},
+		{"invalid id", []Step{{ID: "a b", Plugin: "screenshot"}}, "step 0: 'id' must be up to 64 letters"},
+		{"reserved id", []Step{{ID: "input", Plugin: "screenshot"}}, "step 0: id 'input' is not unique"},
+		{"duplicate id", []Step{{Plugin: "screenshot"}, {Plugin: "screenshot"}}, "step 1: id 'screenshot' is not unique"},
+		{
+			"later step",
+			[]Step{{Plugin: "screenshot", Params: map[string]any{"urls": map[string]any{"$path": "$", "step": "googlesearch"}}}, {Plugin: "googlesearch"}},
+			"step 'screenshot': step 'googlesearch' is not a previous step",
+		},
+		{
+			"invalid path",
+			[]Step{{Plugin: "screenshot", Params: map[string]any{"urls": map[string]any{"$path": "$..link"}}}},
+			"step 'screenshot': '$..link' is not a valid path",
+		},
+		{
+			"unknown key",
+			[]Step{{Plugin: "screenshot", Params: map[string]any{"urls": map[string]any{"$path": "$", "from": "input"}}}},
+			"step 'screenshot': unknown reference key 'from'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := Spec{Steps: tt.steps}
+			err := spec.validate(plugins)
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestService_Run(t *testing.T) {
+	var calls []map[string]any
+	s, err := New(Config{
+		FileStore: newMemoryStore().fileStore(),
+		Plugins:   []string{"googlesearch", "screenshot", "error"},
+		Run: func(ctx context.Context, plugin string, params map[string]any) (map[string]any, error) {
+			calls = append(calls, params)
+			switch plugin {
+			case "googlesearch":
+				return map[string]any{"all": []map[string]string{
+					{"link": "https://go.dev"},
+					{"link": "https://pkg.go.dev"},
+				}}, nil
+			case "screenshot":
+				return map[string]any{"files": []string{"Xk3_a9Qz.screenshot.png"}}, nil
+			}
+			return nil, errors.New("plugin error")
+		},
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("feeds the outputs into the next steps", func(t *testing.T) {
+		calls = nil
+		spec := Spec{Steps: []Step{
+			{Plugin: "googlesearch", Params: map[string]any{"query": map[string]any{"$path": "$.query"}}},
+			{Plugin: "screenshot", Params: map[string]any{"urls": map[string]any{"$path": "$.all[*].link"}}},
+		}}
+		require.NoError(t, s.Validate(&spec))
+		result := s.Run(ctx, "", spec, map[string]any{"query": "golang"})
+
+		assert.Equal(t, []map[string]any{
+			{"query": "golang"},
+			{"urls": []any{"https://go.dev", "https://pkg.go.dev"}},
+		}, calls)
+		require.Len(t, result.Steps, 2)
+		assert.Equal(t, "googlesearch", result.Steps[0].ID)
+		assert.Equal(t, map[string]any{"files": []any{"Xk3_a9Qz.screenshot.png"}}, result.Result)
+		assert.Empty(t, result.FailedStep)
+	})
+
+	t.Run("stops at the failed step", func(t *testing.T) {
+		spec := Spec{Steps: []Step{
+			{Plugin: "googlesearch"},
+			{Plugin: "error"},
+			{Plugin: "screenshot"},
+		}}
+		require.NoError(t, s.Validate(&spec))
+		result := s.Run(ctx, "", spec, nil)
+
+		require.Len(t, result.Steps, 2)
+		assert.Equal(t, &helper.ErrorBody{Code: helper.CodePluginFailed, Message: "plugin error"}, result.Steps[1].Error)
+		assert.Equal(t, "error", result.FailedStep)
+		assert.Nil(t, result.Result)
+	})
+
+	t.Run("unmatched path", func(t *testing.T) {
+		spec := Spec{Steps: []Step{
+			{Plugin: "screenshot", Params: map[string]any{"urls": []any{map[string]any{"$path": "$.url"}}}},
+		}}
+		require.NoError(t, s.Validate(&spec))
+		result := s.Run(ctx, "", spec, nil)
+
+		require.Len(t, result.Steps, 1)
+		assert.Equal(t, &helper.ErrorBody{
+			Code:    helper.CodeInvalidParams,
+			Message: "step 'input': '$.url' matched nothing",
+		}, result.Steps[0].Error)
+		assert.Equal(t, "screenshot", result.FailedStep)
+	})
+
+	t.Run("params reference", func(t *testing.T) {
+		calls = nil
+		spec := Spec{Steps: []Step{
+			{Plugin: "googlesearch", Params: map[string]any{"$path": "$"}},
+			{Plugin: "screenshot", Params: map[string]any{"$path": "$.all"}},
+		}}
+		require.NoError(t, s.Validate(&spec))
+		result := s.Run(ctx, "", spec, map[string]any{"query": "golang"})
+
+		assert.Equal(t, []map[string]any{{"query": "golang"}}, calls)
+		require.Len(t, result.Steps, 2)
+		assert.Equal(t, &helper.ErrorBody{
+			Code:    helper.CodeInvalidParams,
+			Message: "step 'screenshot': params must resolve to an object",
+		}, result.Steps[1].Error)
+		assert.Equal(t, "screenshot", result.FailedStep)
+	})
+}
+
+func TestService_Saved(t *testing.T) {
+	store := newMemoryStore()
+	cfg := Config{
+		FileStore: store.fileStore(),
+		Plugins:   []string{"googlesearch"},
+		Run: func(ctx context.Context, plugin string, params map[string]any) (map[string]any, error) {
+			return params, nil
+		},
+	}
+	s, err := New(cfg)
+	require.NoError(t, err)
+
+	_, _, err = s.Save("bad name", Spec{Steps: []Step{{Plugin: "googlesearch"}}})
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	p, created, err := s.Save("search", Spec{Steps: []Step{{Plugin: "googlesearch"}}})
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "googlesearch", p.Steps[0].ID)
+
+	p, created, err = s.Save("search", Spec{Steps: []Step{{ID: "find", Plugin: "googlesearch"}}})
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	// The pipelines survive a restart.
+	store.mu.Lock()
+	assert.NotEmpty(t, store.objects[fs.StateKey("pipelines.json")])
+	store.mu.Unlock()
+	s, err = New(cfg)
+	require.NoError(t, err)
+	loaded, err := s.Get("search")
+	require.NoError(t, err)
+	assert.Equal(t, "find", loaded.Steps[0].ID)
+	assert.True(t, p.CreatedAt.Equal(loaded.CreatedAt))
+	assert.Len(t, s.List(), 1)
+
+	require.NoError(t, s.Delete("search"))
+	assert.ErrorIs(t, s.Delete("search"), ErrNotFound)
+	_, err = s.Get("search")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{objects: make(map[string][]byte)}
+}
+
+func (s *memoryStore) fileStore() *mock.FileStore {
+	return &mock.FileStore{
+		PutObjectFn: func(object []byte, key string) error {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.objects[key] = object
+			return nil
+		},
+		GetObjectFn: func(key string) ([]byte, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			object, ok := s.objects[key]
+			if !ok {
+				return nil, fs.ErrorFileNotFound
+			}
+			return object, nil
+		},
+	}
+}
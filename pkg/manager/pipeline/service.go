@@ -0,0 +1,271 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/fs"
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/bazuker/browserbro/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// pipelinesKey is the file store key of the saved pipelines.
+var pipelinesKey = fs.StateKey("pipelines.json")
+
+var ErrNotFound = errors.New("pipeline not found")
+
+// RunFunc runs the plugin with the params.
+type RunFunc func(ctx context.Context, plugin string, params map[string]any) (map[string]any, error)
+
+type Config struct {
+	// FileStore stores the saved pipelines (required).
+	FileStore fs.FileStore
+	// Run runs the plugins (required).
+	Run RunFunc
+	// Plugins are the names of the plugins that can be used in the steps.
+	Plugins []string
+	// DescribeError converts the error of a step into the error of its result.
+	// Default: the plugin_failed code with the error message.
+	DescribeError func(err error) helper.ErrorBody
+}
+
+// Service keeps the saved pipelines and runs the pipelines.
+type Service struct {
+	fileStore     fs.FileStore
+	run           RunFunc
+	plugins       map[string]bool
+	describeError func(err error) helper.ErrorBody
+	now           func() time.Time
+
+	mu        sync.Mutex
+	pipelines map[string]Pipeline
+}
+
+func New(cfg Config) (*Service, error) {
+	if cfg.FileStore == nil {
+		return nil, errors.New("file store is required")
+	}
+	if cfg.Run == nil {
+		return nil, errors.New("run function is required")
+	}
+	if cfg.DescribeError == nil {
+		cfg.DescribeError = func(err error) helper.ErrorBody {
+			return helper.ErrorBody{Code: helper.CodePluginFailed, Message: err.Error()}
+		}
+	}
+
+	s := &Service{
+		fileStore:     cfg.FileStore,
+		run:           cfg.Run,
+		plugins:       make(map[string]bool, len(cfg.Plugins)),
+		describeError: cfg.DescribeError,
+		now:           time.Now,
+		pipelines:     make(map[string]Pipeline),
+	}
+	for _, name := range cfg.Plugins {
+		s.plugins[name] = true
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// List returns the saved pipelines in the order of their names.
+func (s *Service) List() []Pipeline {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pipelines := make([]Pipeline, 0, len(s.pipelines))
+	for _, p := range s.pipelines {
+		pipelines = append(pipelines, p)
+	}
+	sort.Slice(pipelines, func(i, j int) bool {
+		return pipelines[i].Name < pipelines[j].Name
+	})
+	return pipelines
+}
+
+func (s *Service) Get(name string) (Pipeline, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pipelines[name]
+	if !ok {
+		return Pipeline{}, ErrNotFound
+	}
+	return p, nil
+}
+
+// Save creates or replaces the pipeline and reports whether it was created.
+func (s *Service) Save(name string, spec Spec) (Pipeline, bool, error) {
+	if !namePattern.MatchString(name) {
+		return Pipeline{}, false, invalid("the name must be up to 64 letters, digits, '_' or '-'")
+	}
+	if err := s.Validate(&spec); err != nil {
+		return Pipeline{}, false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now().UTC()
+	previous, exists := s.pipelines[name]
+	p := Pipeline{Spec: spec, Name: name, CreatedAt: now, UpdatedAt: now}
+	if exists {
+		p.CreatedAt = previous.CreatedAt
+	}
+	s.pipelines[name] = p
+	if err := s.save(); err != nil {
+		if exists {
+			s.pipelines[name] = previous
+		} else {
+			delete(s.pipelines, name)
+		}
+		return Pipeline{}, false, err
+	}
+	return p, !exists, nil
+}
+
+func (s *Service) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pipelines[name]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.pipelines, name)
+	if err := s.save(); err != nil {
+		s.pipelines[name] = p
+		return err
+	}
+	return nil
+}
+
+// Validate checks the spec and sets the default step IDs.
+func (s *Service) Validate(spec *Spec) error {
+	return spec.validate(s.plugins)
+}
+
+// Run runs the steps of the validated spec one after another and stops at the
+// first failed step. The name is only used for tracing, empty for ad hoc runs.
+func (s *Service) Run(ctx context.Context, name string, spec Spec, input map[string]any) Result {
+	spanName := "pipeline"
+	if name != "" {
+		spanName += " " + name
+	}
+	ctx, span := tracing.Start(ctx, spanName, trace.WithAttributes(
+		attribute.String("pipeline.name", name),
+		attribute.Int("pipeline.steps", len(spec.Steps)),
+	))
+
+	if input == nil {
+		input = make(map[string]any)
+	}
+	outputs := map[string]any{InputStep: input}
+	previous := InputStep
+	result := Result{Steps: make([]StepResult, 0, len(spec.Steps))}
+	var err error
+	for _, step := range spec.Steps {
+		stepResult := StepResult{ID: step.ID, Plugin: step.Plugin}
+		var output map[string]any
+		output, err = s.runStep(ctx, step, previous, outputs)
+		if err != nil {
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) {
+				stepResult.Error = &helper.ErrorBody{Code: helper.CodeInvalidParams, Message: err.Error()}
+			} else {
+				body := s.describeError(err)
+				stepResult.Error = &body
+			}
+			result.Steps = append(result.Steps, stepResult)
+			result.FailedStep = step.ID
+			break
+		}
+		stepResult.Output = output
+		result.Steps = append(result.Steps, stepResult)
+		outputs[step.ID] = output
+		previous = step.ID
+		result.Result = output
+	}
+	if err != nil {
+		result.Result = nil
+	}
+	tracing.End(span, err)
+	return result
+}
+
+// runStep resolves the references in the params of the step and runs it.
+func (s *Service) runStep(ctx context.Context, step Step, previous string, outputs map[string]any) (map[string]any, error) {
+	params, err := resolve(step.Params, previous, func(r ref) (any, error) {
+		value, err := r.path.Eval(outputs[r.step])
+		if err != nil {
+			return nil, fmt.Errorf("step '%s': %w", r.step, err)
+		}
+		return value, nil
+	})
+	if err != nil {
+		return nil, invalid("%v", err)
+	}
+	// The params may be a reference as a whole.
+	resolved, ok := params.(map[string]any)
+	if !ok {
+		return nil, invalid("step '%s': params must resolve to an object", step.ID)
+	}
+	output, err := s.run(ctx, step.Plugin, resolved)
+	if err != nil {
+		return nil, err
+	}
+	return normalize(output)
+}
+
+// normalize converts the output to its JSON form, so that the paths see the
+// same values as the clients.
+func normalize(output map[string]any) (map[string]any, error) {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the output: %w", err)
+	}
+	var normalized map[string]any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, fmt.Errorf("failed to decode the output: %w", err)
+	}
+	return normalized, nil
+}
+
+func (s *Service) load() error {
+	data, err := s.fileStore.GetObject(pipelinesKey)
+	if errors.Is(err, fs.ErrorFileNotFound) || (err == nil && len(data) == 0) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load pipelines: %w", err)
+	}
+	var pipelines []Pipeline
+	if err := json.Unmarshal(data, &pipelines); err != nil {
+		return fmt.Errorf("failed to load pipelines: %w", err)
+	}
+	for _, p := range pipelines {
+		s.pipelines[p.Name] = p
+	}
+	return nil
+}
+
+// save persists the pipelines. The caller must hold the lock.
+func (s *Service) save() error {
+	pipelines := make([]Pipeline, 0, len(s.pipelines))
+	for _, p := range s.pipelines {
+		pipelines = append(pipelines, p)
+	}
+	data, err := json.Marshal(pipelines)
+	if err != nil {
+		return fmt.Errorf("failed to save pipelines: %w", err)
+	}
+	if err := s.fileStore.PutObject(data, pipelinesKey); err != nil {
+		return fmt.Errorf("failed to save pipelines: %w", err)
+	}
+	return nil
+}
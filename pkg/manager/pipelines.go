@@ -0,0 +1,52 @@
+package manager
+
+import (
+	"context"
+
+	"github.com/bazuker/browserbro/pkg/manager/auth"
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/bazuker/browserbro/pkg/manager/pipeline"
+	"github.com/bazuker/browserbro/pkg/manager/ratelimit"
+	pluginsRegistry "github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/gin-gonic/gin"
+)
+
+// pipelineClientKey is the context key of the client of a pipeline run.
+type pipelineClientKey struct{}
+
+type pipelineClient struct {
+	id      string
+	session helper.SessionData
+}
+
+// pipelineClient checks that the API key grants the scopes of all the plugins
+// of the pipeline, saved pipelines need no extra scope to run. It adds the
+// client to the context of the run, see runPipelineStep.
+func (m *Manager) pipelineClient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, step := range pipeline.RunRequestFromContext(c).Spec.Steps {
+			if !m.authorizeRun(c, step.Plugin, step.Params) {
+				return
+			}
+		}
+		session, _ := auth.Session(c)
+		client := pipelineClient{id: ratelimit.ClientID(c), session: session}
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), pipelineClientKey{}, client))
+		c.Next()
+	}
+}
+
+// runPipelineStep runs a step once the references in its params are resolved.
+// Like the plugin endpoints, it checks the scopes of the plugins run with the
+// params and takes the cost of the step from the bucket of the client.
+func (m *Manager) runPipelineStep(ctx context.Context, name string, params map[string]any) (map[string]any, error) {
+	if client, ok := ctx.Value(pipelineClientKey{}).(pipelineClient); ok {
+		if err := m.checkScopes(client.session, name, params); err != nil {
+			return nil, err
+		}
+		if err := m.limiter.Charge(client.id, pluginsRegistry.Cost(m.plugin(name), params)); err != nil {
+			return nil, err
+		}
+	}
+	return m.runPlugin(ctx, name, params)
+}
@@ -0,0 +1,149 @@
+package manager
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bazuker/browserbro/pkg/fs"
+	"github.com/bazuker/browserbro/pkg/fs/mock"
+	"github.com/bazuker/browserbro/pkg/manager/auth"
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/bazuker/browserbro/pkg/manager/ratelimit"
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_Pipelines(t *testing.T) {
+	objects := make(map[string][]byte)
+	m, err := New(Config{
+		ServerAddress: ":0",
+		FileStore: &mock.FileStore{
+			PutObjectFn: func(object []byte, key string) error {
+				objects[key] = object
+				return nil
+			},
+			GetObjectFn: func(key string) ([]byte, error) {
+				object, ok := objects[key]
+				if !ok {
+					return nil, fs.ErrorFileNotFound
+				}
+				return object, nil
+			},
+		},
+		APIKeys: []auth.Key{
+			{Name: "admin", Key: "admin-0123456789abcdef", Scopes: []string{"*"}},
+			{Name: "search", Key: "search-0123456789abcdef", Scopes: []string{"plugins:search", "plugins:costly", "plugins:nester"}},
+		},
+		RateLimit: ratelimit.Config{Rate: 0.01, Burst: 3},
+		Plugins: []plugins.Plugin{
+			&mockPlugin{
+				name: "search",
+				runFn: func(params map[string]interface{}) (map[string]interface{}, error) {
+					return map[string]interface{}{"links": []string{"https://go.dev"}}, nil
+				},
+			},
+			&mockPlugin{
+				name: "params",
+				runFn: func(params map[string]interface{}) (map[string]interface{}, error) {
+					return nil, plugins.ParamErrorf("'urls' parameter must be a list of URLs")
+				},
+			},
+			&costlyPlugin{mockPlugin: mockPlugin{name: "costly"}},
+			&nesterPlugin{mockPlugin: mockPlugin{name: "nester"}},
+		},
+	})
+	require.NoError(t, err)
+	m.browserConnector = &mockConnector{}
+
+	require.NoError(t, m.Run())
+	defer func() {
+		require.NoError(t, m.Stop())
+	}()
+
+	request := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set(helper.HeaderRequestID, testRequestID)
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		m.router.ServeHTTP(w, req)
+		return w
+	}
+	pipeline := `{"steps": [
+		{"plugin": "search"},
+		{"plugin": "params", "params": {"urls": {"$path": "$.links"}}}
+	]}`
+
+	resp := request(http.MethodPut, "/api/v1/pipelines/links", "search-0123456789abcdef", pipeline)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.JSONEq(
+		t,
+		`{"error":{"code":"forbidden","message":"API key 'search' is missing the 'pipelines' scope","requestId":"test-request"}}`,
+		resp.Body.String(),
+	)
+	resp = request(http.MethodPut, "/api/v1/pipelines/links", "admin-0123456789abcdef", pipeline)
+	require.Equal(t, http.StatusCreated, resp.Code)
+
+	resp = request(http.MethodPost, "/api/v1/pipelines/links/run", "search-0123456789abcdef", "")
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.JSONEq(
+		t,
+		`{"error":{"code":"forbidden","message":"API key 'search' is missing the 'plugins:params' scope","requestId":"test-request"}}`,
+		resp.Body.String(),
+	)
+
+	// The failed step is described like the plugin endpoints.
+	resp = request(http.MethodPost, "/api/v1/pipelines/links/run", "admin-0123456789abcdef", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{
+		"steps": [
+			{"id": "search", "plugin": "search", "output": {"links": ["https://go.dev"]}},
+			{"id": "params", "plugin": "params", "error": {"code": "invalid_params", "message": "'urls' parameter must be a list of URLs"}}
+		],
+		"failedStep": "params"
+	}`, resp.Body.String())
+
+	// The steps are checked once their params are resolved.
+	resp = request(http.MethodPost, "/api/v1/pipelines/run", "search-0123456789abcdef", `{
+		"steps": [{"plugin": "nester", "params": {"plugin": {"$path": "$.plugin"}}}],
+		"input": {"plugin": "params"}
+	}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{
+		"steps": [
+			{"id": "nester", "plugin": "nester", "error": {"code": "forbidden", "message": "API key 'search' is missing the 'plugins:params' scope"}}
+		],
+		"failedStep": "nester"
+	}`, resp.Body.String())
+	resp = request(http.MethodPost, "/api/v1/pipelines/run", "search-0123456789abcdef", `{
+		"steps": [{"plugin": "costly", "params": {"cost": {"$path": "$.cost"}}}],
+		"input": {"cost": 4}
+	}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{
+		"steps": [
+			{"id": "costly", "plugin": "costly", "error": {"code": "invalid_params", "message": "the request costs 4 tokens, more than the rate limit burst of 3"}}
+		],
+		"failedStep": "costly"
+	}`, resp.Body.String())
+
+	// Every step is charged when it starts.
+	resp = request(http.MethodPost, "/api/v1/pipelines/run", "search-0123456789abcdef", `{"steps": [
+		{"id": "a", "plugin": "search"},
+		{"id": "b", "plugin": "search"},
+		{"id": "c", "plugin": "search"},
+		{"id": "d", "plugin": "search"}
+	]}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{
+		"steps": [
+			{"id": "a", "plugin": "search", "output": {"links": ["https://go.dev"]}},
+			{"id": "b", "plugin": "search", "output": {"links": ["https://go.dev"]}},
+			{"id": "c", "plugin": "search", "output": {"links": ["https://go.dev"]}},
+			{"id": "d", "plugin": "search", "error": {"code": "rate_limited", "message": "rate limit exceeded, retry in 100s"}}
+		],
+		"failedStep": "d"
+	}`, resp.Body.String())
+}
@@ -0,0 +1,250 @@
+// Package ratelimit limits the request rate and the concurrent plugin runs of
+// every client. The clients are identified by the API key or, without
+// authentication, by the IP address.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/manager/auth"
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/gin-gonic/gin"
+)
+
+// cleanupInterval is how often the idle clients are forgotten.
+const cleanupInterval = time.Minute
+
+type Config struct {
+	// Rate is the number of tokens added to the bucket of a client per second.
+	// A request costs one token and a plugin run costs the plugin cost.
+	// The rate is not limited if it is zero.
+	Rate float64
+	// Burst is the size of the bucket. Default: the rate rounded up, at least 1.
+	Burst int
+	// MaxConcurrent is the maximum number of concurrent plugin runs per client.
+	// The runs are not limited if it is zero.
+	MaxConcurrent int
+}
+
+// Limiter keeps a token bucket and the running plugins count of every client.
+type Limiter struct {
+	rate          float64
+	burst         float64
+	maxConcurrent int
+	now           func() time.Time
+
+	mu          sync.Mutex
+	clients     map[string]*client
+	lastCleanup time.Time
+}
+
+type client struct {
+	tokens  float64
+	updated time.Time
+	running int
+}
+
+func New(cfg Config) *Limiter {
+	if cfg.Rate < 0 {
+		cfg.Rate = 0
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = max(int(math.Ceil(cfg.Rate)), 1)
+	}
+	return &Limiter{
+		rate:          cfg.Rate,
+		burst:         float64(cfg.Burst),
+		maxConcurrent: max(cfg.MaxConcurrent, 0),
+		now:           time.Now,
+		clients:       make(map[string]*client),
+	}
+}
+
+// Fits reports whether the cost fits in a full bucket. The requests that cost
+// more than the burst can never pass.
+func (l *Limiter) Fits(cost int) bool {
+	return l.rate == 0 || float64(cost) <= l.burst
+}
+
+// Take takes the cost from the bucket of the client. It returns how long to
+// wait for the tokens if there are not enough of them. A cost that does not
+// fit in the bucket is never taken and the wait is zero, see Fits.
+func (l *Limiter) Take(clientID string, cost int) (time.Duration, bool) {
+	if l.rate == 0 {
+		return 0, true
+	}
+	if !l.Fits(cost) {
+		return 0, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	c := l.client(clientID, now)
+	c.tokens = min(l.burst, c.tokens+now.Sub(c.updated).Seconds()*l.rate)
+	c.updated = now
+
+	need := float64(cost)
+	if c.tokens >= need {
+		c.tokens -= need
+		return 0, true
+	}
+	return time.Duration((need - c.tokens) / l.rate * float64(time.Second)), false
+}
+
+// Acquire reserves a plugin run slot of the client. The release function must
+// be called when the run completes.
+func (l *Limiter) Acquire(clientID string) (release func(), ok bool) {
+	if l.maxConcurrent == 0 {
+		return func() {}, true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c := l.client(clientID, l.now())
+	if c.running >= l.maxConcurrent {
+		return nil, false
+	}
+	c.running++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			c.running--
+		})
+	}, true
+}
+
+// AcquireUpTo reserves up to n plugin run slots of the client, as many as are
+// free, and returns how many it reserved. The release function frees all of them.
+func (l *Limiter) AcquireUpTo(clientID string, n int) (release func(), acquired int) {
+	if l.maxConcurrent == 0 {
+		return func() {}, n
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c := l.client(clientID, l.now())
+	acquired = max(min(n, l.maxConcurrent-c.running), 0)
+	c.running += acquired
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			c.running -= acquired
+		})
+	}, acquired
+}
+
+// Error describes why the cost was not taken, see Charge.
+type Error struct {
+	// Status is the HTTP status of the response.
+	Status int
+	// Code is the error code of the response.
+	Code    string
+	Message string
+	// RetryAfter is the number of seconds to wait, zero if waiting does not help.
+	RetryAfter int
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Charge takes the cost from the bucket of the client, see Take. It returns an
+// *Error if the bucket is empty or if the cost does not fit in the bucket.
+func (l *Limiter) Charge(clientID string, cost int) error {
+	if !l.Fits(cost) {
+		return &Error{
+			Status:  http.StatusBadRequest,
+			Code:    helper.CodeInvalidParams,
+			Message: fmt.Sprintf("the request costs %d tokens, more than the rate limit burst of %d", cost, int(l.burst)),
+		}
+	}
+	wait, ok := l.Take(clientID, cost)
+	if !ok {
+		seconds := int(math.Ceil(wait.Seconds()))
+		return &Error{
+			Status:     http.StatusTooManyRequests,
+			Code:       helper.CodeRateLimited,
+			Message:    fmt.Sprintf("rate limit exceeded, retry in %ds", seconds),
+			RetryAfter: seconds,
+		}
+	}
+	return nil
+}
+
+// RateLimit is a middleware that takes the cost of the request from the bucket
+// of the client and rejects it with 429 Too Many Requests if the bucket is
+// empty. The requests that cost more than the burst are rejected with 400 Bad
+// Request. The request costs 1 if cost is nil.
+func (l *Limiter) RateLimit(cost func(c *gin.Context) int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		n := 1
+		if cost != nil {
+			n = cost(c)
+		}
+		if err := l.Charge(ClientID(c), n); err != nil {
+			limitErr := err.(*Error)
+			if limitErr.RetryAfter > 0 {
+				c.Header("Retry-After", strconv.Itoa(limitErr.RetryAfter))
+			}
+			helper.AbortWithError(c, limitErr.Status, limitErr.Code, limitErr.Message)
+			return
+		}
+		c.Next()
+	}
+}
+
+// ConcurrencyLimit is a middleware that rejects the request with 429 Too Many
+// Requests if the client already runs the maximum number of plugins.
+func (l *Limiter) ConcurrencyLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		release, ok := l.Acquire(ClientID(c))
+		if !ok {
+			c.Header("Retry-After", "1")
+			helper.AbortWithError(c, http.StatusTooManyRequests, helper.CodeConcurrencyLimited,
+				fmt.Sprintf("too many concurrent plugin runs, the limit is %d", l.maxConcurrent))
+			return
+		}
+		defer release()
+		c.Next()
+	}
+}
+
+// ClientID identifies the client by the API key name or the IP address.
+func ClientID(c *gin.Context) string {
+	if session, ok := auth.Session(c); ok {
+		return KeyClientID(session.UserID)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// KeyClientID returns the client ID of the API key with the name.
+func KeyClientID(name string) string {
+	return "key:" + name
+}
+
+// client returns the state of the client and forgets the idle clients from
+// time to time. The caller must hold the lock.
+func (l *Limiter) client(id string, now time.Time) *client {
+	if now.Sub(l.lastCleanup) >= cleanupInterval {
+		l.lastCleanup = now
+		for clientID, c := range l.clients {
+			// A client is idle when nothing runs and its bucket is full again.
+			if c.running == 0 && (l.rate == 0 || now.Sub(c.updated).Seconds()*l.rate >= l.burst) {
+				delete(l.clients, clientID)
+			}
+		}
+	}
+	c, ok := l.clients[id]
+	if !ok {
+		c = &client{tokens: l.burst, updated: now}
+		l.clients[id] = c
+	}
+	return c
+}
@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Take(t *testing.T) {
+	l := New(Config{Rate: 2, Burst: 4})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	_, ok := l.Take("a", 3)
+	require.True(t, ok)
+	wait, ok := l.Take("a", 2)
+	require.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	// The clients have separate buckets.
+	_, ok = l.Take("b", 4)
+	assert.True(t, ok)
+
+	now = now.Add(500 * time.Millisecond)
+	_, ok = l.Take("a", 2)
+	assert.True(t, ok)
+
+	// A cost higher than the burst never passes, even with a full bucket.
+	now = now.Add(time.Hour)
+	assert.True(t, l.Fits(4))
+	assert.False(t, l.Fits(5))
+	wait, ok = l.Take("a", 5)
+	assert.False(t, ok)
+	assert.Zero(t, wait)
+	_, ok = l.Take("a", 4)
+	assert.True(t, ok)
+}
+
+func TestLimiter_Unlimited(t *testing.T) {
+	l := New(Config{})
+	for i := 0; i < 100; i++ {
+		_, ok := l.Take("a", 10)
+		require.True(t, ok)
+		_, ok = l.Acquire("a")
+		require.True(t, ok)
+	}
+}
+
+func TestLimiter_Acquire(t *testing.T) {
+	l := New(Config{MaxConcurrent: 2})
+	release1, ok := l.Acquire("a")
+	require.True(t, ok)
+	_, ok = l.Acquire("a")
+	require.True(t, ok)
+	_, ok = l.Acquire("a")
+	assert.False(t, ok)
+	_, ok = l.Acquire("b")
+	assert.True(t, ok)
+
+	release1()
+	release1()
+	_, ok = l.Acquire("a")
+	assert.True(t, ok)
+	_, ok = l.Acquire("a")
+	assert.False(t, ok)
+}
+
+func TestLimiter_AcquireUpTo(t *testing.T) {
+	l := New(Config{MaxConcurrent: 3})
+	_, ok := l.Acquire("a")
+	require.True(t, ok)
+	release, acquired := l.AcquireUpTo("a", 4)
+	assert.Equal(t, 2, acquired)
+	_, acquired = l.AcquireUpTo("a", 1)
+	assert.Zero(t, acquired)
+
+	release()
+	release()
+	_, acquired = l.AcquireUpTo("a", 1)
+	assert.Equal(t, 1, acquired)
+
+	_, acquired = New(Config{}).AcquireUpTo("a", 4)
+	assert.Equal(t, 4, acquired)
+}
+
+func TestLimiter_cleanup(t *testing.T) {
+	l := New(Config{Rate: 1, Burst: 2})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	l.Take("a", 1)
+	l.Take("b", 1)
+	now = now.Add(time.Second)
+	l.Take("b", 1)
+	require.Len(t, l.clients, 2)
+
+	now = now.Add(cleanupInterval)
+	l.Take("c", 1)
+	assert.Len(t, l.clients, 1)
+}
+
+func TestMiddleware(t *testing.T) {
+	l := New(Config{Rate: 1, Burst: 2, MaxConcurrent: 1})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	block := make(chan struct{})
+	r := gin.New()
+	r.GET("/status", l.RateLimit(nil), func(c *gin.Context) {})
+	r.POST("/run", l.RateLimit(func(c *gin.Context) int { return 2 }), func(c *gin.Context) {})
+	r.POST("/crawl", l.RateLimit(func(c *gin.Context) int { return 3 }), func(c *gin.Context) {})
+	r.POST("/slow", l.ConcurrencyLimit(), func(c *gin.Context) { <-block })
+	r.GET("/key", func(c *gin.Context) {
+		c.Set(helper.ContextSession, helper.SessionData{UserID: "ci"})
+		c.Next()
+	}, l.RateLimit(nil), func(c *gin.Context) {})
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/status").Code)
+	resp := request(http.MethodPost, "/run")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "1", resp.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":{"code":"rate_limited","message":"rate limit exceeded, retry in 1s"}}`, resp.Body.String())
+
+	// The cost is higher than the burst, waiting does not help.
+	resp = request(http.MethodPost, "/crawl")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Empty(t, resp.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":{"code":"invalid_params","message":"the request costs 3 tokens, more than the rate limit burst of 2"}}`, resp.Body.String())
+
+	// The API key is a separate client.
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/key").Code)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		request(http.MethodPost, "/slow")
+	}()
+	require.Eventually(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.clients["ip:10.0.0.1"].running == 1
+	}, time.Second, time.Millisecond)
+	resp = request(http.MethodPost, "/slow")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "1", resp.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":{"code":"concurrency_limited","message":"too many concurrent plugin runs, the limit is 1"}}`, resp.Body.String())
+	close(block)
+	<-done
+}
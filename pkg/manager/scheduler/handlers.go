@@ -0,0 +1,126 @@
+package scheduler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bazuker/browserbro/pkg/manager/auth"
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/gin-gonic/gin"
+)
+
+// Middlewares are added to the schedules endpoints.
+type Middlewares struct {
+	// Save run before the schedules are created or updated, once the spec is
+	// in the context.
+	Save []gin.HandlerFunc
+}
+
+// SpecFromContext returns the spec of the schedule being saved.
+func SpecFromContext(c *gin.Context) Spec {
+	return c.MustGet(helper.ContextSchedule).(Spec)
+}
+
+// Register adds the schedules endpoints to the router group. The save
+// middlewares see the spec in the context, see SpecFromContext.
+func Register(group *gin.RouterGroup, service *Service, middlewares Middlewares) {
+	h := &handlers{service: service}
+	save := func(handler gin.HandlerFunc) []gin.HandlerFunc {
+		handlers := append([]gin.HandlerFunc{h.bindSpec}, middlewares.Save...)
+		return append(handlers, handler)
+	}
+	group.GET("", h.list)
+	group.POST("", save(h.create)...)
+	group.GET("/:id", h.get)
+	group.PUT("/:id", save(h.update)...)
+	group.DELETE("/:id", h.delete)
+	group.GET("/:id/runs", h.runs)
+	group.POST("/:id/run", h.run)
+}
+
+type handlers struct {
+	service *Service
+}
+
+func (h *handlers) list(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"schedules": h.service.List()})
+}
+
+func (h *handlers) bindSpec(c *gin.Context) {
+	var spec Spec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		helper.AbortWithError(c, http.StatusBadRequest, helper.CodeInvalidParams, "invalid request body")
+		return
+	}
+	c.Set(helper.ContextSchedule, spec)
+}
+
+func (h *handlers) create(c *gin.Context) {
+	session, _ := auth.Session(c)
+	schedule, err := h.service.Create(SpecFromContext(c), session.UserID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, schedule)
+}
+
+func (h *handlers) get(c *gin.Context) {
+	schedule, err := h.service.Get(c.Param("id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (h *handlers) update(c *gin.Context) {
+	session, _ := auth.Session(c)
+	schedule, err := h.service.Update(c.Param("id"), SpecFromContext(c), session.UserID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (h *handlers) delete(c *gin.Context) {
+	if err := h.service.Delete(c.Param("id")); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, helper.HTTPMessage{Message: "schedule deleted"})
+}
+
+func (h *handlers) runs(c *gin.Context) {
+	runs, err := h.service.Runs(c.Param("id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+func (h *handlers) run(c *gin.Context) {
+	run, err := h.service.RunNow(c.Param("id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+func writeError(c *gin.Context, err error) {
+	var validationErr *ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		helper.AbortWithError(c, http.StatusBadRequest, helper.CodeInvalidParams, err.Error())
+	case errors.Is(err, ErrNotFound):
+		helper.AbortWithError(c, http.StatusNotFound, helper.CodeNotFound, err.Error())
+	case errors.Is(err, ErrBusy):
+		helper.AbortWithError(c, http.StatusConflict, helper.CodeConflict, err.Error())
+	default:
+		helper.Logger(c.Request.Context()).Error().Err(err).Msg("schedule request failed")
+		helper.AbortWithError(c, http.StatusInternalServerError, helper.CodeInternal, "internal server error")
+	}
+}
@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlers(t *testing.T) {
+	s, err := New(Config{
+		FileStore: newMemoryStore().fileStore(),
+		Plugins:   []string{"screenshot"},
+		Run: func(ctx context.Context, plugin string, params map[string]any) (map[string]any, error) {
+			assert.Equal(t, "admin", CreatorFromContext(ctx))
+			return map[string]any{"files": []any{"abc.screenshot.png"}}, nil
+		},
+	})
+	require.NoError(t, err)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(helper.ContextSession, helper.SessionData{UserID: "admin"})
+	})
+	Register(router.Group("/schedules"), s, Middlewares{Save: []gin.HandlerFunc{
+		func(c *gin.Context) {
+			if _, ok := SpecFromContext(c).Params["forbidden"]; ok {
+				helper.AbortWithError(c, http.StatusForbidden, helper.CodeForbidden, "forbidden")
+			}
+		},
+	}})
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	resp := request(http.MethodPost, "/schedules", `{"cron":"@daily","plugin":"dne"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.JSONEq(t, `{"error":{"code":"invalid_params","message":"plugin 'dne' is not loaded"}}`, resp.Body.String())
+
+	resp = request(http.MethodPost, "/schedules", `[`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.JSONEq(t, `{"error":{"code":"invalid_params","message":"invalid request body"}}`, resp.Body.String())
+
+	resp = request(http.MethodPost, "/schedules", `{"cron":"@daily","plugin":"screenshot","params":{"forbidden":true}}`)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	resp = request(http.MethodPost, "/schedules", `{"cron":"@daily","plugin":"screenshot","params":{"urls":["https://example.com"]}}`)
+	require.Equal(t, http.StatusCreated, resp.Code)
+	var created Schedule
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, "admin", created.CreatedBy)
+	assert.NotNil(t, created.NextRunAt)
+
+	resp = request(http.MethodGet, "/schedules", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var list struct {
+		Schedules []Schedule `json:"schedules"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &list))
+	require.Len(t, list.Schedules, 1)
+
+	resp = request(http.MethodPut, "/schedules/"+created.ID, `{"cron":"0 * * * *","plugin":"screenshot","params":{"forbidden":true}}`)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	resp = request(http.MethodPut, "/schedules/"+created.ID, `{"cron":"0 * * * *","plugin":"screenshot"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	resp = request(http.MethodPost, "/schedules/"+created.ID+"/run", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var run Run
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &run))
+	assert.Equal(t, TriggerManual, run.Trigger)
+
+	resp = request(http.MethodGet, "/schedules/"+created.ID+"/runs", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var runs struct {
+		Runs []Run `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &runs))
+	assert.Len(t, runs.Runs, 1)
+
+	resp = request(http.MethodDelete, "/schedules/"+created.ID, "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"message":"schedule deleted"}`, resp.Body.String())
+
+	resp = request(http.MethodGet, "/schedules/"+created.ID, "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.JSONEq(t, `{"error":{"code":"not_found","message":"schedule not found"}}`, resp.Body.String())
+}
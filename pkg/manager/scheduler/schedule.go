@@ -0,0 +1,110 @@
+// Package scheduler runs plugins on cron schedules and keeps the history of
+// the runs.
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// TriggerSchedule marks the runs started by the schedule.
+	TriggerSchedule = "schedule"
+	// TriggerManual marks the runs started through the API.
+	TriggerManual = "manual"
+
+	// RunSucceeded and RunFailed are the statuses of the completed runs.
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+
+	// maxJitter is the longest allowed jitter in seconds.
+	maxJitter = 3600
+)
+
+// cronParser accepts the standard five fields, the descriptors like "@daily"
+// and "@every 1h" and the CRON_TZ prefix.
+var cronParser = cron.NewParser(
+	cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// Spec is the user-defined part of a schedule.
+type Spec struct {
+	// Cron is the cron expression of the schedule, e.g. "0 6 * * *".
+	Cron string `json:"cron"`
+	// Plugin is the name of the plugin to run.
+	Plugin string `json:"plugin"`
+	// Params are the parameters of the plugin.
+	Params map[string]any `json:"params"`
+	// Jitter is the maximum number of seconds every run is randomly delayed by.
+	Jitter int `json:"jitter,omitempty"`
+	// Paused stops the schedule from running until it is resumed.
+	Paused bool `json:"paused,omitempty"`
+}
+
+// Schedule is a registered schedule with the state of its runs.
+type Schedule struct {
+	Spec
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// CreatedBy is the name of the API key that created or last updated the
+	// schedule, empty without authentication. The runs are made on behalf of
+	// the key.
+	CreatedBy string `json:"createdBy,omitempty"`
+
+	// NextRunAt is the time of the next run including the jitter, nil if paused.
+	NextRunAt *time.Time `json:"nextRunAt,omitempty"`
+	// LastRunAt is the start time of the last run.
+	LastRunAt *time.Time `json:"lastRunAt,omitempty"`
+	// Running reports whether the plugin is running.
+	Running bool `json:"running"`
+	// SkippedRuns is the number of runs skipped because the previous run was
+	// still in progress.
+	SkippedRuns int `json:"skippedRuns"`
+}
+
+// Run is a record of a plugin run. The output of the plugin is not kept, only
+// the files it stored.
+type Run struct {
+	Trigger    string    `json:"trigger"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	// Status is RunSucceeded or RunFailed.
+	Status string `json:"status"`
+	// Files are the names of the files referenced by the output of the plugin.
+	Files []string `json:"files,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// ValidationError is returned for invalid schedule specs.
+type ValidationError struct {
+	msg string
+}
+
+func (e *ValidationError) Error() string {
+	return e.msg
+}
+
+func invalid(format string, args ...any) error {
+	return &ValidationError{msg: fmt.Sprintf(format, args...)}
+}
+
+// validate checks the spec against the loaded plugins.
+func (s *Spec) validate(plugins map[string]bool) (cron.Schedule, error) {
+	schedule, err := cronParser.Parse(s.Cron)
+	if err != nil {
+		return nil, invalid("'cron' is not a valid cron expression: %v", err)
+	}
+	if !plugins[s.Plugin] {
+		return nil, invalid("plugin '%s' is not loaded", s.Plugin)
+	}
+	if s.Jitter < 0 || s.Jitter > maxJitter {
+		return nil, invalid("'jitter' must be between 0 and %d seconds", maxJitter)
+	}
+	if s.Params == nil {
+		s.Params = make(map[string]any)
+	}
+	return schedule, nil
+}
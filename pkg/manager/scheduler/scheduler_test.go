@@ -0,0 +1,294 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/fs"
+	"github.com/bazuker/browserbro/pkg/fs/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecValidate(t *testing.T) {
+	plugins := map[string]bool{"googlesearch": true}
+
+	spec := Spec{Cron: "0 6 * * *", Plugin: "googlesearch"}
+	_, err := spec.validate(plugins)
+	require.NoError(t, err)
+	assert.NotNil(t, spec.Params)
+
+	for _, expr := range []string{"@daily", "@every 1h30m", "CRON_TZ=Europe/Berlin 0 6 * * 1-5"} {
+		spec := Spec{Cron: expr, Plugin: "googlesearch"}
+		_, err := spec.validate(plugins)
+		assert.NoError(t, err, expr)
+	}
+
+	tests := []struct {
+		name string
+		spec Spec
+		err  string
+	}{
+		{"invalid cron", Spec{Cron: "0 6 * *", Plugin: "googlesearch"}, "'cron' is not a valid cron expression"},
+		{"unknown plugin", Spec{Cron: "@daily", Plugin: "dne"}, "plugin 'dne' is not loaded"},
+		{"negative jitter", Spec{Cron: "@daily", Plugin: "googlesearch", Jitter: -1}, "'jitter' must be between 0 and 3600 seconds"},
+		{"long jitter", Spec{Cron: "@daily", Plugin: "googlesearch", Jitter: 3601}, "'jitter' must be between 0 and 3600 seconds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.spec.validate(plugins)
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestService(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		calls  []map[string]any
+		runErr error
+	)
+	store := newMemoryStore()
+	s, err := New(Config{
+		FileStore: store.fileStore(),
+		Plugins:   []string{"googlesearch"},
+		Run: func(ctx context.Context, plugin string, params map[string]any) (map[string]any, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, "googlesearch", plugin)
+			assert.Equal(t, "admin", CreatorFromContext(ctx))
+			calls = append(calls, params)
+			if runErr != nil {
+				return nil, runErr
+			}
+			return map[string]any{"title": "Go", "files": []string{"Xk3_a9Qz.screenshot.png"}}, nil
+		},
+		HistorySize: 2,
+	})
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 5, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	s.jitter = func(maxSeconds int) time.Duration { return time.Duration(maxSeconds) * time.Second }
+
+	schedule, err := s.Create(Spec{
+		Cron:   "0 6 * * *",
+		Plugin: "googlesearch",
+		Params: map[string]any{"query": "golang"},
+		Jitter: 30,
+	}, "admin")
+	require.NoError(t, err)
+	require.NotNil(t, schedule.NextRunAt)
+	assert.Equal(t, time.Date(2024, 1, 1, 6, 0, 30, 0, time.UTC), *schedule.NextRunAt)
+
+	t.Run("not due", func(t *testing.T) {
+		s.runDue()
+		s.wg.Wait()
+		assert.Empty(t, calls)
+	})
+
+	t.Run("due", func(t *testing.T) {
+		now = time.Date(2024, 1, 1, 6, 0, 30, 0, time.UTC)
+		s.runDue()
+		s.wg.Wait()
+		require.Len(t, calls, 1)
+		assert.Equal(t, map[string]any{"query": "golang"}, calls[0])
+
+		schedule, err := s.Get(schedule.ID)
+		require.NoError(t, err)
+		assert.False(t, schedule.Running)
+		assert.Equal(t, now, *schedule.LastRunAt)
+		assert.Equal(t, time.Date(2024, 1, 2, 6, 0, 30, 0, time.UTC), *schedule.NextRunAt)
+
+		runs, err := s.Runs(schedule.ID)
+		require.NoError(t, err)
+		require.Len(t, runs, 1)
+		assert.Equal(t, TriggerSchedule, runs[0].Trigger)
+		assert.Equal(t, RunSucceeded, runs[0].Status)
+		assert.Equal(t, []string{"Xk3_a9Qz.screenshot.png"}, runs[0].Files)
+	})
+
+	t.Run("manual run with error", func(t *testing.T) {
+		runErr = errors.New("failed to navigate")
+		run, err := s.RunNow(schedule.ID)
+		require.NoError(t, err)
+		runErr = nil
+		assert.Equal(t, TriggerManual, run.Trigger)
+		assert.Equal(t, RunFailed, run.Status)
+		assert.Equal(t, "failed to navigate", run.Error)
+		assert.Empty(t, run.Files)
+	})
+
+	t.Run("history is capped", func(t *testing.T) {
+		_, err := s.RunNow(schedule.ID)
+		require.NoError(t, err)
+		runs, err := s.Runs(schedule.ID)
+		require.NoError(t, err)
+		require.Len(t, runs, 2)
+		assert.Empty(t, runs[0].Error)
+		assert.Equal(t, "failed to navigate", runs[1].Error)
+	})
+
+	t.Run("schedules are persisted", func(t *testing.T) {
+		loaded, err := New(Config{
+			FileStore: store.fileStore(),
+			Plugins:   []string{"googlesearch"},
+			Run:       s.run,
+		})
+		require.NoError(t, err)
+		actual, err := loaded.Get(schedule.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "0 6 * * *", actual.Cron)
+		runs, err := loaded.Runs(schedule.ID)
+		require.NoError(t, err)
+		assert.Len(t, runs, 2)
+
+		// The state is not one of the served files and the history is capped
+		// when the history size is lowered.
+		assert.Contains(t, store.objects, fs.StateKey("schedules.json"))
+		loaded, err = New(Config{
+			FileStore:   store.fileStore(),
+			Plugins:     []string{"googlesearch"},
+			Run:         s.run,
+			HistorySize: 1,
+		})
+		require.NoError(t, err)
+		runs, err = loaded.Runs(schedule.ID)
+		require.NoError(t, err)
+		assert.Len(t, runs, 1)
+	})
+
+	t.Run("pause", func(t *testing.T) {
+		updated, err := s.Update(schedule.ID, Spec{Cron: "0 6 * * *", Plugin: "googlesearch", Paused: true}, "admin")
+		require.NoError(t, err)
+		assert.Nil(t, updated.NextRunAt)
+		runs, err := s.Runs(schedule.ID)
+		require.NoError(t, err)
+		assert.Len(t, runs, 2)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, s.Delete(schedule.ID))
+		_, err := s.Get(schedule.ID)
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = s.RunNow(schedule.ID)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestServiceOverlap(t *testing.T) {
+	release := make(chan struct{})
+	s, err := New(Config{
+		FileStore: newMemoryStore().fileStore(),
+		Plugins:   []string{"crawl"},
+		Run: func(ctx context.Context, plugin string, params map[string]any) (map[string]any, error) {
+			<-release
+			return nil, nil
+		},
+	})
+	require.NoError(t, err)
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s.now = clock.Now
+
+	schedule, err := s.Create(Spec{Cron: "@every 1m", Plugin: "crawl"}, "")
+	require.NoError(t, err)
+
+	clock.Add(time.Minute)
+	s.runDue()
+	clock.Add(time.Minute)
+	s.runDue()
+
+	running, err := s.Get(schedule.ID)
+	require.NoError(t, err)
+	assert.True(t, running.Running)
+	assert.Equal(t, 1, running.SkippedRuns)
+	_, err = s.RunNow(schedule.ID)
+	assert.ErrorIs(t, err, ErrBusy)
+
+	close(release)
+	s.wg.Wait()
+	runs, err := s.Runs(schedule.ID)
+	require.NoError(t, err)
+	assert.Len(t, runs, 1)
+}
+
+func TestServiceStop(t *testing.T) {
+	s, err := New(Config{
+		FileStore:    newMemoryStore().fileStore(),
+		Plugins:      []string{"crawl"},
+		PollInterval: 10 * time.Millisecond,
+		Run: func(ctx context.Context, plugin string, params map[string]any) (map[string]any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+	require.NoError(t, err)
+	schedule, err := s.Create(Spec{Cron: "@every 1s", Plugin: "crawl"}, "")
+	require.NoError(t, err)
+
+	s.Start()
+	require.Eventually(t, func() bool {
+		running, _ := s.Get(schedule.ID)
+		return running.Running
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// The run that does not complete before the deadline is canceled and recorded.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	s.Stop(ctx)
+	runs, err := s.Runs(schedule.ID)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, "context canceled", runs[0].Error)
+}
+
+// testClock is a clock that is safe to advance while the runs are in progress.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{objects: make(map[string][]byte)}
+}
+
+func (s *memoryStore) fileStore() *mock.FileStore {
+	return &mock.FileStore{
+		PutObjectFn: func(object []byte, key string) error {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.objects[key] = object
+			return nil
+		},
+		GetObjectFn: func(key string) ([]byte, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			object, ok := s.objects[key]
+			if !ok {
+				return nil, fs.ErrorFileNotFound
+			}
+			return object, nil
+		},
+	}
+}
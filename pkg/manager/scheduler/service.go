@@ -0,0 +1,445 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/fs"
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/bazuker/browserbro/pkg/tracing"
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// schedulesKey is the file store key of the registered schedules.
+var schedulesKey = fs.StateKey("schedules.json")
+
+var (
+	ErrNotFound = errors.New("schedule not found")
+	ErrBusy     = errors.New("schedule is running")
+)
+
+// RunFunc runs the plugin with the params.
+type RunFunc func(ctx context.Context, plugin string, params map[string]any) (map[string]any, error)
+
+// creatorKey is the context key of the creator of the schedule of a run.
+type creatorKey struct{}
+
+// CreatorFromContext returns the name of the API key that created the schedule
+// of the run, see Schedule.CreatedBy.
+func CreatorFromContext(ctx context.Context) string {
+	creator, _ := ctx.Value(creatorKey{}).(string)
+	return creator
+}
+
+type Config struct {
+	// FileStore stores the schedules and their runs (required).
+	FileStore fs.FileStore
+	// Run runs the plugins (required).
+	Run RunFunc
+	// Plugins are the names of the plugins that can be scheduled.
+	Plugins []string
+	// HistorySize is the number of runs kept per schedule. Default: 20.
+	HistorySize int
+	// PollInterval is how often the schedules are looked through for due runs. Default: 1 second.
+	PollInterval time.Duration
+}
+
+// entry is a schedule with its parsed cron expression and the run history.
+type entry struct {
+	Schedule
+	Runs []Run `json:"runs"`
+
+	cron cron.Schedule
+}
+
+// Service keeps the registered schedules and runs them on time.
+type Service struct {
+	fileStore    fs.FileStore
+	run          RunFunc
+	plugins      map[string]bool
+	historySize  int
+	pollInterval time.Duration
+	now          func() time.Time
+	jitter       func(maxSeconds int) time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	// runsCtx is canceled to abort the running plugins on shutdown.
+	runsCtx    context.Context
+	cancelRuns context.CancelFunc
+}
+
+func New(cfg Config) (*Service, error) {
+	if cfg.FileStore == nil {
+		return nil, errors.New("file store is required")
+	}
+	if cfg.Run == nil {
+		return nil, errors.New("run function is required")
+	}
+	if cfg.HistorySize <= 0 {
+		cfg.HistorySize = 20
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+
+	s := &Service{
+		fileStore:    cfg.FileStore,
+		run:          cfg.Run,
+		plugins:      make(map[string]bool, len(cfg.Plugins)),
+		historySize:  cfg.HistorySize,
+		pollInterval: cfg.PollInterval,
+		now:          time.Now,
+		jitter:       randomJitter,
+		entries:      make(map[string]*entry),
+	}
+	s.runsCtx, s.cancelRuns = context.WithCancel(context.Background())
+	for _, name := range cfg.Plugins {
+		s.plugins[name] = true
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// List returns the schedules in the order of creation.
+func (s *Service) List() []Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	schedules := make([]Schedule, 0, len(s.entries))
+	for _, e := range s.entries {
+		schedules = append(schedules, e.Schedule)
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		if schedules[i].CreatedAt.Equal(schedules[j].CreatedAt) {
+			return schedules[i].ID < schedules[j].ID
+		}
+		return schedules[i].CreatedAt.Before(schedules[j].CreatedAt)
+	})
+	return schedules
+}
+
+func (s *Service) Get(id string) (Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok {
+		return Schedule{}, ErrNotFound
+	}
+	return e.Schedule, nil
+}
+
+// Runs returns the run history of the schedule, the latest run first.
+func (s *Service) Runs(id string) ([]Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	runs := make([]Run, len(e.Runs))
+	copy(runs, e.Runs)
+	return runs, nil
+}
+
+// Create registers the schedule on behalf of the API key, empty without authentication.
+func (s *Service) Create(spec Spec, createdBy string) (Schedule, error) {
+	schedule, err := spec.validate(s.plugins)
+	if err != nil {
+		return Schedule{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now().UTC()
+	e := &entry{
+		Schedule: Schedule{
+			Spec:      spec,
+			ID:        helper.GenerateRandomString(6),
+			CreatedAt: now,
+			UpdatedAt: now,
+			CreatedBy: createdBy,
+		},
+		Runs: make([]Run, 0),
+		cron: schedule,
+	}
+	s.plan(e, now)
+	s.entries[e.ID] = e
+	if err := s.save(); err != nil {
+		delete(s.entries, e.ID)
+		return Schedule{}, err
+	}
+	return e.Schedule, nil
+}
+
+// Update replaces the spec of the schedule and plans its next run.
+// The run history is kept and the runs are then made on behalf of the API key
+// that updated it.
+func (s *Service) Update(id string, spec Spec, updatedBy string) (Schedule, error) {
+	schedule, err := spec.validate(s.plugins)
+	if err != nil {
+		return Schedule{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok {
+		return Schedule{}, ErrNotFound
+	}
+	previous := *e
+	now := s.now().UTC()
+	e.Spec = spec
+	e.UpdatedAt = now
+	e.CreatedBy = updatedBy
+	e.cron = schedule
+	s.plan(e, now)
+	if err := s.save(); err != nil {
+		*e = previous
+		return Schedule{}, err
+	}
+	return e.Schedule, nil
+}
+
+// Delete removes the schedule. A run in progress is completed but not recorded.
+func (s *Service) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.entries, id)
+	if err := s.save(); err != nil {
+		s.entries[id] = e
+		return err
+	}
+	return nil
+}
+
+// RunNow runs the schedule immediately, even if it is paused, and returns the run.
+func (s *Service) RunNow(id string) (Run, error) {
+	s.mu.Lock()
+	e, ok := s.entries[id]
+	if !ok {
+		s.mu.Unlock()
+		return Run{}, ErrNotFound
+	}
+	if e.Running {
+		s.mu.Unlock()
+		return Run{}, ErrBusy
+	}
+	e.Running = true
+	j := job{id: id, plugin: e.Plugin, params: maps.Clone(e.Params), createdBy: e.CreatedBy}
+	s.mu.Unlock()
+
+	return s.execute(j, TriggerManual), nil
+}
+
+// Start runs the due schedules in the background until Stop is called.
+// The runs missed while the server was down are not caught up.
+func (s *Service) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.runDue()
+			}
+		}
+	}()
+}
+
+// Stop stops scheduling runs and waits for the running plugins to complete.
+// The runs still in progress when the context is done are canceled.
+func (s *Service) Stop(ctx context.Context) {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.cancelRuns()
+		<-done
+	}
+}
+
+// runDue starts the due schedules. A schedule whose previous run is still in
+// progress skips the run to prevent overlapping runs.
+func (s *Service) runDue() {
+	s.mu.Lock()
+	now := s.now().UTC()
+	jobs := make([]job, 0)
+	for id, e := range s.entries {
+		if e.NextRunAt == nil || now.Before(*e.NextRunAt) {
+			continue
+		}
+		s.plan(e, now)
+		if e.Running {
+			e.SkippedRuns++
+			log.Warn().Str("schedule", id).Msg("skipping the run, the previous run is still in progress")
+			continue
+		}
+		e.Running = true
+		jobs = append(jobs, job{id: id, plugin: e.Plugin, params: maps.Clone(e.Params), createdBy: e.CreatedBy})
+	}
+	if len(jobs) > 0 {
+		s.saveOrLog()
+	}
+	s.mu.Unlock()
+
+	for _, j := range jobs {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.execute(j, TriggerSchedule)
+		}()
+	}
+}
+
+// job is a run of a schedule, copied from the schedule under the lock.
+type job struct {
+	id, plugin string
+	params     map[string]any
+	createdBy  string
+}
+
+// execute runs the plugin and records the run in the history of the schedule.
+func (s *Service) execute(j job, trigger string) Run {
+	id := j.id
+	logger := log.With().Str("schedule_id", id).Logger()
+	ctx := context.WithValue(logger.WithContext(s.runsCtx), creatorKey{}, j.createdBy)
+	ctx, span := tracing.Start(ctx, "schedule "+id, trace.WithAttributes(
+		attribute.String("schedule.id", id),
+		attribute.String("schedule.trigger", trigger),
+	))
+	run := Run{Trigger: trigger, StartedAt: s.now().UTC()}
+	result, err := s.run(ctx, j.plugin, j.params)
+	tracing.End(span, err)
+	run.FinishedAt = s.now().UTC()
+	if err != nil {
+		run.Status = RunFailed
+		run.Error = err.Error()
+	} else {
+		run.Status = RunSucceeded
+		run.Files = helper.FileNames(normalizeOutput(result))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok {
+		return run
+	}
+	e.Running = false
+	e.LastRunAt = &run.StartedAt
+	e.Runs = append([]Run{run}, e.Runs...)
+	if len(e.Runs) > s.historySize {
+		e.Runs = e.Runs[:s.historySize]
+	}
+	s.saveOrLog()
+	return run
+}
+
+// plan sets the time of the next run after the time. The caller must hold the lock.
+func (s *Service) plan(e *entry, after time.Time) {
+	if e.Paused {
+		e.NextRunAt = nil
+		return
+	}
+	next := e.cron.Next(after)
+	if e.Jitter > 0 {
+		next = next.Add(s.jitter(e.Jitter))
+	}
+	e.NextRunAt = &next
+}
+
+func (s *Service) load() error {
+	data, err := s.fileStore.GetObject(schedulesKey)
+	if errors.Is(err, fs.ErrorFileNotFound) || (err == nil && len(data) == 0) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load schedules: %w", err)
+	}
+	var entries []*entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to load schedules: %w", err)
+	}
+	now := s.now().UTC()
+	for _, e := range entries {
+		e.cron, err = cronParser.Parse(e.Cron)
+		if err != nil {
+			return fmt.Errorf("failed to load schedule '%s': %w", e.ID, err)
+		}
+		// The runs in progress were interrupted by the restart.
+		e.Running = false
+		if len(e.Runs) > s.historySize {
+			e.Runs = e.Runs[:s.historySize]
+		}
+		s.plan(e, now)
+		s.entries[e.ID] = e
+	}
+	return nil
+}
+
+// save persists the schedules. The caller must hold the lock.
+func (s *Service) save() error {
+	entries := make([]*entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to save schedules: %w", err)
+	}
+	if err := s.fileStore.PutObject(data, schedulesKey); err != nil {
+		return fmt.Errorf("failed to save schedules: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) saveOrLog() {
+	if err := s.save(); err != nil {
+		log.Error().Err(err).Msg("failed to save schedules")
+	}
+}
+
+// normalizeOutput converts the output to its JSON form, in which the file
+// names are strings. An output that cannot be encoded references no files.
+func normalizeOutput(output map[string]any) any {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return nil
+	}
+	var normalized any
+	_ = json.Unmarshal(data, &normalized)
+	return normalized
+}
+
+func randomJitter(maxSeconds int) time.Duration {
+	return rand.N(time.Duration(maxSeconds) * time.Second)
+}
@@ -0,0 +1,43 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bazuker/browserbro/pkg/manager/ratelimit"
+	"github.com/bazuker/browserbro/pkg/manager/scheduler"
+	pluginsRegistry "github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/gin-gonic/gin"
+)
+
+// authorizeSchedule checks that the API key grants the scopes of the plugin
+// of the schedule being saved, and of the plugins it runs with the params.
+func (m *Manager) authorizeSchedule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		spec := scheduler.SpecFromContext(c)
+		if !m.authorizeRun(c, spec.Plugin, spec.Params) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// runScheduled runs the plugin of a schedule on behalf of the API key that
+// saved it. Like the plugin endpoints, it checks the scopes of the key, which
+// may have changed since, and takes the cost of the run from its bucket.
+func (m *Manager) runScheduled(ctx context.Context, name string, params map[string]any) (map[string]any, error) {
+	if m.auth.Enabled() {
+		creator := scheduler.CreatorFromContext(ctx)
+		session, ok := m.auth.KeySession(creator)
+		if !ok {
+			return nil, fmt.Errorf("API key '%s' of the schedule does not exist", creator)
+		}
+		if err := m.checkScopes(session, name, params); err != nil {
+			return nil, err
+		}
+		if err := m.limiter.Charge(ratelimit.KeyClientID(creator), pluginsRegistry.Cost(m.plugin(name), params)); err != nil {
+			return nil, err
+		}
+	}
+	return m.runPlugin(ctx, name, params)
+}
@@ -4,10 +4,16 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+
+	"github.com/bazuker/browserbro/pkg/manager/helper"
 )
 
+// testRequestID is the request ID sent by performRequest.
+const testRequestID = "test-request"
+
 func performRequest(r http.Handler, method, path string, body io.Reader) *httptest.ResponseRecorder {
 	req := httptest.NewRequest(method, path, body)
+	req.Header.Set(helper.HeaderRequestID, testRequestID)
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 	return w
@@ -0,0 +1,154 @@
+package bingsearch
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/fs"
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/plugins/botwall"
+	"github.com/bazuker/browserbro/pkg/plugins/consent"
+	"github.com/bazuker/browserbro/pkg/plugins/serp"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/stealth"
+)
+
+const (
+	pluginName = "bingsearch"
+)
+
+// timeFilters maps the time ranges supported by Bing to its "filters" parameter.
+var timeFilters = map[string]string{
+	"day":   `ex1:"ez1"`,
+	"week":  `ex1:"ez2"`,
+	"month": `ex1:"ez3"`,
+}
+
+// resultsJS collects the organic results of the results page.
+const resultsJS = `() => Array.from(document.querySelectorAll('#b_results > li.b_algo'), block => {
+	const a = block.querySelector('h2 a');
+	const description = block.querySelector('.b_caption p, .b_lineclamp2, .b_lineclamp3, .b_lineclamp4');
+	return {
+		link: a ? a.href : '',
+		title: a ? a.innerText : '',
+		description: description ? description.innerText : '',
+	};
+})`
+
+type BingSearch struct {
+	browser          *rod.Browser
+	fileStore        fs.FileStore
+	maxTimePerSearch time.Duration
+}
+
+func New(browser *rod.Browser, fileStore fs.FileStore, cfg plugins.Config) *BingSearch {
+	return &BingSearch{
+		browser:          browser,
+		fileStore:        fileStore,
+		maxTimePerSearch: cfg.TimeoutOr(15 * time.Second),
+	}
+}
+
+func (p *BingSearch) Name() string {
+	return pluginName
+}
+
+// Cost is the number of results pages to read.
+func (p *BingSearch) Cost(params map[string]any) int {
+	query, err := serp.ParseQuery(params)
+	if err != nil {
+		return 1
+	}
+	return query.Pages
+}
+
+func (p *BingSearch) Run(ctx context.Context, params map[string]any) (output map[string]any, err error) {
+	query, err := serp.ParseQuery(params)
+	if err != nil {
+		return nil, err
+	}
+	searchURL, err := buildSearchURL(query)
+	if err != nil {
+		return nil, err
+	}
+	screenshotOnBlock, err := botwall.ParseScreenshotParam(params)
+	if err != nil {
+		return nil, err
+	}
+	dismissConsent, err := consent.ParseParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var stealthPage *rod.Page
+	stealthPage, err = stealth.Page(p.browser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stealth page: %w", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	page := stealthPage.Context(ctx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to complete: %v", r)
+		}
+		err = plugins.TimeLimitError(ctx, err)
+		_ = page.Close()
+	}()
+
+	go func() {
+		time.Sleep(p.maxTimePerSearch * time.Duration(query.Pages))
+		cancel(plugins.ErrTimeLimit)
+	}()
+
+	paginator := serp.Paginator{
+		Parse:          serp.ParseScript(resultsJS),
+		Next:           serp.NextLink("a.sb_pagN"),
+		DismissConsent: dismissConsent,
+	}
+	results, err := paginator.Collect(page, searchURL, query.Pages)
+	if err != nil {
+		if screenshotOnBlock {
+			err = botwall.Capture(page, p.fileStore, err)
+		}
+		return nil, err
+	}
+
+	output = make(map[string]any)
+	output["all"] = results
+
+	return output, nil
+}
+
+func buildSearchURL(query serp.Query) (string, error) {
+	values := url.Values{}
+	values.Set("q", query.Text)
+	if query.Num > 0 {
+		values.Set("count", fmt.Sprint(query.Num))
+	}
+	if query.Language != "" {
+		values.Set("setlang", query.Language)
+	}
+	if query.Country != "" {
+		values.Set("cc", strings.ToUpper(query.Country))
+	}
+	if query.Safe != nil {
+		if *query.Safe {
+			values.Set("adlt", "strict")
+		} else {
+			values.Set("adlt", "off")
+		}
+	}
+	if query.TimeRange != "" {
+		filter, ok := timeFilters[query.TimeRange]
+		if !ok {
+			return "", plugins.ParamErrorf("time range '%s' is not supported by Bing", query.TimeRange)
+		}
+		values.Set("filters", filter)
+	}
+	return "https://www.bing.com/search?" + values.Encode(), nil
+}
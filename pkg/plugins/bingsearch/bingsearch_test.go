@@ -0,0 +1,26 @@
+package bingsearch
+
+import (
+	"testing"
+
+	"github.com/bazuker/browserbro/pkg/plugins/serp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSearchURL(t *testing.T) {
+	safe := true
+	searchURL, err := buildSearchURL(serp.Query{
+		Text:      "golang",
+		Num:       20,
+		Language:  "de",
+		Country:   "at",
+		Safe:      &safe,
+		TimeRange: "week",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://www.bing.com/search?adlt=strict&cc=AT&count=20&filters=ex1%3A%22ez2%22&q=golang&setlang=de", searchURL)
+
+	_, err = buildSearchURL(serp.Query{Text: "golang", TimeRange: "hour"})
+	assert.EqualError(t, err, "time range 'hour' is not supported by Bing")
+}
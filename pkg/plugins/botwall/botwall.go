@@ -0,0 +1,192 @@
+// Package botwall recognizes the interstitial pages search engines and CDNs
+// show to automated clients instead of the requested content.
+package botwall
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/bazuker/browserbro/pkg/fs"
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/tracing"
+	"github.com/go-rod/rod"
+)
+
+// ErrBlocked is matched by every *BlockedError with errors.Is.
+var ErrBlocked = errors.New("blocked by a bot wall")
+
+const (
+	BlockerUnusualTraffic = "unusualTraffic"
+	BlockerRecaptcha      = "recaptcha"
+	BlockerHcaptcha       = "hcaptcha"
+	BlockerCloudflare     = "cloudflare"
+	BlockerConsent        = "consent"
+)
+
+// ScreenshotParam is the name of the plugin parameter that enables saving a
+// screenshot of the block page.
+const ScreenshotParam = "screenshotOnBlock"
+
+// interstitialTextLength is the maximum length of the visible text of a page
+// on which a CAPTCHA or consent widget is treated as a wall. Longer pages are
+// regular content pages that merely embed such a widget, e.g. a login form.
+const interstitialTextLength = 1500
+
+var blockerNames = map[string]string{
+	BlockerUnusualTraffic: "the unusual traffic page",
+	BlockerRecaptcha:      "a reCAPTCHA challenge",
+	BlockerHcaptcha:       "an hCaptcha challenge",
+	BlockerCloudflare:     "a Cloudflare challenge",
+	BlockerConsent:        "a consent wall",
+}
+
+// markerSelectors are the elements that identify a blocker when they are
+// present on a short page.
+var markerSelectors = map[string]string{
+	BlockerRecaptcha:  `iframe[src*="/recaptcha/"], iframe[title="reCAPTCHA"], .g-recaptcha`,
+	BlockerHcaptcha:   `iframe[src*="hcaptcha.com"], .h-captcha`,
+	BlockerCloudflare: `#challenge-form, #challenge-running, #cf-challenge-running, .cf-turnstile`,
+	BlockerConsent:    `form[action*="consent."], iframe[src*="consent."]`,
+}
+
+var cloudflareTitles = []string{
+	"Just a moment...",
+	"Attention Required! | Cloudflare",
+}
+
+var consentHosts = []string{
+	"consent.google.",
+	"consent.youtube.",
+	"consent.yahoo.",
+}
+
+// snapshotJS collects what Detect needs to know about the page.
+const snapshotJS = `selectors => ({
+	url: location.href,
+	title: document.title,
+	text: document.body ? document.body.innerText.slice(0, 10000) : '',
+	markers: Object.keys(selectors).filter(name => document.querySelector(selectors[name])),
+})`
+
+// BlockedError is returned when the loaded page is a bot wall.
+type BlockedError struct {
+	// Blocker is one of the Blocker constants.
+	Blocker string
+	// URL is the address of the block page.
+	URL string
+	// Screenshot is the name of the block page screenshot in the file store, if one was saved.
+	Screenshot string
+}
+
+func (e *BlockedError) Error() string {
+	msg := fmt.Sprintf("blocked by %s at '%s'", blockerNames[e.Blocker], e.URL)
+	if e.Screenshot != "" {
+		msg += fmt.Sprintf(" (screenshot: %s)", e.Screenshot)
+	}
+	return msg
+}
+
+func (e *BlockedError) Is(target error) bool {
+	return target == ErrBlocked
+}
+
+// Snapshot is the state of a loaded page.
+type Snapshot struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	// Text is the beginning of the visible text of the page.
+	Text string `json:"text"`
+	// Markers are the blockers whose marker elements are present on the page.
+	Markers []string `json:"markers"`
+}
+
+// Detect returns the blocker of the page or an empty string if the page is not blocked.
+func Detect(s Snapshot) string {
+	if u, err := url.Parse(s.URL); err == nil {
+		host := strings.TrimPrefix(u.Hostname(), "www.")
+		if strings.HasPrefix(host, "google.") && strings.HasPrefix(u.Path, "/sorry/") {
+			return BlockerUnusualTraffic
+		}
+		for _, consentHost := range consentHosts {
+			if strings.HasPrefix(host, consentHost) {
+				return BlockerConsent
+			}
+		}
+	}
+	if strings.Contains(s.Text, "Our systems have detected unusual traffic from your computer network") {
+		return BlockerUnusualTraffic
+	}
+	for _, title := range cloudflareTitles {
+		if strings.TrimSpace(s.Title) == title {
+			return BlockerCloudflare
+		}
+	}
+
+	if len(s.Text) > interstitialTextLength {
+		return ""
+	}
+	// The order matters: CAPTCHAs are often embedded in the challenge pages.
+	for _, blocker := range []string{BlockerCloudflare, BlockerRecaptcha, BlockerHcaptcha, BlockerConsent} {
+		for _, marker := range s.Markers {
+			if marker == blocker {
+				return blocker
+			}
+		}
+	}
+	return ""
+}
+
+// Check returns a *BlockedError if the loaded page is a bot wall.
+func Check(page *rod.Page) error {
+	res, err := page.Eval(snapshotJS, markerSelectors)
+	if err != nil {
+		return fmt.Errorf("failed to check the page for bot walls: %w", err)
+	}
+	var s Snapshot
+	if err := res.Value.Unmarshal(&s); err != nil {
+		return fmt.Errorf("failed to check the page for bot walls: %w", err)
+	}
+	if blocker := Detect(s); blocker != "" {
+		return &BlockedError{Blocker: blocker, URL: s.URL}
+	}
+	return nil
+}
+
+// Capture saves a screenshot of the page to the file store if err is a
+// *BlockedError and records the file name in it. Capturing is best effort,
+// the error is returned in any case.
+func Capture(page *rod.Page, fileStore fs.FileStore, err error) error {
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		return err
+	}
+	var screenshot []byte
+	screenshotErr := tracing.Screenshot(page, func() (err error) {
+		screenshot, err = page.Screenshot(false, nil)
+		return err
+	})
+	if screenshotErr != nil {
+		return err
+	}
+	filename := helper.NewFileName("blocked", "png")
+	if tracing.PutObject(page.GetContext(), fileStore, screenshot, filename) == nil {
+		blocked.Screenshot = filename
+	}
+	return err
+}
+
+// ParseScreenshotParam reads the ScreenshotParam parameter.
+func ParseScreenshotParam(params map[string]any) (bool, error) {
+	value, ok := params[ScreenshotParam]
+	if !ok {
+		return false, nil
+	}
+	screenshot, ok := value.(bool)
+	if !ok {
+		return false, plugins.ParamErrorf("'%s' parameter must be a boolean", ScreenshotParam)
+	}
+	return screenshot, nil
+}
@@ -0,0 +1,100 @@
+package botwall
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect(t *testing.T) {
+	longText := strings.Repeat("Regular page content. ", 100)
+	tests := []struct {
+		name     string
+		snapshot Snapshot
+		expected string
+	}{
+		{
+			name:     "regular page",
+			snapshot: Snapshot{URL: "https://www.google.com/search?q=golang", Title: "golang - Google Search", Text: longText},
+		},
+		{
+			name:     "google sorry page",
+			snapshot: Snapshot{URL: "https://www.google.com/sorry/index?continue=https://www.google.com/search"},
+			expected: BlockerUnusualTraffic,
+		},
+		{
+			name:     "unusual traffic text",
+			snapshot: Snapshot{URL: "https://www.google.de/search", Text: "Our systems have detected unusual traffic from your computer network. This page checks ..."},
+			expected: BlockerUnusualTraffic,
+		},
+		{
+			name:     "google consent",
+			snapshot: Snapshot{URL: "https://consent.google.com/ml?continue=https://www.google.com/search", Title: "Before you continue to Google"},
+			expected: BlockerConsent,
+		},
+		{
+			name:     "cloudflare title",
+			snapshot: Snapshot{URL: "https://example.com/", Title: "Just a moment...", Markers: []string{BlockerRecaptcha}},
+			expected: BlockerCloudflare,
+		},
+		{
+			name:     "cloudflare markers win over captcha",
+			snapshot: Snapshot{URL: "https://example.com/", Markers: []string{BlockerHcaptcha, BlockerCloudflare}},
+			expected: BlockerCloudflare,
+		},
+		{
+			name:     "recaptcha interstitial",
+			snapshot: Snapshot{URL: "https://example.com/", Text: "Please verify you are a human", Markers: []string{BlockerRecaptcha}},
+			expected: BlockerRecaptcha,
+		},
+		{
+			name:     "hcaptcha interstitial",
+			snapshot: Snapshot{URL: "https://example.com/", Markers: []string{BlockerHcaptcha}},
+			expected: BlockerHcaptcha,
+		},
+		{
+			name:     "captcha embedded in a content page",
+			snapshot: Snapshot{URL: "https://example.com/login", Text: longText, Markers: []string{BlockerRecaptcha}},
+		},
+		{
+			name:     "consent form",
+			snapshot: Snapshot{URL: "https://www.youtube.com/", Markers: []string{BlockerConsent}},
+			expected: BlockerConsent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Detect(tt.snapshot))
+		})
+	}
+}
+
+func TestBlockedError(t *testing.T) {
+	var err error = &BlockedError{Blocker: BlockerCloudflare, URL: "https://example.com/"}
+	assert.EqualError(t, err, "blocked by a Cloudflare challenge at 'https://example.com/'")
+
+	wrapped := fmt.Errorf("failed to search: %w", err)
+	assert.True(t, errors.Is(wrapped, ErrBlocked))
+	var blocked *BlockedError
+	require.True(t, errors.As(wrapped, &blocked))
+	assert.Equal(t, BlockerCloudflare, blocked.Blocker)
+
+	assert.False(t, errors.Is(errors.New("failed"), ErrBlocked))
+}
+
+func TestParseScreenshotParam(t *testing.T) {
+	screenshot, err := ParseScreenshotParam(map[string]any{})
+	require.NoError(t, err)
+	assert.False(t, screenshot)
+
+	screenshot, err = ParseScreenshotParam(map[string]any{"screenshotOnBlock": true})
+	require.NoError(t, err)
+	assert.True(t, screenshot)
+
+	_, err = ParseScreenshotParam(map[string]any{"screenshotOnBlock": "yes"})
+	assert.EqualError(t, err, "'screenshotOnBlock' parameter must be a boolean")
+}
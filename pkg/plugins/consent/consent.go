@@ -0,0 +1,96 @@
+// Package consent closes the cookie consent dialogs of common consent
+// management platforms so that they do not cover the captured content.
+package consent
+
+import (
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Param is the name of the plugin parameter that enables dismissing consent dialogs.
+const Param = "dismissConsent"
+
+// closeTimeout is how long to wait for a dialog to close after a button is clicked.
+const closeTimeout = 5 * time.Second
+
+// closedJS reports whether the dialog is gone or hidden.
+const closedJS = `selector => {
+	const el = document.querySelector(selector);
+	return !el || el.offsetParent === null || getComputedStyle(el).visibility === 'hidden';
+}`
+
+// ParseParam reads the Param parameter.
+func ParseParam(params map[string]any) (bool, error) {
+	value, ok := params[Param]
+	if !ok {
+		return false, nil
+	}
+	dismiss, ok := value.(bool)
+	if !ok {
+		return false, plugins.ParamErrorf("'" + Param + "' parameter must be a boolean")
+	}
+	return dismiss, nil
+}
+
+// Dismiss closes the consent dialog of the loaded page, if there is one, and
+// returns the name of the applied rule. Dismissing is best effort: an empty
+// name is returned if no dialog was found or it could not be closed.
+func Dismiss(page *rod.Page) string {
+	for _, rule := range Rules {
+		if apply(page, rule) {
+			return rule.Name
+		}
+	}
+	return ""
+}
+
+// apply clicks the first present button of the rule's dialog and waits for
+// the dialog to close.
+func apply(page *rod.Page, rule Rule) bool {
+	dialogPage := page
+	if rule.Frame != "" {
+		hasFrame, frame, err := page.Has(rule.Frame)
+		if err != nil || !hasFrame {
+			return false
+		}
+		if dialogPage, err = frame.Frame(); err != nil {
+			return false
+		}
+	}
+	if hasDialog, _, err := dialogPage.Has(rule.Dialog); err != nil || !hasDialog {
+		return false
+	}
+
+	for _, selector := range rule.Buttons {
+		hasButton, button, err := dialogPage.Has(selector)
+		if err != nil || !hasButton {
+			continue
+		}
+
+		waitPage := page.Timeout(closeTimeout)
+		defer waitPage.CancelTimeout()
+		var waitNavigation func()
+		if rule.Navigates {
+			waitNavigation = waitPage.WaitNavigation(proto.PageLifecycleEventNameLoad)
+		}
+		// A JS click also works for buttons covered by other elements.
+		if _, err := button.Eval(`() => this.click()`); err != nil {
+			continue
+		}
+		if waitNavigation != nil {
+			waitNavigation()
+			return true
+		}
+
+		closed := rule.Dialog
+		if rule.Frame != "" {
+			closed = rule.Frame
+		}
+		_ = waitPage.Wait(rod.Eval(closedJS, closed))
+		return true
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package consent
+
+import (
+	"testing"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRules(t *testing.T) {
+	names := make(map[string]bool)
+	for _, rule := range Rules {
+		t.Run(rule.Name, func(t *testing.T) {
+			require.NotEmpty(t, rule.Name)
+			assert.False(t, names[rule.Name], "duplicate rule name")
+			names[rule.Name] = true
+
+			selectors := append([]string{rule.Dialog}, rule.Buttons...)
+			if rule.Frame != "" {
+				selectors = append(selectors, rule.Frame)
+			}
+			require.NotEmpty(t, rule.Buttons)
+			for _, selector := range selectors {
+				_, err := cascadia.ParseGroup(selector)
+				assert.NoError(t, err, selector)
+			}
+		})
+	}
+}
+
+func TestParseParam(t *testing.T) {
+	dismiss, err := ParseParam(map[string]any{})
+	require.NoError(t, err)
+	assert.False(t, dismiss)
+
+	dismiss, err = ParseParam(map[string]any{"dismissConsent": true})
+	require.NoError(t, err)
+	assert.True(t, dismiss)
+
+	_, err = ParseParam(map[string]any{"dismissConsent": 1.0})
+	assert.EqualError(t, err, "'dismissConsent' parameter must be a boolean")
+}
@@ -0,0 +1,120 @@
+package consent
+
+// Rule describes the consent dialog of a consent management platform (CMP).
+type Rule struct {
+	// Name is the name of the consent management platform.
+	Name string
+	// Frame is the selector of the iframe the dialog is rendered in.
+	// It is empty for dialogs rendered in the page itself.
+	Frame string
+	// Dialog is the selector of the dialog.
+	Dialog string
+	// Buttons are the selectors of the buttons that close the dialog in the
+	// order of preference. Rejecting is preferred over accepting.
+	Buttons []string
+	// Navigates is set when closing the dialog loads another page.
+	Navigates bool
+}
+
+// Rules are the known consent dialogs. The first rule whose dialog and one of
+// the buttons are present on the page is applied.
+var Rules = []Rule{
+	{
+		Name:   "google",
+		Dialog: `form[action*="consent.google."][action$="/save"], form[action*="consent.youtube."][action$="/save"]`,
+		Buttons: []string{
+			`form[action$="/save"]:has(input[name="set_eom"][value="true"]) button`,
+			`form[action$="/save"]:has(input[name="set_eom"][value="false"]) button`,
+		},
+		Navigates: true,
+	},
+	{
+		Name:    "google-dialog",
+		Dialog:  `#CXQnmb, div[aria-modal="true"]:has(#L2AGLb)`,
+		Buttons: []string{`#W0wltc`, `#L2AGLb`},
+	},
+	{
+		Name:    "onetrust",
+		Dialog:  `#onetrust-banner-sdk, #onetrust-pc-sdk`,
+		Buttons: []string{`#onetrust-reject-all-handler`, `.ot-pc-refuse-all-handler`, `#onetrust-accept-btn-handler`},
+	},
+	{
+		Name:   "cookiebot",
+		Dialog: `#CybotCookiebotDialog`,
+		Buttons: []string{
+			`#CybotCookiebotDialogBodyButtonDecline`,
+			`#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll`,
+			`#CybotCookiebotDialogBodyButtonAccept`,
+		},
+	},
+	{
+		Name:    "didomi",
+		Dialog:  `#didomi-popup, #didomi-notice`,
+		Buttons: []string{`#didomi-notice-disagree-button`, `.didomi-continue-without-agreeing`, `#didomi-notice-agree-button`},
+	},
+	{
+		Name:   "quantcast",
+		Dialog: `.qc-cmp2-container`,
+		Buttons: []string{
+			`.qc-cmp2-summary-buttons button[mode="secondary"]`,
+			`.qc-cmp2-summary-buttons button[mode="primary"]`,
+		},
+	},
+	{
+		Name:    "sourcepoint",
+		Frame:   `iframe[id^="sp_message_iframe"]`,
+		Dialog:  `.message, .message-container`,
+		Buttons: []string{`button.sp_choice_type_13`, `button[title="Reject all"]`, `button.sp_choice_type_11`, `button[title="Accept all"]`},
+	},
+	{
+		Name:    "trustarc",
+		Dialog:  `#truste-consent-track`,
+		Buttons: []string{`#truste-consent-required`, `#truste-consent-button`},
+	},
+	{
+		Name:    "trustarc-frame",
+		Frame:   `iframe[src*="consent-pref.trustarc.com"]`,
+		Dialog:  `.pdynamicbutton`,
+		Buttons: []string{`.pdynamicbutton .required`, `.pdynamicbutton .call`},
+	},
+	{
+		Name:    "consentmanager",
+		Dialog:  `#cmpbox`,
+		Buttons: []string{`.cmpboxbtnno`, `.cmpboxbtnyes`},
+	},
+	{
+		Name:    "osano",
+		Dialog:  `.osano-cm-window`,
+		Buttons: []string{`.osano-cm-denial`, `.osano-cm-accept-all`},
+	},
+	{
+		Name:    "complianz",
+		Dialog:  `.cmplz-cookiebanner`,
+		Buttons: []string{`.cmplz-deny`, `.cmplz-accept`},
+	},
+	{
+		Name:    "cookieyes",
+		Dialog:  `.cky-consent-container`,
+		Buttons: []string{`.cky-btn-reject`, `.cky-btn-accept`},
+	},
+	{
+		Name:    "borlabs",
+		Dialog:  `#BorlabsCookieBox`,
+		Buttons: []string{`a[data-cookie-refuse]`, `a[data-cookie-accept]`},
+	},
+	{
+		Name:    "klaro",
+		Dialog:  `.klaro .cookie-notice, .klaro .cookie-modal`,
+		Buttons: []string{`.klaro .cn-decline`, `.klaro .cm-btn-success`},
+	},
+	{
+		Name:    "iubenda",
+		Dialog:  `#iubenda-cs-banner`,
+		Buttons: []string{`.iubenda-cs-reject-btn`, `.iubenda-cs-accept-btn`},
+	},
+	{
+		Name:    "cookie-notice",
+		Dialog:  `#cookie-notice`,
+		Buttons: []string{`#cn-refuse-cookie`, `#cn-accept-cookie`},
+	},
+}
@@ -0,0 +1,435 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/plugins/botwall"
+	"github.com/bazuker/browserbro/pkg/plugins/consent"
+	"github.com/bazuker/browserbro/pkg/tracing"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/stealth"
+)
+
+const (
+	pluginName = "crawl"
+)
+
+type Crawl struct {
+	browser         *rod.Browser
+	plugins         []plugins.Plugin
+	run             plugins.RunFunc
+	httpClient      *http.Client
+	maxTimePerPage  time.Duration
+	maxPages        int
+	defaultMaxPages int
+	defaultMaxDepth int
+	defaultDelay    time.Duration
+	maxDelay        time.Duration
+	pluginTimeout   time.Duration
+}
+
+// Config is the configuration section of the crawl plugin.
+type Config struct {
+	plugins.Config `yaml:",inline"`
+	// MaxPages is the largest allowed 'maxPages' parameter. Default: 200.
+	MaxPages int `yaml:"maxPages"`
+	// DefaultMaxPages is the default of the 'maxPages' parameter. Default: 20.
+	DefaultMaxPages int `yaml:"defaultMaxPages"`
+	// MaxDelay is the largest allowed 'delay' parameter, the longer robots.txt
+	// crawl delays are shortened to it. Default: 10 seconds.
+	MaxDelay time.Duration `yaml:"maxDelay"`
+	// PluginTimeout is the maximum time of the plugin run on a crawled page.
+	// Default: 30 seconds.
+	PluginTimeout time.Duration `yaml:"pluginTimeout"`
+}
+
+func (c Config) Validate() error {
+	if c.MaxPages < 0 {
+		return errors.New("'maxPages' must not be negative")
+	}
+	if c.DefaultMaxPages < 0 {
+		return errors.New("'defaultMaxPages' must not be negative")
+	}
+	if c.MaxPages > 0 && c.DefaultMaxPages > c.MaxPages {
+		return errors.New("'defaultMaxPages' must not exceed 'maxPages'")
+	}
+	if c.MaxDelay < 0 {
+		return errors.New("'maxDelay' must not be negative")
+	}
+	if c.PluginTimeout < 0 {
+		return errors.New("'pluginTimeout' must not be negative")
+	}
+	return c.Config.Validate()
+}
+
+// New creates a crawler that can run any of the given plugins on the crawled pages.
+func New(browser *rod.Browser, pluginsList []plugins.Plugin, cfg Config) *Crawl {
+	p := &Crawl{
+		browser:         browser,
+		plugins:         pluginsList,
+		httpClient:      &http.Client{Timeout: 10 * time.Second},
+		maxTimePerPage:  cfg.TimeoutOr(15 * time.Second),
+		maxPages:        200,
+		defaultMaxPages: 20,
+		defaultMaxDepth: 2,
+		defaultDelay:    time.Second,
+		maxDelay:        10 * time.Second,
+		pluginTimeout:   30 * time.Second,
+	}
+	if cfg.MaxPages > 0 {
+		p.maxPages = cfg.MaxPages
+		p.defaultMaxPages = min(p.defaultMaxPages, p.maxPages)
+	}
+	if cfg.DefaultMaxPages > 0 {
+		p.defaultMaxPages = cfg.DefaultMaxPages
+	}
+	if cfg.MaxDelay > 0 {
+		p.maxDelay = cfg.MaxDelay
+		p.defaultDelay = min(p.defaultDelay, p.maxDelay)
+	}
+	if cfg.PluginTimeout > 0 {
+		p.pluginTimeout = cfg.PluginTimeout
+	}
+	return p
+}
+
+func (p *Crawl) Name() string {
+	return pluginName
+}
+
+// Cost is the maximum number of pages to crawl, plus the cost of running the
+// plugin on every page.
+func (p *Crawl) Cost(params map[string]any) int {
+	maxPages, err := parseCount(params, "maxPages", p.defaultMaxPages, 1)
+	if err != nil {
+		return 1
+	}
+	pages := min(maxPages, p.maxPages)
+	pagePlugin, pluginParams, err := p.parsePlugin(params)
+	if err != nil || pagePlugin == nil {
+		return pages
+	}
+	return pages + pages*plugins.Cost(pagePlugin, withURL(pluginParams, ""))
+}
+
+// NestedPlugins returns the plugin run on the crawled pages, if any.
+func (p *Crawl) NestedPlugins(params map[string]any) []string {
+	if name, ok := params["plugin"].(string); ok {
+		return []string{name}
+	}
+	return nil
+}
+
+// SetRunFunc sets the function that runs the plugin on the crawled pages.
+// The plugin is run directly if it is not set.
+func (p *Crawl) SetRunFunc(run plugins.RunFunc) {
+	p.run = run
+}
+
+type crawlItem struct {
+	url    *url.URL
+	depth  int
+	parent string
+}
+
+func (p *Crawl) Run(ctx context.Context, params map[string]any) (output map[string]any, err error) {
+	seeds, err := parseSeeds(params["urls"])
+	if err != nil {
+		return nil, err
+	}
+	maxDepth, err := parseCount(params, "maxDepth", p.defaultMaxDepth, 0)
+	if err != nil {
+		return nil, err
+	}
+	maxPages, err := parseCount(params, "maxPages", p.defaultMaxPages, 1)
+	if err != nil {
+		return nil, err
+	}
+	if maxPages > p.maxPages {
+		return nil, plugins.ParamErrorf("'maxPages' parameter must not exceed %d", p.maxPages)
+	}
+	delay := p.defaultDelay
+	if value, ok := params["delay"]; ok {
+		seconds, ok := value.(float64)
+		if !ok || seconds < 0 || seconds > p.maxDelay.Seconds() {
+			return nil, plugins.ParamErrorf("'delay' parameter must be a number of seconds between 0 and %g", p.maxDelay.Seconds())
+		}
+		delay = time.Duration(seconds * float64(time.Second))
+	}
+	respectRobots, ok := params["respectRobots"].(bool)
+	if !ok {
+		respectRobots = true
+	}
+	dismissConsent, err := consent.ParseParam(params)
+	if err != nil {
+		return nil, err
+	}
+	crawlScope, err := parseScope(params["scope"], seeds)
+	if err != nil {
+		return nil, err
+	}
+	pagePlugin, pluginParams, err := p.parsePlugin(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var stealthPage *rod.Page
+	stealthPage, err = stealth.Page(p.browser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stealth page: %w", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	page := stealthPage.Context(ctx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to complete: %v", r)
+		}
+		err = plugins.TimeLimitError(ctx, err)
+		_ = page.Close()
+	}()
+
+	go func() {
+		time.Sleep(p.timeLimit(maxPages, delay, respectRobots, pagePlugin != nil))
+		cancel(plugins.ErrTimeLimit)
+	}()
+
+	var (
+		queue      = make([]crawlItem, 0, len(seeds))
+		seen       = make(map[string]bool)
+		lastVisit  = make(map[string]time.Time)
+		rules      = newRobots(p.httpClient)
+		pages      = make([]map[string]any, 0, maxPages)
+		disallowed = make([]string, 0)
+	)
+	for _, seed := range seeds {
+		if !seen[seed.String()] {
+			seen[seed.String()] = true
+			queue = append(queue, crawlItem{url: seed})
+		}
+	}
+
+	for len(queue) > 0 && len(pages) < maxPages {
+		item := queue[0]
+		queue = queue[1:]
+		link := item.url.String()
+
+		hostDelay := delay
+		if respectRobots {
+			allowed, crawlDelay := rules.allowed(ctx, item.url)
+			// The rules fetched by a canceled crawl are not reliable.
+			if ctx.Err() != nil {
+				break
+			}
+			if !allowed {
+				disallowed = append(disallowed, link)
+				continue
+			}
+			hostDelay = max(hostDelay, min(crawlDelay, p.maxDelay))
+		}
+		if last, ok := lastVisit[item.url.Host]; ok {
+			if err := sleepContext(ctx, hostDelay-time.Since(last)); err != nil {
+				break
+			}
+		}
+		lastVisit[item.url.Host] = time.Now()
+
+		node := map[string]any{
+			"url":   link,
+			"depth": item.depth,
+		}
+		if item.parent != "" {
+			node["parent"] = item.parent
+		}
+		pages = append(pages, node)
+
+		title, links, err := p.visit(page, link, dismissConsent)
+		if err != nil {
+			node["error"] = err.Error()
+			if errors.Is(err, botwall.ErrBlocked) {
+				node["blocked"] = true
+			}
+			continue
+		}
+		node["title"] = title
+
+		inScope := make([]string, 0, len(links))
+		for _, l := range links {
+			u, err := normalizeURL(l)
+			if err != nil || !crawlScope.contains(u) {
+				continue
+			}
+			inScope = append(inScope, u.String())
+			if item.depth < maxDepth && !seen[u.String()] {
+				seen[u.String()] = true
+				queue = append(queue, crawlItem{url: u, depth: item.depth + 1, parent: link})
+			}
+		}
+		node["links"] = inScope
+
+		if pagePlugin != nil {
+			result, err := p.runPlugin(ctx, pagePlugin, withURL(pluginParams, link))
+			if err != nil {
+				node["error"] = err.Error()
+			} else {
+				node["result"] = result
+			}
+		}
+	}
+
+	output = make(map[string]any)
+	output["pages"] = pages
+	output["disallowed"] = disallowed
+
+	return output, nil
+}
+
+// timeLimit is the time limit of a crawl: every page may take the time to load
+// it, to run the plugin on it and to wait for the delay of its host, which is
+// up to the maximum delay with the robots.txt crawl delays.
+func (p *Crawl) timeLimit(maxPages int, delay time.Duration, respectRobots, hasPlugin bool) time.Duration {
+	perPage := p.maxTimePerPage + delay
+	if respectRobots {
+		perPage = p.maxTimePerPage + max(delay, p.maxDelay)
+	}
+	if hasPlugin {
+		perPage += p.pluginTimeout
+	}
+	return perPage * time.Duration(maxPages)
+}
+
+// runPlugin runs the plugin on a crawled page.
+func (p *Crawl) runPlugin(ctx context.Context, plugin plugins.Plugin, params map[string]any) (map[string]any, error) {
+	// The run is a part of the crawl's time limit, see timeLimit.
+	ctx, cancel := context.WithTimeoutCause(ctx, p.pluginTimeout, plugins.ErrTimeLimit)
+	defer cancel()
+	if p.run != nil {
+		return p.run(ctx, plugin.Name(), params)
+	}
+	return plugin.Run(ctx, params)
+}
+
+// visit loads the page and returns its title and links.
+func (p *Crawl) visit(page *rod.Page, link string, dismissConsent bool) (string, []string, error) {
+	page = page.Timeout(p.maxTimePerPage)
+	defer page.CancelTimeout()
+
+	if err := tracing.Navigate(page, link); err != nil {
+		return "", nil, fmt.Errorf("failed to navigate to the page '%s': %w", link, err)
+	}
+	if err := tracing.WaitLoad(page); err != nil {
+		return "", nil, fmt.Errorf("failed to wait for page to load: %w", err)
+	}
+	if dismissConsent {
+		consent.Dismiss(page)
+	}
+	if err := botwall.Check(page); err != nil {
+		return "", nil, err
+	}
+	res, err := page.Eval(`() => ({
+		title: document.title,
+		links: Array.from(document.querySelectorAll('a[href]'), a => a.href),
+	})`)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to collect links: %w", err)
+	}
+
+	links := make([]string, 0)
+	for _, l := range res.Value.Get("links").Arr() {
+		links = append(links, l.Str())
+	}
+	return res.Value.Get("title").Str(), links, nil
+}
+
+func (p *Crawl) parsePlugin(params map[string]any) (plugins.Plugin, map[string]any, error) {
+	value, ok := params["plugin"]
+	if !ok {
+		return nil, nil, nil
+	}
+	name, ok := value.(string)
+	if !ok {
+		return nil, nil, plugins.ParamErrorf("'plugin' parameter must be a string")
+	}
+	if name == pluginName {
+		return nil, nil, plugins.ParamErrorf("'plugin' parameter cannot refer to the crawl plugin")
+	}
+	var pluginParams map[string]any
+	if value, ok := params["pluginParams"]; ok {
+		if pluginParams, ok = value.(map[string]any); !ok {
+			return nil, nil, plugins.ParamErrorf("'pluginParams' parameter must be an object")
+		}
+	}
+	for _, plugin := range p.plugins {
+		if plugin.Name() == name {
+			return plugin, pluginParams, nil
+		}
+	}
+	return nil, nil, plugins.ParamErrorf("unknown plugin '%s'", name)
+}
+
+func parseSeeds(raw any) ([]*url.URL, error) {
+	list, ok := raw.([]any)
+	if !ok {
+		return nil, plugins.ParamErrorf("'urls' parameter must be an array of string")
+	}
+	if len(list) == 0 {
+		return nil, plugins.ParamErrorf("empty 'urls' parameter")
+	}
+	seeds := make([]*url.URL, 0, len(list))
+	for _, item := range list {
+		link, ok := item.(string)
+		if !ok {
+			return nil, plugins.ParamErrorf("'urls' parameter must only contain strings")
+		}
+		u, err := normalizeURL(link)
+		if err != nil {
+			return nil, plugins.ParamErrorf("invalid URL '%s': %w", link, err)
+		}
+		seeds = append(seeds, u)
+	}
+	return seeds, nil
+}
+
+func parseCount(params map[string]any, name string, defaultValue, minValue int) (int, error) {
+	value, ok := params[name]
+	if !ok {
+		return defaultValue, nil
+	}
+	number, ok := value.(float64)
+	if !ok || number != float64(int(number)) || int(number) < minValue {
+		return 0, plugins.ParamErrorf("'%s' parameter must be an integer not less than %d", name, minValue)
+	}
+	return int(number), nil
+}
+
+// withURL copies the plugin params and points them to the crawled page.
+func withURL(params map[string]any, link string) map[string]any {
+	pageParams := make(map[string]any, len(params)+2)
+	for k, v := range params {
+		pageParams[k] = v
+	}
+	pageParams["url"] = link
+	pageParams["urls"] = []any{link}
+	return pageParams
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,207 @@
+package crawl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScope_contains(t *testing.T) {
+	seed, err := normalizeURL("https://go.dev/doc/#top")
+	require.NoError(t, err)
+	assert.Equal(t, "https://go.dev/doc/", seed.String())
+
+	mustParse := func(link string) *url.URL {
+		u, err := normalizeURL(link)
+		require.NoError(t, err)
+		return u
+	}
+
+	t.Run("default scope", func(t *testing.T) {
+		s, err := parseScope(nil, []*url.URL{seed})
+		require.NoError(t, err)
+		assert.True(t, s.contains(mustParse("https://go.dev/blog")))
+		assert.False(t, s.contains(mustParse("https://pkg.go.dev")))
+	})
+
+	t.Run("custom scope", func(t *testing.T) {
+		s, err := parseScope(map[string]any{
+			"sameHost":   false,
+			"pathPrefix": "/doc",
+			"include":    `\.dev/`,
+			"exclude":    `/archive`,
+		}, []*url.URL{seed})
+		require.NoError(t, err)
+		assert.True(t, s.contains(mustParse("https://pkg.go.dev/doc/tutorial")))
+		assert.False(t, s.contains(mustParse("https://go.dev/blog")))
+		assert.False(t, s.contains(mustParse("https://go.dev/doc/archive")))
+		assert.False(t, s.contains(mustParse("https://golang.org/doc/")))
+	})
+
+	t.Run("invalid scope", func(t *testing.T) {
+		_, err := parseScope("go.dev", nil)
+		assert.EqualError(t, err, "'scope' parameter must be an object")
+		_, err = parseScope(map[string]any{"sameHost": "yes"}, nil)
+		assert.EqualError(t, err, "'scope.sameHost' parameter must be a boolean")
+		_, err = parseScope(map[string]any{"include": "("}, nil)
+		assert.ErrorContains(t, err, "'scope.include' parameter is not a valid regular expression")
+	})
+
+	t.Run("invalid links", func(t *testing.T) {
+		_, err := normalizeURL("mailto:gopher@go.dev")
+		assert.EqualError(t, err, "unsupported scheme 'mailto'")
+		_, err = normalizeURL("https:///path")
+		assert.EqualError(t, err, "missing host")
+	})
+}
+
+func TestRobots_allowed(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/robots.txt", r.URL.Path)
+		_, _ = w.Write([]byte("User-agent: *\nDisallow: /private\nCrawl-delay: 2\n"))
+	}))
+	defer server.Close()
+
+	r := newRobots(server.Client())
+	allowed, delay := r.allowed(context.Background(), &url.URL{Scheme: "http", Host: server.Listener.Addr().String(), Path: "/public"})
+	assert.True(t, allowed)
+	assert.Equal(t, 2*time.Second, delay)
+	allowed, _ = r.allowed(context.Background(), &url.URL{Scheme: "http", Host: server.Listener.Addr().String(), Path: "/private/page"})
+	assert.False(t, allowed)
+	assert.Equal(t, 1, requests)
+
+	// Unreachable hosts have no rules.
+	allowed, delay = r.allowed(context.Background(), &url.URL{Scheme: "http", Host: "127.0.0.1:1", Path: "/"})
+	assert.True(t, allowed)
+	assert.Zero(t, delay)
+}
+
+func TestRobots_canceled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	r := newRobots(server.Client())
+	r.allowed(ctx, &url.URL{Scheme: "http", Host: server.Listener.Addr().String(), Path: "/"})
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestCrawl_parsePlugin(t *testing.T) {
+	screenshot := &mockPlugin{name: "screenshot"}
+	p := New(nil, []plugins.Plugin{screenshot}, Config{})
+
+	plugin, params, err := p.parsePlugin(map[string]any{
+		"plugin":       "screenshot",
+		"pluginParams": map[string]any{"waitStable": false},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, screenshot, plugin)
+	assert.Equal(t, map[string]any{
+		"waitStable": false,
+		"url":        "https://go.dev/",
+		"urls":       []any{"https://go.dev/"},
+	}, withURL(params, "https://go.dev/"))
+
+	plugin, _, err = p.parsePlugin(map[string]any{})
+	require.NoError(t, err)
+	assert.Nil(t, plugin)
+
+	_, _, err = p.parsePlugin(map[string]any{"plugin": "crawl"})
+	assert.EqualError(t, err, "'plugin' parameter cannot refer to the crawl plugin")
+	_, _, err = p.parsePlugin(map[string]any{"plugin": "dne"})
+	assert.EqualError(t, err, "unknown plugin 'dne'")
+}
+
+func TestCrawl_Cost(t *testing.T) {
+	p := New(nil, nil, Config{})
+	assert.Equal(t, 20, plugins.Cost(p, map[string]any{}))
+	assert.Equal(t, 50, plugins.Cost(p, map[string]any{"maxPages": float64(50)}))
+	assert.Equal(t, 200, plugins.Cost(p, map[string]any{"maxPages": float64(5000)}))
+	assert.Equal(t, 1, plugins.Cost(p, map[string]any{"maxPages": "many"}))
+
+	p = New(nil, []plugins.Plugin{&mockPlugin{name: "screenshot"}, &mockCoster{mockPlugin{name: "search"}}}, Config{})
+	assert.Equal(t, 20, plugins.Cost(p, map[string]any{"plugin": "dne"}))
+	assert.Equal(t, 40, plugins.Cost(p, map[string]any{"plugin": "screenshot"}))
+	assert.Equal(t, 40, plugins.Cost(p, map[string]any{"plugin": "search", "maxPages": float64(10)}))
+}
+
+func TestCrawl_NestedPlugins(t *testing.T) {
+	screenshot := &mockPlugin{name: "screenshot"}
+	p := New(nil, []plugins.Plugin{screenshot}, Config{})
+	assert.Equal(t, []string{"screenshot"}, plugins.Nested(p, map[string]any{"plugin": "screenshot"}))
+	assert.Nil(t, plugins.Nested(p, map[string]any{}))
+
+	output, err := p.runPlugin(context.Background(), screenshot, map[string]any{})
+	require.NoError(t, err)
+	assert.Nil(t, output)
+
+	var ran string
+	p.SetRunFunc(func(_ context.Context, plugin string, params map[string]any) (map[string]any, error) {
+		ran = plugin
+		return map[string]any{"url": params["url"]}, nil
+	})
+	output, err = p.runPlugin(context.Background(), screenshot, withURL(nil, "https://go.dev/"))
+	require.NoError(t, err)
+	assert.Equal(t, "screenshot", ran)
+	assert.Equal(t, map[string]any{"url": "https://go.dev/"}, output)
+}
+
+func TestCrawl_timeLimit(t *testing.T) {
+	p := New(nil, nil, Config{
+		Config:        plugins.Config{Timeout: 15 * time.Second},
+		MaxDelay:      5 * time.Second,
+		PluginTimeout: 20 * time.Second,
+	})
+	assert.Equal(t, 10*(16*time.Second), p.timeLimit(10, time.Second, false, false))
+	// Up to the maximum delay per page with the robots.txt crawl delays.
+	assert.Equal(t, 10*(20*time.Second), p.timeLimit(10, time.Second, true, false))
+	assert.Equal(t, 10*(40*time.Second), p.timeLimit(10, time.Second, true, true))
+
+	_, err := p.Run(context.Background(), map[string]any{"urls": []any{"https://go.dev/"}, "delay": float64(6)})
+	assert.EqualError(t, err, "'delay' parameter must be a number of seconds between 0 and 5")
+
+	// The plugin run on a page is a part of the time limit of the crawl.
+	p = New(nil, nil, Config{PluginTimeout: 10 * time.Millisecond})
+	p.SetRunFunc(func(ctx context.Context, _ string, _ map[string]any) (map[string]any, error) {
+		<-ctx.Done()
+		return nil, plugins.TimeLimitError(ctx, ctx.Err())
+	})
+	_, err = p.runPlugin(context.Background(), &mockPlugin{name: "screenshot"}, nil)
+	assert.ErrorIs(t, err, plugins.ErrTimeLimit)
+}
+
+type mockPlugin struct {
+	name string
+}
+
+func (mp *mockPlugin) Name() string {
+	return mp.name
+}
+
+func (mp *mockPlugin) Run(context.Context, map[string]any) (map[string]any, error) {
+	return nil, nil
+}
+
+// mockCoster costs 3 per run.
+type mockCoster struct {
+	mockPlugin
+}
+
+func (mc *mockCoster) Cost(map[string]any) int {
+	return 3
+}
@@ -0,0 +1,67 @@
+package crawl
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+const userAgent = "BrowserBro"
+
+// robots fetches and caches robots.txt rules per host.
+type robots struct {
+	client *http.Client
+	rules  map[string]*robotstxt.Group
+}
+
+func newRobots(client *http.Client) *robots {
+	return &robots{
+		client: client,
+		rules:  make(map[string]*robotstxt.Group),
+	}
+}
+
+// allowed reports whether the page can be crawled and the crawl delay requested by the host.
+func (r *robots) allowed(ctx context.Context, u *url.URL) (bool, time.Duration) {
+	group := r.group(ctx, u)
+	if group == nil {
+		return true, 0
+	}
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return group.Test(path), group.CrawlDelay
+}
+
+func (r *robots) group(ctx context.Context, u *url.URL) *robotstxt.Group {
+	key := u.Scheme + "://" + u.Host
+	if group, ok := r.rules[key]; ok {
+		return group
+	}
+
+	var group *robotstxt.Group
+	resp, err := r.fetch(ctx, key+"/robots.txt")
+	if err == nil {
+		// Missing rules allow everything and server errors disallow everything.
+		data, err := robotstxt.FromResponse(resp)
+		_ = resp.Body.Close()
+		if err == nil {
+			group = data.FindGroup(userAgent)
+		}
+	}
+	r.rules[key] = group
+
+	return group
+}
+
+func (r *robots) fetch(ctx context.Context, link string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	return r.client.Do(req)
+}
@@ -0,0 +1,112 @@
+package crawl
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/bazuker/browserbro/pkg/plugins"
+)
+
+// scope decides which discovered links are followed.
+type scope struct {
+	sameHost   bool
+	pathPrefix string
+	include    *regexp.Regexp
+	exclude    *regexp.Regexp
+	hosts      map[string]bool
+}
+
+func parseScope(raw any, seeds []*url.URL) (*scope, error) {
+	s := &scope{
+		sameHost: true,
+		hosts:    make(map[string]bool),
+	}
+	for _, seed := range seeds {
+		s.hosts[seed.Host] = true
+	}
+	if raw == nil {
+		return s, nil
+	}
+
+	params, ok := raw.(map[string]any)
+	if !ok {
+		return nil, plugins.ParamErrorf("'scope' parameter must be an object")
+	}
+	if value, ok := params["sameHost"]; ok {
+		if s.sameHost, ok = value.(bool); !ok {
+			return nil, plugins.ParamErrorf("'scope.sameHost' parameter must be a boolean")
+		}
+	}
+	if value, ok := params["pathPrefix"]; ok {
+		if s.pathPrefix, ok = value.(string); !ok {
+			return nil, plugins.ParamErrorf("'scope.pathPrefix' parameter must be a string")
+		}
+	}
+	var err error
+	if s.include, err = parseRegex(params, "include"); err != nil {
+		return nil, err
+	}
+	if s.exclude, err = parseRegex(params, "exclude"); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func parseRegex(params map[string]any, name string) (*regexp.Regexp, error) {
+	value, ok := params[name]
+	if !ok {
+		return nil, nil
+	}
+	expr, ok := value.(string)
+	if !ok {
+		return nil, plugins.ParamErrorf("'scope.%s' parameter must be a string", name)
+	}
+	regex, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, plugins.ParamErrorf("'scope.%s' parameter is not a valid regular expression: %w", name, err)
+	}
+	return regex, nil
+}
+
+func (s *scope) contains(u *url.URL) bool {
+	if s.sameHost && !s.hosts[u.Host] {
+		return false
+	}
+	if s.pathPrefix != "" && !strings.HasPrefix(u.Path, s.pathPrefix) {
+		return false
+	}
+	link := u.String()
+	if s.include != nil && !s.include.MatchString(link) {
+		return false
+	}
+	if s.exclude != nil && s.exclude.MatchString(link) {
+		return false
+	}
+	return true
+}
+
+// normalizeURL parses an absolute HTTP(S) link and strips its fragment so
+// that the same page is not visited twice.
+func normalizeURL(link string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme '%s'", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, errors.New("missing host")
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.Host = strings.ToLower(u.Host)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u, nil
+}
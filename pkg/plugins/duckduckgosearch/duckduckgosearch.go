@@ -0,0 +1,171 @@
+package duckduckgosearch
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/fs"
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/plugins/botwall"
+	"github.com/bazuker/browserbro/pkg/plugins/consent"
+	"github.com/bazuker/browserbro/pkg/plugins/serp"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/stealth"
+)
+
+const (
+	pluginName = "duckduckgosearch"
+)
+
+// timeFilters maps the time ranges supported by DuckDuckGo to its "df" parameter.
+var timeFilters = map[string]string{
+	"day":   "d",
+	"week":  "w",
+	"month": "m",
+	"year":  "y",
+}
+
+// resultsJS collects the organic results of the HTML version results page.
+const resultsJS = `() => Array.from(document.querySelectorAll('.result:not(.result--ad)'), block => {
+	const a = block.querySelector('a.result__a');
+	const description = block.querySelector('.result__snippet');
+	return {
+		link: a ? a.href : '',
+		title: a ? a.innerText : '',
+		description: description ? description.innerText : '',
+	};
+})`
+
+type DuckDuckGoSearch struct {
+	browser          *rod.Browser
+	fileStore        fs.FileStore
+	maxTimePerSearch time.Duration
+}
+
+func New(browser *rod.Browser, fileStore fs.FileStore, cfg plugins.Config) *DuckDuckGoSearch {
+	return &DuckDuckGoSearch{
+		browser:          browser,
+		fileStore:        fileStore,
+		maxTimePerSearch: cfg.TimeoutOr(15 * time.Second),
+	}
+}
+
+func (p *DuckDuckGoSearch) Name() string {
+	return pluginName
+}
+
+// Cost is the number of results pages to read.
+func (p *DuckDuckGoSearch) Cost(params map[string]any) int {
+	query, err := serp.ParseQuery(params)
+	if err != nil {
+		return 1
+	}
+	return query.Pages
+}
+
+func (p *DuckDuckGoSearch) Run(ctx context.Context, params map[string]any) (output map[string]any, err error) {
+	query, err := serp.ParseQuery(params)
+	if err != nil {
+		return nil, err
+	}
+	searchURL, err := buildSearchURL(query)
+	if err != nil {
+		return nil, err
+	}
+	screenshotOnBlock, err := botwall.ParseScreenshotParam(params)
+	if err != nil {
+		return nil, err
+	}
+	dismissConsent, err := consent.ParseParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var stealthPage *rod.Page
+	stealthPage, err = stealth.Page(p.browser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stealth page: %w", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	page := stealthPage.Context(ctx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to complete: %v", r)
+		}
+		err = plugins.TimeLimitError(ctx, err)
+		_ = page.Close()
+	}()
+
+	go func() {
+		time.Sleep(p.maxTimePerSearch * time.Duration(query.Pages))
+		cancel(plugins.ErrTimeLimit)
+	}()
+
+	paginator := serp.Paginator{
+		Parse:          serp.ParseScript(resultsJS),
+		Next:           nextPage,
+		DismissConsent: dismissConsent,
+	}
+	results, err := paginator.Collect(page, searchURL, query.Pages)
+	if err != nil {
+		if screenshotOnBlock {
+			err = botwall.Capture(page, p.fileStore, err)
+		}
+		return nil, err
+	}
+
+	output = make(map[string]any)
+	output["all"] = results
+
+	return output, nil
+}
+
+// nextPage submits the "Next" form of the HTML version results page.
+func nextPage(page *rod.Page) (bool, error) {
+	hasNext, next, err := page.Has(`.nav-link input[type="submit"][value="Next"]`)
+	if err != nil || !hasNext {
+		return false, err
+	}
+	wait := page.WaitNavigation("load")
+	if err := next.Click("left", 1); err != nil {
+		return false, err
+	}
+	wait()
+	return true, nil
+}
+
+func buildSearchURL(query serp.Query) (string, error) {
+	// The HTML version always has the same number of results per page.
+	if query.Num > 0 {
+		return "", plugins.ParamErrorf("'num' parameter is not supported by DuckDuckGo")
+	}
+	values := url.Values{}
+	values.Set("q", query.Text)
+	// DuckDuckGo regions combine the country and the language, e.g. "us-en".
+	if query.Country != "" || query.Language != "" {
+		if query.Country == "" || query.Language == "" {
+			return "", plugins.ParamErrorf("'hl' and 'gl' parameters must be used together with DuckDuckGo")
+		}
+		values.Set("kl", strings.ToLower(query.Country)+"-"+strings.ToLower(query.Language))
+	}
+	if query.Safe != nil {
+		if *query.Safe {
+			values.Set("kp", "1")
+		} else {
+			values.Set("kp", "-2")
+		}
+	}
+	if query.TimeRange != "" {
+		filter, ok := timeFilters[query.TimeRange]
+		if !ok {
+			return "", plugins.ParamErrorf("time range '%s' is not supported by DuckDuckGo", query.TimeRange)
+		}
+		values.Set("df", filter)
+	}
+	return "https://html.duckduckgo.com/html/?" + values.Encode(), nil
+}
@@ -0,0 +1,29 @@
+package duckduckgosearch
+
+import (
+	"testing"
+
+	"github.com/bazuker/browserbro/pkg/plugins/serp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSearchURL(t *testing.T) {
+	safe := false
+	searchURL, err := buildSearchURL(serp.Query{
+		Text:      "golang",
+		Language:  "de",
+		Country:   "AT",
+		Safe:      &safe,
+		TimeRange: "year",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://html.duckduckgo.com/html/?df=y&kl=at-de&kp=-2&q=golang", searchURL)
+
+	_, err = buildSearchURL(serp.Query{Text: "golang", Language: "de"})
+	assert.EqualError(t, err, "'hl' and 'gl' parameters must be used together with DuckDuckGo")
+	_, err = buildSearchURL(serp.Query{Text: "golang", TimeRange: "hour"})
+	assert.EqualError(t, err, "time range 'hour' is not supported by DuckDuckGo")
+	_, err = buildSearchURL(serp.Query{Text: "golang", Num: 50})
+	assert.EqualError(t, err, "'num' parameter is not supported by DuckDuckGo")
+}
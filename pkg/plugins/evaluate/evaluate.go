@@ -0,0 +1,207 @@
+package evaluate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/plugins/consent"
+	"github.com/bazuker/browserbro/pkg/tracing"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/stealth"
+)
+
+const (
+	pluginName = "evaluate"
+)
+
+type Evaluate struct {
+	browser            *rod.Browser
+	maxTimePerPage     time.Duration
+	defaultEvalTimeout time.Duration
+	maxEvalTimeout     time.Duration
+	maxResultSize      int
+}
+
+// Config is the configuration section of the evaluate plugin.
+type Config struct {
+	plugins.Config `yaml:",inline"`
+	// MaxScriptTimeout is the largest allowed 'timeout' parameter. Default: 30 seconds.
+	MaxScriptTimeout time.Duration `yaml:"maxScriptTimeout"`
+	// MaxResultSize is the largest allowed size of the encoded result in bytes. Default: 1 MiB.
+	MaxResultSize int `yaml:"maxResultSize"`
+}
+
+func (c Config) Validate() error {
+	if c.MaxScriptTimeout < 0 {
+		return errors.New("'maxScriptTimeout' must not be negative")
+	}
+	if c.MaxResultSize < 0 {
+		return errors.New("'maxResultSize' must not be negative")
+	}
+	return c.Config.Validate()
+}
+
+func New(browser *rod.Browser, cfg Config) *Evaluate {
+	p := &Evaluate{
+		browser:            browser,
+		maxTimePerPage:     cfg.TimeoutOr(15 * time.Second),
+		defaultEvalTimeout: 5 * time.Second,
+		maxEvalTimeout:     30 * time.Second,
+		maxResultSize:      1 << 20,
+	}
+	if cfg.MaxScriptTimeout > 0 {
+		p.maxEvalTimeout = cfg.MaxScriptTimeout
+		p.defaultEvalTimeout = min(p.defaultEvalTimeout, p.maxEvalTimeout)
+	}
+	if cfg.MaxResultSize > 0 {
+		p.maxResultSize = cfg.MaxResultSize
+	}
+	return p
+}
+
+func (p *Evaluate) Name() string {
+	return pluginName
+}
+
+func (p *Evaluate) Run(ctx context.Context, params map[string]any) (output map[string]any, err error) {
+	urlString, ok := params["url"].(string)
+	if !ok || urlString == "" {
+		return nil, plugins.ParamErrorf("'url' parameter must be a non-empty string")
+	}
+	script, ok := params["script"].(string)
+	if !ok || strings.TrimSpace(script) == "" {
+		return nil, plugins.ParamErrorf("'script' parameter must be a non-empty string")
+	}
+	var args []any
+	if rawArgs, ok := params["args"]; ok {
+		args, ok = rawArgs.([]any)
+		if !ok {
+			return nil, plugins.ParamErrorf("'args' parameter must be an array")
+		}
+	}
+	evalTimeout, err := p.parseTimeout(params["timeout"])
+	if err != nil {
+		return nil, err
+	}
+	wait, err := parseWaitCondition(params)
+	if err != nil {
+		return nil, err
+	}
+	dismissConsent, err := consent.ParseParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var stealthPage *rod.Page
+	stealthPage, err = stealth.Page(p.browser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stealth page: %w", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	page := stealthPage.Context(ctx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to complete: %v", r)
+		}
+		err = plugins.TimeLimitError(ctx, err)
+		_ = page.Close()
+	}()
+
+	go func() {
+		time.Sleep(p.maxTimePerPage + evalTimeout)
+		cancel(plugins.ErrTimeLimit)
+	}()
+
+	err = tracing.Navigate(page, urlString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to navigate to the page '%s': %w", urlString, err)
+	}
+	err = tracing.WaitLoad(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for page to load: %w", err)
+	}
+	if dismissConsent {
+		consent.Dismiss(page)
+	}
+	err = wait.wait(page)
+	if err != nil {
+		return nil, err
+	}
+
+	evalPage := page.Timeout(evalTimeout)
+	defer evalPage.CancelTimeout()
+	res, err := evalPage.Evaluate(rod.Eval(wrapScript(script), args...).ByPromise())
+	if err != nil {
+		var evalErr *rod.EvalError
+		if errors.As(err, &evalErr) {
+			return nil, fmt.Errorf("script threw an exception: %s", describeException(evalErr))
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("script did not complete within %s", evalTimeout)
+		}
+		return nil, fmt.Errorf("failed to evaluate script: %w", err)
+	}
+
+	result, err := res.Value.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode script result: %w", err)
+	}
+	if len(result) > p.maxResultSize {
+		return nil, fmt.Errorf(
+			"script result is %d bytes which exceeds the limit of %d bytes",
+			len(result),
+			p.maxResultSize,
+		)
+	}
+
+	output = make(map[string]any)
+	output["result"] = res.Value.Val()
+	if info, err := page.Info(); err == nil {
+		output["url"] = info.URL
+	}
+
+	return output, nil
+}
+
+func (p *Evaluate) parseTimeout(raw any) (time.Duration, error) {
+	if raw == nil {
+		return p.defaultEvalTimeout, nil
+	}
+	seconds, ok := raw.(float64)
+	if !ok || seconds <= 0 {
+		return 0, plugins.ParamErrorf("'timeout' parameter must be a positive number")
+	}
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout > p.maxEvalTimeout {
+		return 0, plugins.ParamErrorf("'timeout' parameter must not exceed %s", p.maxEvalTimeout)
+	}
+	return timeout, nil
+}
+
+// wrapScript turns a function body into an async function so that the body
+// can use await and return a value.
+func wrapScript(body string) string {
+	return "async function() {\n" + body + "\n}"
+}
+
+func describeException(evalErr *rod.EvalError) string {
+	message := evalErr.Text
+	if exception := evalErr.Exception; exception != nil {
+		if exception.Description != "" {
+			// The description contains the stack trace after the message.
+			message, _, _ = strings.Cut(exception.Description, "\n")
+		} else if !exception.Value.Nil() {
+			// Thrown values that are not errors have no description.
+			message = evalErr.Text + " " + exception.Value.JSON("", "")
+		}
+	}
+	// The wrapper adds one line before the body, so the 0-based line number
+	// of the wrapped script is the 1-based line number of the body.
+	return fmt.Sprintf("%s (line %d, column %d)", message, evalErr.LineNumber, evalErr.ColumnNumber+1)
+}
@@ -0,0 +1,59 @@
+package evaluate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluate_parseTimeout(t *testing.T) {
+	p := New(nil, Config{})
+
+	timeout, err := p.parseTimeout(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, timeout)
+
+	timeout, err = p.parseTimeout(1.5)
+	require.NoError(t, err)
+	assert.Equal(t, 1500*time.Millisecond, timeout)
+
+	_, err = p.parseTimeout("1")
+	assert.EqualError(t, err, "'timeout' parameter must be a positive number")
+	_, err = p.parseTimeout(0.0)
+	assert.EqualError(t, err, "'timeout' parameter must be a positive number")
+	_, err = p.parseTimeout(31.0)
+	assert.EqualError(t, err, "'timeout' parameter must not exceed 30s")
+}
+
+func Test_parseWaitCondition(t *testing.T) {
+	cond, err := parseWaitCondition(map[string]any{
+		"waitSelector":    "#app",
+		"waitNetworkIdle": true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, waitCondition{selector: "#app", networkIdle: true}, cond)
+
+	_, err = parseWaitCondition(map[string]any{"waitSelector": ""})
+	assert.EqualError(t, err, "'waitSelector' parameter must be a non-empty string")
+	_, err = parseWaitCondition(map[string]any{"waitStable": "yes"})
+	assert.EqualError(t, err, "'waitStable' parameter must be a boolean")
+}
+
+func Test_describeException(t *testing.T) {
+	evalErr := &rod.EvalError{RuntimeExceptionDetails: &proto.RuntimeExceptionDetails{
+		Text:         "Uncaught",
+		LineNumber:   2,
+		ColumnNumber: 4,
+		Exception: &proto.RuntimeRemoteObject{
+			Description: "Error: boom\n    at <anonymous>:3:5",
+		},
+	}}
+	assert.Equal(t, "Error: boom (line 2, column 5)", describeException(evalErr))
+
+	evalErr.Exception = nil
+	assert.Equal(t, "Uncaught (line 2, column 5)", describeException(evalErr))
+}
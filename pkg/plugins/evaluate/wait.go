@@ -0,0 +1,63 @@
+package evaluate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/tracing"
+	"github.com/go-rod/rod"
+)
+
+// waitCondition describes what to wait for after the page is loaded.
+type waitCondition struct {
+	selector    string
+	networkIdle bool
+	stable      bool
+}
+
+func parseWaitCondition(params map[string]any) (waitCondition, error) {
+	var cond waitCondition
+	if raw, ok := params["waitSelector"]; ok {
+		selector, ok := raw.(string)
+		if !ok || selector == "" {
+			return cond, plugins.ParamErrorf("'waitSelector' parameter must be a non-empty string")
+		}
+		cond.selector = selector
+	}
+	if raw, ok := params["waitNetworkIdle"]; ok {
+		networkIdle, ok := raw.(bool)
+		if !ok {
+			return cond, plugins.ParamErrorf("'waitNetworkIdle' parameter must be a boolean")
+		}
+		cond.networkIdle = networkIdle
+	}
+	if raw, ok := params["waitStable"]; ok {
+		stable, ok := raw.(bool)
+		if !ok {
+			return cond, plugins.ParamErrorf("'waitStable' parameter must be a boolean")
+		}
+		cond.stable = stable
+	}
+	return cond, nil
+}
+
+func (w waitCondition) wait(page *rod.Page) error {
+	if w.networkIdle {
+		page.WaitRequestIdle(500*time.Millisecond, nil, nil, nil)()
+		if err := page.GetContext().Err(); err != nil {
+			return fmt.Errorf("failed to wait for network idle: %w", err)
+		}
+	}
+	if w.stable {
+		if err := tracing.WaitStable(page, time.Second); err != nil {
+			return fmt.Errorf("failed to wait for page to stabilize: %w", err)
+		}
+	}
+	if w.selector != "" {
+		if _, err := page.Element(w.selector); err != nil {
+			return fmt.Errorf("failed to wait for '%s': %w", w.selector, err)
+		}
+	}
+	return nil
+}
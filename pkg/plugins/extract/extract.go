@@ -0,0 +1,125 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/plugins/consent"
+	"github.com/bazuker/browserbro/pkg/tracing"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/stealth"
+	"golang.org/x/net/html"
+)
+
+const (
+	pluginName = "extract"
+)
+
+type Extract struct {
+	browser        *rod.Browser
+	maxTimePerPage time.Duration
+}
+
+func New(browser *rod.Browser, cfg plugins.Config) *Extract {
+	return &Extract{
+		browser:        browser,
+		maxTimePerPage: cfg.TimeoutOr(15 * time.Second),
+	}
+}
+
+func (p *Extract) Name() string {
+	return pluginName
+}
+
+func (p *Extract) Run(ctx context.Context, params map[string]any) (output map[string]any, err error) {
+	urlString, ok := params["url"].(string)
+	if !ok || urlString == "" {
+		return nil, plugins.ParamErrorf("'url' parameter must be a non-empty string")
+	}
+	s, err := parseSchema(params["schema"])
+	if err != nil {
+		return nil, err
+	}
+	var waitSelector string
+	if value, ok := params["waitSelector"]; ok {
+		if waitSelector, ok = value.(string); !ok {
+			return nil, plugins.ParamErrorf("'waitSelector' parameter must be a string")
+		}
+		if waitSelector != "" {
+			if _, err := cascadia.ParseGroup(waitSelector); err != nil {
+				return nil, plugins.ParamErrorf("'waitSelector' parameter is not a valid CSS selector: %w", err)
+			}
+		}
+	}
+	waitStable, ok := params["waitStable"].(bool)
+	if !ok {
+		waitStable = true
+	}
+	dismissConsent, err := consent.ParseParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var stealthPage *rod.Page
+	stealthPage, err = stealth.Page(p.browser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stealth page: %w", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	page := stealthPage.Context(ctx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to complete: %v", r)
+		}
+		err = plugins.TimeLimitError(ctx, err)
+		_ = page.Close()
+	}()
+
+	go func() {
+		time.Sleep(p.maxTimePerPage)
+		cancel(plugins.ErrTimeLimit)
+	}()
+
+	err = tracing.Navigate(page, urlString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to navigate to the page '%s': %w", urlString, err)
+	}
+	err = tracing.WaitLoad(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for page to load: %w", err)
+	}
+	if dismissConsent {
+		consent.Dismiss(page)
+	}
+	if waitStable {
+		err = tracing.WaitStable(page, time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("failed to wait for page to stabilize: %w", err)
+		}
+	}
+	if waitSelector != "" {
+		_, err = page.Element(waitSelector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to wait for '%s': %w", waitSelector, err)
+		}
+	}
+
+	content, err := page.HTML()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page content: %w", err)
+	}
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse page content: %w", err)
+	}
+
+	output = s.apply(doc)
+
+	return output, nil
+}
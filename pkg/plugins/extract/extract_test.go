@@ -0,0 +1,22 @@
+package extract
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtract_RunParams(t *testing.T) {
+	p := New(nil, plugins.Config{})
+	schema := map[string]any{"title": map[string]any{"selector": "h1"}}
+
+	_, err := p.Run(context.Background(), map[string]any{"url": "https://go.dev", "schema": schema, "waitSelector": true})
+	assert.EqualError(t, err, "'waitSelector' parameter must be a string")
+	var paramErr *plugins.ParamError
+	assert.ErrorAs(t, err, &paramErr)
+
+	_, err = p.Run(context.Background(), map[string]any{"url": "https://go.dev", "schema": schema, "waitSelector": "div["})
+	assert.ErrorContains(t, err, "'waitSelector' parameter is not a valid CSS selector")
+}
@@ -0,0 +1,213 @@
+package extract
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/htmlquery"
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"golang.org/x/net/html"
+)
+
+const (
+	valueText      = "text"
+	valueHTML      = "html"
+	valueAttribute = "attribute"
+)
+
+// field describes how to extract a single named value from a document.
+type field struct {
+	// Selector is a CSS selector relative to the parent element.
+	Selector string `json:"selector"`
+	// XPath is an XPath expression relative to the parent element.
+	XPath string `json:"xpath"`
+	// Type is the extracted value type: text, html or attribute.
+	Type string `json:"type"`
+	// Attribute is the name of the attribute to extract.
+	Attribute string `json:"attribute"`
+	// List extracts every matched element instead of the first one.
+	List bool `json:"list"`
+	// Fields is a nested schema applied to every matched element.
+	Fields map[string]*field `json:"fields"`
+	// Regex is applied to the extracted value.
+	Regex string `json:"regex"`
+	// Group is the regex capturing group to keep. Default: the whole match.
+	Group int `json:"group"`
+
+	regex *regexp.Regexp
+}
+
+type schema map[string]*field
+
+func parseSchema(raw any) (schema, error) {
+	if _, ok := raw.(map[string]any); !ok {
+		return nil, plugins.ParamErrorf("'schema' parameter must be an object")
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode schema: %w", err)
+	}
+	var s schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, plugins.ParamErrorf("'schema' parameter is malformed: %w", err)
+	}
+	if len(s) == 0 {
+		return nil, plugins.ParamErrorf("empty 'schema' parameter")
+	}
+	if err := s.compile(""); err != nil {
+		return nil, &plugins.ParamError{Err: err}
+	}
+	return s, nil
+}
+
+// compile validates the schema and compiles its regular expressions.
+func (s schema) compile(path string) error {
+	for name, f := range s {
+		fieldPath := path + name
+		if f == nil {
+			return fmt.Errorf("field '%s' must be an object", fieldPath)
+		}
+		if f.Selector != "" && f.XPath != "" {
+			return fmt.Errorf("field '%s': only one of 'selector' and 'xpath' can be set", fieldPath)
+		}
+		if f.Type == "" {
+			f.Type = valueText
+			if f.Attribute != "" {
+				f.Type = valueAttribute
+			}
+		}
+		switch f.Type {
+		case valueText, valueHTML:
+		case valueAttribute:
+			if f.Attribute == "" {
+				return fmt.Errorf("field '%s': missing 'attribute'", fieldPath)
+			}
+		default:
+			return fmt.Errorf("field '%s': invalid type '%s'", fieldPath, f.Type)
+		}
+		// goquery silently matches nothing for invalid selectors.
+		if f.Selector != "" {
+			if _, err := cascadia.ParseGroup(f.Selector); err != nil {
+				return fmt.Errorf("field '%s': invalid selector: %w", fieldPath, err)
+			}
+		}
+		if f.XPath != "" {
+			if _, err := htmlquery.QueryAll(&html.Node{Type: html.DocumentNode}, f.XPath); err != nil {
+				return fmt.Errorf("field '%s': invalid xpath: %w", fieldPath, err)
+			}
+		}
+		if f.Regex != "" {
+			if len(f.Fields) > 0 {
+				return fmt.Errorf("field '%s': 'regex' cannot be combined with 'fields'", fieldPath)
+			}
+			regex, err := regexp.Compile(f.Regex)
+			if err != nil {
+				return fmt.Errorf("field '%s': invalid regex: %w", fieldPath, err)
+			}
+			if f.Group < 0 || f.Group > regex.NumSubexp() {
+				return fmt.Errorf("field '%s': regex has no group %d", fieldPath, f.Group)
+			}
+			f.regex = regex
+		}
+		if len(f.Fields) > 0 {
+			if err := schema(f.Fields).compile(fieldPath + "."); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// apply extracts the schema fields relative to the node.
+func (s schema) apply(node *html.Node) map[string]any {
+	output := make(map[string]any, len(s))
+	for name, f := range s {
+		output[name] = f.extract(node)
+	}
+	return output
+}
+
+func (f *field) extract(node *html.Node) any {
+	nodes := f.match(node)
+	if !f.List {
+		if len(nodes) == 0 {
+			return nil
+		}
+		return f.value(nodes[0])
+	}
+
+	values := make([]any, 0, len(nodes))
+	for _, n := range nodes {
+		value := f.value(n)
+		if value == nil {
+			continue
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// match returns the elements matched by the field relative to the node.
+// A field without a selector matches the node itself.
+func (f *field) match(node *html.Node) []*html.Node {
+	switch {
+	case f.XPath != "":
+		nodes, err := htmlquery.QueryAll(node, f.XPath)
+		if err != nil {
+			return nil
+		}
+		return nodes
+	case f.Selector != "":
+		return goquery.NewDocumentFromNode(node).Find(f.Selector).Nodes
+	default:
+		return []*html.Node{node}
+	}
+}
+
+func (f *field) value(node *html.Node) any {
+	if len(f.Fields) > 0 {
+		return schema(f.Fields).apply(node)
+	}
+
+	var value string
+	switch f.Type {
+	case valueAttribute:
+		if node.Type != html.ElementNode {
+			return nil
+		}
+		attr, ok := goquery.NewDocumentFromNode(node).Attr(f.Attribute)
+		if !ok {
+			return nil
+		}
+		value = attr
+	case valueHTML:
+		if node.Type != html.ElementNode {
+			value = node.Data
+			break
+		}
+		outerHTML, err := goquery.OuterHtml(goquery.NewDocumentFromNode(node).Selection)
+		if err != nil {
+			return nil
+		}
+		value = outerHTML
+	default:
+		value = normalizeSpace(htmlquery.InnerText(node))
+	}
+
+	if f.regex == nil {
+		return value
+	}
+	match := f.regex.FindStringSubmatch(value)
+	if match == nil {
+		return nil
+	}
+	return match[f.Group]
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
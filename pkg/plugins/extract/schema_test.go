@@ -0,0 +1,138 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+)
+
+const testPage = `
+<html>
+<head><title>Results</title></head>
+<body>
+  <h1 class="heading">  Search   results </h1>
+  <div class="g">
+    <a href="https://go.dev"><h3>The Go Programming Language</h3></a>
+    <span>Go is an open source programming language.</span>
+    <span class="price">Price: $10.50</span>
+  </div>
+  <div class="g">
+    <a href="https://pkg.go.dev"><h3>Go Packages</h3></a>
+    <span>Discover packages.</span>
+  </div>
+</body>
+</html>`
+
+func TestSchema_apply(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(testPage))
+	require.NoError(t, err)
+
+	s, err := parseSchema(map[string]any{
+		"heading": map[string]any{"selector": "h1"},
+		"title":   map[string]any{"xpath": "//title"},
+		"missing": map[string]any{"selector": ".missing"},
+		"links":   map[string]any{"selector": "a", "attribute": "href", "list": true},
+		"results": map[string]any{
+			"selector": ".g",
+			"list":     true,
+			"fields": map[string]any{
+				"title":       map[string]any{"selector": "h3"},
+				"link":        map[string]any{"xpath": ".//a/@href"},
+				"description": map[string]any{"selector": "span:first-of-type"},
+				"price": map[string]any{
+					"selector": ".price",
+					"regex":    `\$([0-9.]+)`,
+					"group":    1,
+				},
+			},
+		},
+		"firstHeading": map[string]any{"selector": "h3", "type": "html"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{
+		"heading": "Search results",
+		"title":   "Results",
+		"missing": nil,
+		"links":   []any{"https://go.dev", "https://pkg.go.dev"},
+		"results": []any{
+			map[string]any{
+				"title":       "The Go Programming Language",
+				"link":        "https://go.dev",
+				"description": "Go is an open source programming language.",
+				"price":       "10.50",
+			},
+			map[string]any{
+				"title":       "Go Packages",
+				"link":        "https://pkg.go.dev",
+				"description": "Discover packages.",
+				"price":       nil,
+			},
+		},
+		"firstHeading": "<h3>The Go Programming Language</h3>",
+	}, s.apply(doc))
+}
+
+func Test_parseSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema any
+		err    string
+	}{
+		{"not an object", "title", "'schema' parameter must be an object"},
+		{"empty", map[string]any{}, "empty 'schema' parameter"},
+		{"malformed", map[string]any{"a": map[string]any{"list": "yes"}}, "'schema' parameter is malformed"},
+		{"null field", map[string]any{"a": nil}, "field 'a' must be an object"},
+		{
+			"selector and xpath",
+			map[string]any{"a": map[string]any{"selector": "a", "xpath": "//a"}},
+			"field 'a': only one of 'selector' and 'xpath' can be set",
+		},
+		{
+			"invalid type",
+			map[string]any{"a": map[string]any{"type": "json"}},
+			"field 'a': invalid type 'json'",
+		},
+		{
+			"attribute without name",
+			map[string]any{"a": map[string]any{"type": "attribute"}},
+			"field 'a': missing 'attribute'",
+		},
+		{
+			"invalid selector",
+			map[string]any{"a": map[string]any{"selector": "div["}},
+			"field 'a': invalid selector",
+		},
+		{
+			"invalid xpath",
+			map[string]any{"a": map[string]any{"xpath": "//a["}},
+			"field 'a': invalid xpath",
+		},
+		{
+			"invalid regex",
+			map[string]any{"a": map[string]any{"regex": "("}},
+			"field 'a': invalid regex",
+		},
+		{
+			"missing regex group",
+			map[string]any{"a": map[string]any{"regex": "a(b)", "group": 2}},
+			"field 'a': regex has no group 2",
+		},
+		{
+			"nested field error",
+			map[string]any{"a": map[string]any{
+				"fields": map[string]any{"b": map[string]any{"type": "json"}},
+			}},
+			"field 'a.b': invalid type 'json'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseSchema(tt.schema)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
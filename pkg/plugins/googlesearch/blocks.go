@@ -0,0 +1,122 @@
+package googlesearch
+
+import (
+	"fmt"
+
+	"github.com/go-rod/rod"
+)
+
+// richBlocksJS collects the rich result blocks of the "all" results page.
+// Blocks that are not present on the page are null or empty.
+const richBlocksJS = `() => {
+	const text = el => el ? el.innerText.trim() : '';
+	const link = el => {
+		const a = el && el.querySelector('a[href^="http"]');
+		return a ? a.href : '';
+	};
+
+	let featuredSnippet = null;
+	const snippet = document.querySelector('.xpdopen .hgKElc, [data-attrid="wa:/description"]');
+	if (snippet) {
+		const block = snippet.closest('.xpdopen') || snippet.closest('block-component') || snippet.parentElement;
+		featuredSnippet = {
+			description: text(snippet),
+			title: text(block.querySelector('h3')),
+			link: link(block),
+		};
+	}
+
+	const peopleAlsoAsk = Array.from(document.querySelectorAll('[data-q]'), el => el.getAttribute('data-q'))
+		.filter(q => q);
+
+	let knowledgePanel = null;
+	const panel = document.querySelector('.kp-wholepage, #rhs .kp-blk');
+	if (panel) {
+		const attributes = {};
+		panel.querySelectorAll('[data-attrid^="kc:/"], [data-attrid^="ss:/"]').forEach(row => {
+			const label = text(row.querySelector('.w8qArf')).replace(/:$/, '');
+			const value = text(row.querySelector('.LrzXr, .kno-fv'));
+			if (label && value) {
+				attributes[label] = value;
+			}
+		});
+		knowledgePanel = {
+			title: text(panel.querySelector('[data-attrid="title"]')),
+			subtitle: text(panel.querySelector('[data-attrid="subtitle"]')),
+			description: text(panel.querySelector('.kno-rdesc span, [data-attrid="description"] span')),
+			link: link(panel.querySelector('.kno-rdesc, [data-attrid="description"]')),
+			attributes,
+		};
+	}
+
+	const relatedSearches = Array.from(
+		document.querySelectorAll('#bres a[href*="/search?"], #botstuff a[href*="/search?"]'),
+		text,
+	).filter((q, i, all) => q && all.indexOf(q) === i);
+
+	const topStories = [];
+	const storiesHeading = Array.from(document.querySelectorAll('[role="heading"], h3, span'))
+		.find(el => el.childElementCount === 0 && el.innerText.trim() === 'Top stories');
+	const storiesSection = storiesHeading && (storiesHeading.closest('g-section-with-header') ||
+		storiesHeading.closest('[data-hveid]'));
+	if (storiesSection) {
+		storiesSection.querySelectorAll('a[href^="http"]').forEach(a => {
+			const title = text(a.querySelector('[role="heading"]'));
+			if (title) {
+				topStories.push({
+					title,
+					link: a.href,
+					source: text(a.querySelector('.MgUUmf, .CEMjEf')),
+				});
+			}
+		});
+	}
+
+	return {featuredSnippet, peopleAlsoAsk, knowledgePanel, relatedSearches, topStories};
+}`
+
+// newsResultsJS collects the results of the news results page.
+const newsResultsJS = `() => Array.from(document.querySelectorAll('.SoaBEf, .WlydOe'), block => {
+	const a = block.matches('a') ? block : block.querySelector('a[href^="http"]');
+	const heading = block.querySelector('[role="heading"]');
+	if (!a || !heading) {
+		return null;
+	}
+	const text = selector => {
+		const el = block.querySelector(selector);
+		return el ? el.innerText.trim() : '';
+	};
+	return {
+		link: a.href,
+		title: heading.innerText.trim(),
+		description: text('.GI74Re'),
+		source: text('.MgUUmf, .CEMjEf'),
+		date: text('.OSrXXb, .ZE0LJd'),
+	};
+}).filter(r => r)`
+
+// imageResultsJS collects the results of the images results page.
+const imageResultsJS = `() => Array.from(document.querySelectorAll('div[data-id], div[data-ri]'), block => {
+	const img = block.querySelector('img');
+	const a = Array.from(block.querySelectorAll('a[href^="http"]'))
+		.find(a => !a.href.startsWith('https://www.google.'));
+	if (!img || !a) {
+		return null;
+	}
+	return {
+		link: a.href,
+		title: (a.innerText || img.alt || '').trim(),
+		thumbnail: img.src || img.getAttribute('data-src') || '',
+	};
+}).filter(r => r)`
+
+func parseRichBlocks(page *rod.Page, output map[string]any) error {
+	res, err := page.Eval(richBlocksJS)
+	if err != nil {
+		return fmt.Errorf("failed to parse rich results: %w", err)
+	}
+	for key, value := range res.Value.Map() {
+		output[key] = value.Val()
+	}
+	return nil
+}
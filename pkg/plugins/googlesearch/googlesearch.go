@@ -2,12 +2,13 @@ package googlesearch
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"net/url"
-	"strings"
 	"time"
 
+	"github.com/bazuker/browserbro/pkg/fs"
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/plugins/botwall"
+	"github.com/bazuker/browserbro/pkg/plugins/serp"
 	"github.com/go-rod/rod"
 	"github.com/go-rod/stealth"
 )
@@ -18,13 +19,15 @@ const (
 
 type GoogleSearch struct {
 	browser          *rod.Browser
+	fileStore        fs.FileStore
 	maxTimePerSearch time.Duration
 }
 
-func New(browser *rod.Browser) *GoogleSearch {
+func New(browser *rod.Browser, fileStore fs.FileStore, cfg plugins.Config) *GoogleSearch {
 	return &GoogleSearch{
 		browser:          browser,
-		maxTimePerSearch: 15 * time.Second,
+		fileStore:        fileStore,
+		maxTimePerSearch: cfg.TimeoutOr(15 * time.Second),
 	}
 }
 
@@ -32,38 +35,33 @@ func (p *GoogleSearch) Name() string {
 	return pluginName
 }
 
-func (p *GoogleSearch) Run(params map[string]any) (output map[string]any, err error) {
-	query, ok := params["query"]
-	if !ok {
-		return nil, errors.New("missing 'query' parameter")
+// Cost is the number of results pages to read.
+func (p *GoogleSearch) Cost(params map[string]any) int {
+	query, err := serp.ParseQuery(params)
+	if err != nil {
+		return 1
 	}
-	queryString, ok := query.(string)
-	if !ok {
-		return nil, errors.New("'query' parameter must be a string")
+	searchTypes, err := parseSearchTypes(params)
+	if err != nil {
+		return query.Pages
 	}
+	return query.Pages * len(searchTypes)
+}
 
-	searchTypesMap := make(map[string]bool)
-	searchType, ok := params["type"]
-	if ok {
-		types, ok := searchType.([]any)
-		if !ok {
-			return nil, errors.New("'type' parameter must be an array of strings")
-		}
-		for _, t := range types {
-			typeString, ok := t.(string)
-			if !ok {
-				return nil, errors.New("'type' parameter must only contain strings")
-			}
-			loweredType := strings.ToLower(typeString)
-			switch loweredType {
-			case "all", "videos":
-				searchTypesMap[loweredType] = true
-			default:
-				return nil, errors.New("invalid search type: " + typeString)
-			}
-		}
-	} else {
-		searchTypesMap["all"] = true
+func (p *GoogleSearch) Run(ctx context.Context, params map[string]any) (output map[string]any, err error) {
+	opts, err := parseSearchOptions(params)
+	if err != nil {
+		return nil, err
+	}
+
+	searchTypes, err := parseSearchTypes(params)
+	if err != nil {
+		return nil, err
+	}
+
+	screenshotOnBlock, err := botwall.ParseScreenshotParam(params)
+	if err != nil {
+		return nil, err
 	}
 
 	var stealthPage *rod.Page
@@ -72,77 +70,106 @@ func (p *GoogleSearch) Run(params map[string]any) (output map[string]any, err er
 		return nil, fmt.Errorf("failed to create stealth page: %w", err)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancelCause(ctx)
 	page := stealthPage.Context(ctx)
 
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("failed to complete: %v", r)
 		}
+		err = plugins.TimeLimitError(ctx, err)
 		_ = page.Close()
 	}()
 
 	go func() {
-		time.Sleep(p.maxTimePerSearch)
-		cancel()
+		time.Sleep(p.maxTimePerSearch * time.Duration(opts.pages*len(searchTypes)))
+		cancel(plugins.ErrTimeLimit)
 	}()
 
 	output = make(map[string]any)
-	urlParams := url.Values{}
-	urlParams.Set("q", queryString)
-
-	if searchTypesMap["all"] || searchTypesMap["videos"] {
-		searchKey := "all"
-		if searchTypesMap["videos"] {
-			urlParams.Set("tbm", "vid")
-			searchKey = "videos"
+	// Every search type is a separate navigation as the types are different Google tabs.
+	for _, searchType := range searchTypes {
+		searchResults, err := p.search(page, searchType, opts, output)
+		if err != nil {
+			if screenshotOnBlock {
+				err = botwall.Capture(page, p.fileStore, err)
+			}
+			return nil, err
 		}
-		err = page.Navigate("https://www.google.com/search?" + urlParams.Encode())
+		output[searchType] = searchResults
+	}
+
+	return output, nil
+}
+
+// search reads the results of the search type from the requested number of
+// pages. The rich result blocks of the first "all" results page are written
+// to the output.
+func (p *GoogleSearch) search(
+	page *rod.Page,
+	searchType string,
+	opts searchOptions,
+	output map[string]any,
+) ([]serp.Result, error) {
+	paginator := serp.Paginator{
+		Parse:          parseResults,
+		Next:           serp.NextLink("a#pnnext"),
+		DismissConsent: opts.dismissConsent,
+	}
+	switch searchType {
+	case "all":
+		paginator.OnPage = func(page *rod.Page, index int) error {
+			if index > 0 {
+				return nil
+			}
+			return parseRichBlocks(page, output)
+		}
+	case "news":
+		paginator.Parse = serp.ParseScript(newsResultsJS)
+	case "images":
+		paginator.Parse = serp.ParseScript(imageResultsJS)
+	}
+
+	return paginator.Collect(page, opts.searchURL(searchType), opts.pages)
+}
+
+// parseResults reads the organic results of the current page.
+func parseResults(page *rod.Page) ([]serp.Result, error) {
+	blocks, err := page.Elements(".g")
+	if err != nil {
+		return nil, err
+	}
+	searchResults := make([]serp.Result, 0, len(blocks))
+	for _, block := range blocks {
+		href, err := block.Element("a")
 		if err != nil {
-			return nil, fmt.Errorf("failed to navigate to 'all' page: %w", err)
+			continue
+		}
+		link, err := href.Attribute("href")
+		if err != nil || link == nil {
+			continue
 		}
-		err = page.WaitLoad()
+		h3, err := block.Element("h3")
 		if err != nil {
-			return nil, fmt.Errorf("failed to wait for page to load: %w", err)
+			continue
 		}
-		blocks, err := page.Elements(".g")
+		title, err := h3.Text()
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse search results: %w", err)
+			continue
 		}
-		searchResults := make([]map[string]any, 0, len(blocks))
-		for _, block := range blocks {
-			href, err := block.Element("a")
-			if err != nil {
-				continue
-			}
-			link, err := href.Attribute("href")
-			if err != nil {
-				continue
-			}
-			h3, err := block.Element("h3")
-			if err != nil {
-				continue
-			}
-			title, err := h3.Text()
-			if err != nil {
-				continue
-			}
-			spans, err := block.Elements("span")
-			if err != nil {
-				continue
-			}
-			description, err := spans[len(spans)-1].Text()
-			if err != nil {
-				description = ""
-			}
-			searchResults = append(searchResults, map[string]any{
-				"link":        link,
-				"title":       title,
-				"description": description,
-			})
+		spans, err := block.Elements("span")
+		if err != nil || len(spans) == 0 {
+			continue
+		}
+		description, err := spans[len(spans)-1].Text()
+		if err != nil {
+			description = ""
 		}
-		output[searchKey] = searchResults
+		searchResults = append(searchResults, serp.Result{
+			Link:        *link,
+			Title:       title,
+			Description: description,
+		})
 	}
-
-	return output, nil
+	return searchResults, nil
 }
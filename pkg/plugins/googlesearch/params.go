@@ -0,0 +1,132 @@
+package googlesearch
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/plugins/consent"
+	"github.com/bazuker/browserbro/pkg/plugins/serp"
+)
+
+// searchVerticals maps the supported search types to the Google "tbm" parameter.
+var searchVerticals = map[string]string{
+	"all":    "",
+	"videos": "vid",
+	"news":   "nws",
+	"images": "isch",
+}
+
+var timeRanges = map[string]string{
+	"hour":  "qdr:h",
+	"day":   "qdr:d",
+	"week":  "qdr:w",
+	"month": "qdr:m",
+	"year":  "qdr:y",
+}
+
+// searchOptions are the search parameters shared by all search types.
+type searchOptions struct {
+	pages          int
+	values         url.Values
+	dismissConsent bool
+}
+
+func parseSearchOptions(params map[string]any) (searchOptions, error) {
+	opts := searchOptions{
+		values: url.Values{},
+	}
+
+	query, err := serp.ParseQuery(params)
+	if err != nil {
+		return opts, err
+	}
+	opts.pages = query.Pages
+	opts.values.Set("q", query.Text)
+	if query.Num > 0 {
+		opts.values.Set("num", fmt.Sprint(query.Num))
+	}
+	if query.Language != "" {
+		opts.values.Set("hl", query.Language)
+	}
+	if query.Country != "" {
+		opts.values.Set("gl", query.Country)
+	}
+	if value, ok := params["lr"]; ok {
+		lr, ok := value.(string)
+		if !ok || lr == "" {
+			return opts, plugins.ParamErrorf("'lr' parameter must be a non-empty string")
+		}
+		opts.values.Set("lr", lr)
+	}
+	if query.Safe != nil {
+		if *query.Safe {
+			opts.values.Set("safe", "active")
+		} else {
+			opts.values.Set("safe", "off")
+		}
+	}
+	if query.TimeRange != "" {
+		opts.values.Set("tbs", timeRanges[query.TimeRange])
+	}
+	if value, ok := params["tbs"]; ok {
+		tbs, ok := value.(string)
+		if !ok {
+			return opts, plugins.ParamErrorf("'tbs' parameter must be a string")
+		}
+		if opts.values.Has("tbs") {
+			return opts, plugins.ParamErrorf("'tbs' and 'timeRange' parameters cannot be used together")
+		}
+		opts.values.Set("tbs", tbs)
+	}
+	if opts.dismissConsent, err = consent.ParseParam(params); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+// parseSearchTypes returns the requested search types in the request order without duplicates.
+func parseSearchTypes(params map[string]any) ([]string, error) {
+	searchType, ok := params["type"]
+	if !ok {
+		return []string{"all"}, nil
+	}
+	types, ok := searchType.([]any)
+	if !ok {
+		return nil, plugins.ParamErrorf("'type' parameter must be an array of strings")
+	}
+	searchTypes := make([]string, 0, len(types))
+	seen := make(map[string]bool)
+	for _, t := range types {
+		typeString, ok := t.(string)
+		if !ok {
+			return nil, plugins.ParamErrorf("'type' parameter must only contain strings")
+		}
+		loweredType := strings.ToLower(typeString)
+		if _, ok := searchVerticals[loweredType]; !ok {
+			return nil, plugins.ParamErrorf("invalid search type '%s'", typeString)
+		}
+		if !seen[loweredType] {
+			seen[loweredType] = true
+			searchTypes = append(searchTypes, loweredType)
+		}
+	}
+	if len(searchTypes) == 0 {
+		return nil, plugins.ParamErrorf("empty 'type' parameter")
+	}
+	return searchTypes, nil
+}
+
+// searchURL returns the first results page URL of the search type.
+func (opts searchOptions) searchURL(searchType string) string {
+	values := url.Values{}
+	for k, v := range opts.values {
+		values[k] = v
+	}
+	if tbm := searchVerticals[searchType]; tbm != "" {
+		values.Set("tbm", tbm)
+	}
+	return "https://www.google.com/search?" + values.Encode()
+}
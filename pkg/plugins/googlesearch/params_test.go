@@ -0,0 +1,120 @@
+package googlesearch
+
+import (
+	"testing"
+
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseSearchOptions(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		opts, err := parseSearchOptions(map[string]any{"query": "golang"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, opts.pages)
+		assert.Equal(t, "q=golang", opts.values.Encode())
+	})
+
+	t.Run("all options", func(t *testing.T) {
+		opts, err := parseSearchOptions(map[string]any{
+			"query":     "golang",
+			"pages":     3.0,
+			"num":       50.0,
+			"hl":        "de",
+			"gl":        "at",
+			"lr":        "lang_de",
+			"safe":      true,
+			"timeRange": "week",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, opts.pages)
+		assert.Equal(
+			t,
+			"gl=at&hl=de&lr=lang_de&num=50&q=golang&safe=active&tbs=qdr%3Aw",
+			opts.values.Encode(),
+		)
+
+		opts, err = parseSearchOptions(map[string]any{
+			"query": "golang",
+			"safe":  false,
+			"tbs":   "cdr:1,cd_min:1/1/2024",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "off", opts.values.Get("safe"))
+		assert.Equal(t, "cdr:1,cd_min:1/1/2024", opts.values.Get("tbs"))
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			params map[string]any
+			err    string
+		}{
+			{"missing query", map[string]any{}, "missing 'query' parameter"},
+			{"invalid query", map[string]any{"query": 1.0}, "'query' parameter must be a string"},
+			{"too many pages", map[string]any{"query": "go", "pages": 11.0}, "'pages' parameter must be an integer between 1 and 10"},
+			{"fractional num", map[string]any{"query": "go", "num": 1.5}, "'num' parameter must be an integer between 1 and 100"},
+			{"empty hl", map[string]any{"query": "go", "hl": ""}, "'hl' parameter must be a non-empty string"},
+			{"invalid safe", map[string]any{"query": "go", "safe": "on"}, "'safe' parameter must be a boolean"},
+			{"invalid time range", map[string]any{"query": "go", "timeRange": "decade"}, "invalid time range 'decade'"},
+			{
+				"tbs and time range",
+				map[string]any{"query": "go", "timeRange": "day", "tbs": "qdr:h"},
+				"'tbs' and 'timeRange' parameters cannot be used together",
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := parseSearchOptions(tt.params)
+				assert.EqualError(t, err, tt.err)
+			})
+		}
+	})
+}
+
+func Test_parseSearchTypes(t *testing.T) {
+	searchTypes, err := parseSearchTypes(map[string]any{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"all"}, searchTypes)
+
+	searchTypes, err = parseSearchTypes(map[string]any{
+		"type": []any{"Videos", "all", "news", "videos", "images"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"videos", "all", "news", "images"}, searchTypes)
+
+	_, err = parseSearchTypes(map[string]any{"type": "all"})
+	assert.EqualError(t, err, "'type' parameter must be an array of strings")
+	_, err = parseSearchTypes(map[string]any{"type": []any{1.0}})
+	assert.EqualError(t, err, "'type' parameter must only contain strings")
+	_, err = parseSearchTypes(map[string]any{"type": []any{"maps"}})
+	assert.EqualError(t, err, "invalid search type 'maps'")
+	_, err = parseSearchTypes(map[string]any{"type": []any{"100%s"}})
+	assert.EqualError(t, err, "invalid search type '100%s'")
+	_, err = parseSearchTypes(map[string]any{"type": []any{}})
+	assert.EqualError(t, err, "empty 'type' parameter")
+}
+
+func TestSearchOptions_searchURL(t *testing.T) {
+	opts, err := parseSearchOptions(map[string]any{"query": "golang", "hl": "en"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://www.google.com/search?hl=en&q=golang", opts.searchURL("all"))
+	assert.Equal(t, "https://www.google.com/search?hl=en&q=golang&tbm=vid", opts.searchURL("videos"))
+	assert.Equal(t, "https://www.google.com/search?hl=en&q=golang&tbm=nws", opts.searchURL("news"))
+	assert.Equal(t, "https://www.google.com/search?hl=en&q=golang&tbm=isch", opts.searchURL("images"))
+	// Building a vertical URL must not leak into the other search types.
+	assert.False(t, opts.values.Has("tbm"))
+}
+
+func TestGoogleSearch_Cost(t *testing.T) {
+	p := New(nil, nil, plugins.Config{})
+	assert.Equal(t, 1, p.Cost(map[string]any{"query": "golang"}))
+	assert.Equal(t, 6, p.Cost(map[string]any{
+		"query": "golang",
+		"pages": float64(3),
+		"type":  []any{"all", "news"},
+	}))
+	assert.Equal(t, 1, p.Cost(map[string]any{}))
+}
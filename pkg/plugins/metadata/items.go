@@ -0,0 +1,162 @@
+package metadata
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxItemDepth limits the nesting of microdata and RDFa items, which can be
+// cyclic through itemref.
+const maxItemDepth = 10
+
+// microdataItems returns the top-level microdata items of the document.
+func microdataItems(doc *goquery.Document, base *url.URL) []map[string]any {
+	items := make([]map[string]any, 0)
+	doc.Find("[itemscope]").Each(func(_ int, s *goquery.Selection) {
+		if _, ok := s.Attr("itemprop"); ok {
+			return
+		}
+		items = append(items, microdataItem(doc, s, base, 0))
+	})
+	return items
+}
+
+func microdataItem(doc *goquery.Document, s *goquery.Selection, base *url.URL, depth int) map[string]any {
+	item := map[string]any{
+		"type": strings.Fields(s.AttrOr("itemtype", "")),
+	}
+	if id, ok := s.Attr("itemid"); ok {
+		item["id"] = absoluteURL(base, id)
+	}
+	properties := make(map[string][]any)
+	item["properties"] = properties
+	if depth >= maxItemDepth {
+		return item
+	}
+
+	// visit adds the element to the properties if it has any and descends
+	// into it unless it is a nested item.
+	var visit func(_ int, el *goquery.Selection)
+	visit = func(_ int, el *goquery.Selection) {
+		_, isItem := el.Attr("itemscope")
+		if names, ok := el.Attr("itemprop"); ok {
+			var value any
+			if isItem {
+				value = microdataItem(doc, el, base, depth+1)
+			} else {
+				value = microdataValue(el, base)
+			}
+			for _, name := range strings.Fields(names) {
+				properties[name] = append(properties[name], value)
+			}
+		}
+		if !isItem {
+			el.Children().Each(visit)
+		}
+	}
+	s.Children().Each(visit)
+	// Properties can also be declared outside of the item and referenced by id.
+	for _, id := range strings.Fields(s.AttrOr("itemref", "")) {
+		elementByID(doc, id).Each(visit)
+	}
+	return item
+}
+
+func microdataValue(s *goquery.Selection, base *url.URL) any {
+	switch goquery.NodeName(s) {
+	case "meta":
+		return s.AttrOr("content", "")
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		return absoluteURL(base, s.AttrOr("src", ""))
+	case "a", "area", "link":
+		return absoluteURL(base, s.AttrOr("href", ""))
+	case "object":
+		return absoluteURL(base, s.AttrOr("data", ""))
+	case "data", "meter":
+		return s.AttrOr("value", "")
+	case "time":
+		if datetime, ok := s.Attr("datetime"); ok {
+			return datetime
+		}
+	}
+	return normalizeSpace(s.Text())
+}
+
+// rdfaItems returns the RDFa items of the document that are not property
+// values of other items.
+func rdfaItems(doc *goquery.Document, base *url.URL) []map[string]any {
+	items := make([]map[string]any, 0)
+	doc.Find("[typeof]").Each(func(_ int, s *goquery.Selection) {
+		if _, ok := s.Attr("property"); ok {
+			return
+		}
+		items = append(items, rdfaItem(s, base, 0))
+	})
+	return items
+}
+
+func rdfaItem(s *goquery.Selection, base *url.URL, depth int) map[string]any {
+	item := map[string]any{
+		"type": strings.Fields(s.AttrOr("typeof", "")),
+	}
+	if vocab, ok := s.Closest("[vocab]").Attr("vocab"); ok {
+		item["vocab"] = vocab
+	}
+	for _, attr := range []string{"about", "resource"} {
+		if id, ok := s.Attr(attr); ok {
+			item["id"] = absoluteURL(base, id)
+			break
+		}
+	}
+	properties := make(map[string][]any)
+	item["properties"] = properties
+	if depth >= maxItemDepth {
+		return item
+	}
+
+	var visit func(_ int, el *goquery.Selection)
+	visit = func(_ int, el *goquery.Selection) {
+		_, isItem := el.Attr("typeof")
+		if names, ok := el.Attr("property"); ok {
+			var value any
+			if isItem {
+				value = rdfaItem(el, base, depth+1)
+			} else {
+				value = rdfaValue(el, base)
+			}
+			for _, name := range strings.Fields(names) {
+				properties[name] = append(properties[name], value)
+			}
+		}
+		if !isItem {
+			el.Children().Each(visit)
+		}
+	}
+	s.Children().Each(visit)
+	return item
+}
+
+func rdfaValue(s *goquery.Selection, base *url.URL) any {
+	if content, ok := s.Attr("content"); ok {
+		return content
+	}
+	for _, attr := range []string{"resource", "href", "src"} {
+		if link, ok := s.Attr(attr); ok {
+			return absoluteURL(base, link)
+		}
+	}
+	if datetime, ok := s.Attr("datetime"); ok {
+		return datetime
+	}
+	return normalizeSpace(s.Text())
+}
+
+// elementByID finds the element by its id without building a CSS selector,
+// which would need escaping.
+func elementByID(doc *goquery.Document, id string) *goquery.Selection {
+	return doc.Find("[id]").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return s.AttrOr("id", "") == id
+	}).First()
+}
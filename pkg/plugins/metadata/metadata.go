@@ -0,0 +1,99 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/plugins/consent"
+	"github.com/bazuker/browserbro/pkg/tracing"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/stealth"
+)
+
+const (
+	pluginName = "metadata"
+)
+
+type Metadata struct {
+	browser        *rod.Browser
+	maxTimePerPage time.Duration
+}
+
+func New(browser *rod.Browser, cfg plugins.Config) *Metadata {
+	return &Metadata{
+		browser:        browser,
+		maxTimePerPage: cfg.TimeoutOr(15 * time.Second),
+	}
+}
+
+func (p *Metadata) Name() string {
+	return pluginName
+}
+
+func (p *Metadata) Run(ctx context.Context, params map[string]any) (output map[string]any, err error) {
+	urlString, ok := params["url"].(string)
+	if !ok || urlString == "" {
+		return nil, plugins.ParamErrorf("'url' parameter must be a non-empty string")
+	}
+	waitStable, ok := params["waitStable"].(bool)
+	if !ok {
+		waitStable = true
+	}
+	dismissConsent, err := consent.ParseParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var stealthPage *rod.Page
+	stealthPage, err = stealth.Page(p.browser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stealth page: %w", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	page := stealthPage.Context(ctx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to complete: %v", r)
+		}
+		err = plugins.TimeLimitError(ctx, err)
+		_ = page.Close()
+	}()
+
+	go func() {
+		time.Sleep(p.maxTimePerPage)
+		cancel(plugins.ErrTimeLimit)
+	}()
+
+	err = tracing.Navigate(page, urlString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to navigate to the page '%s': %w", urlString, err)
+	}
+	err = tracing.WaitLoad(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for page to load: %w", err)
+	}
+	if dismissConsent {
+		consent.Dismiss(page)
+	}
+	if waitStable {
+		err = tracing.WaitStable(page, time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("failed to wait for page to stabilize: %w", err)
+		}
+	}
+
+	content, err := page.HTML()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page content: %w", err)
+	}
+	// Resolve relative links against the final URL after redirects.
+	if info, err := page.Info(); err == nil {
+		urlString = info.URL
+	}
+
+	return parseMetadata(content, urlString)
+}
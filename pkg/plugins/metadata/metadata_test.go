@@ -0,0 +1,121 @@
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPage = `
+<html lang="en">
+<head>
+  <title>  Gopher plush toy | Go Store </title>
+  <meta name="Description" content="A soft gopher.">
+  <link rel="canonical" href="/products/gopher">
+  <meta property="og:title" content="Gopher plush toy">
+  <meta property="og:image" content="https://store.go.dev/gopher-1.png">
+  <meta property="og:image" content="https://store.go.dev/gopher-2.png">
+  <meta name="twitter:card" content="summary_large_image">
+  <meta name="twitter:site" content="@golang">
+  <link rel="icon" href="/favicon.ico">
+  <link rel="apple-touch-icon" sizes="180x180" href="/apple-touch-icon.png">
+  <link rel="alternate" hreflang="de" href="https://store.go.dev/de/products/gopher">
+  <link rel="alternate" type="application/rss+xml" title="Go Store news" href="/feed.xml">
+  <script type="application/ld+json">{"@context": "https://schema.org", "@type": "Product", "name": "Gopher"}</script>
+  <script type="application/ld+json">{invalid</script>
+</head>
+<body>
+  <div itemscope itemtype="https://schema.org/Product" itemref="price">
+    <h1 itemprop="name">Gopher plush toy</h1>
+    <img itemprop="image" src="gopher.png">
+    <div itemprop="brand" itemscope itemtype="https://schema.org/Brand">
+      <span itemprop="name">Go</span>
+    </div>
+  </div>
+  <p id="price">Price: <data itemprop="price" value="9.99">$9.99</data></p>
+  <div vocab="https://schema.org/" typeof="Person" resource="#rob">
+    <span property="name">Rob Pike</span>
+    <a property="url" href="https://github.com/robpike">GitHub</a>
+    <div property="worksFor" typeof="Organization"><span property="name">Google</span></div>
+  </div>
+</body>
+</html>`
+
+func TestParseMetadata(t *testing.T) {
+	output, err := parseMetadata(testPage, "https://store.go.dev/products/gopher?ref=home")
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://store.go.dev/products/gopher?ref=home", output["url"])
+	assert.Equal(t, "Gopher plush toy | Go Store", output["title"])
+	assert.Equal(t, "A soft gopher.", output["description"])
+	assert.Equal(t, "en", output["language"])
+	assert.Equal(t, "https://store.go.dev/products/gopher", output["canonical"])
+	assert.Equal(t, map[string]any{
+		"title": "Gopher plush toy",
+		"image": []string{"https://store.go.dev/gopher-1.png", "https://store.go.dev/gopher-2.png"},
+	}, output["openGraph"])
+	assert.Equal(t, map[string]any{
+		"card": "summary_large_image",
+		"site": "@golang",
+	}, output["twitter"])
+	assert.Equal(t, []any{
+		map[string]any{"@context": "https://schema.org", "@type": "Product", "name": "Gopher"},
+	}, output["jsonLd"])
+	assert.Equal(t, []map[string]string{
+		{"href": "https://store.go.dev/favicon.ico", "rel": "icon"},
+		{"href": "https://store.go.dev/apple-touch-icon.png", "rel": "apple-touch-icon", "sizes": "180x180"},
+	}, output["icons"])
+	assert.Equal(t, []map[string]string{
+		{"hreflang": "de", "href": "https://store.go.dev/de/products/gopher"},
+	}, output["alternates"])
+	assert.Equal(t, []map[string]string{
+		{"href": "https://store.go.dev/feed.xml", "type": "application/rss+xml", "title": "Go Store news"},
+	}, output["feeds"])
+
+	assert.Equal(t, []map[string]any{
+		{
+			"type": []string{"https://schema.org/Product"},
+			"properties": map[string][]any{
+				"name":  {"Gopher plush toy"},
+				"image": {"https://store.go.dev/products/gopher.png"},
+				"brand": {map[string]any{
+					"type":       []string{"https://schema.org/Brand"},
+					"properties": map[string][]any{"name": {"Go"}},
+				}},
+				"price": {"9.99"},
+			},
+		},
+	}, output["microdata"])
+
+	assert.Equal(t, []map[string]any{
+		{
+			"type":  []string{"Person"},
+			"vocab": "https://schema.org/",
+			"id":    "https://store.go.dev/products/gopher?ref=home#rob",
+			"properties": map[string][]any{
+				"name": {"Rob Pike"},
+				"url":  {"https://github.com/robpike"},
+				"worksFor": {map[string]any{
+					"type":       []string{"Organization"},
+					"vocab":      "https://schema.org/",
+					"properties": map[string][]any{"name": {"Google"}},
+				}},
+			},
+		},
+	}, output["rdfa"])
+}
+
+func TestParseMetadata_empty(t *testing.T) {
+	output, err := parseMetadata("<html></html>", "https://go.dev/")
+	require.NoError(t, err)
+	assert.Equal(t, "", output["title"])
+	assert.Equal(t, "", output["canonical"])
+	assert.Empty(t, output["openGraph"])
+	assert.Empty(t, output["jsonLd"])
+	assert.Empty(t, output["microdata"])
+	assert.Empty(t, output["icons"])
+
+	_, err = parseMetadata("<html></html>", "://invalid")
+	assert.ErrorContains(t, err, "invalid page URL")
+}
@@ -0,0 +1,179 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// feedTypes are the MIME types of the feed links.
+var feedTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/json":      true,
+}
+
+// parseMetadata reads the metadata of the HTML document. Relative URLs are
+// resolved against the page URL.
+func parseMetadata(content, pageURL string) (map[string]any, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse page content: %w", err)
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page URL: %w", err)
+	}
+	// The base element changes the URL relative links are resolved against.
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if baseHref, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			base = baseHref
+		}
+	}
+
+	output := map[string]any{
+		"url":         pageURL,
+		"title":       normalizeSpace(doc.Find("title").First().Text()),
+		"description": metaContent(doc, "description"),
+		"language":    strings.TrimSpace(doc.Find("html").AttrOr("lang", "")),
+		"canonical":   "",
+		"openGraph":   metaTags(doc, "og:"),
+		"twitter":     metaTags(doc, "twitter:"),
+		"jsonLd":      jsonLD(doc),
+		"microdata":   microdataItems(doc, base),
+		"rdfa":        rdfaItems(doc, base),
+		"icons":       icons(doc, base),
+		"alternates":  alternates(doc, base),
+		"feeds":       feeds(doc, base),
+	}
+	if href, ok := doc.Find(`link[rel~="canonical"][href]`).First().Attr("href"); ok {
+		output["canonical"] = absoluteURL(base, href)
+	}
+	return output, nil
+}
+
+// metaContent returns the content of the first meta element with the name.
+func metaContent(doc *goquery.Document, name string) string {
+	var content string
+	doc.Find("meta[name]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if strings.EqualFold(s.AttrOr("name", ""), name) {
+			content = strings.TrimSpace(s.AttrOr("content", ""))
+			return false
+		}
+		return true
+	})
+	return content
+}
+
+// metaTags collects the meta elements whose property or name starts with the
+// prefix. The keys are the names without the prefix. Repeated tags, e.g.
+// several "og:image" tags, are collected into arrays.
+func metaTags(doc *goquery.Document, prefix string) map[string]any {
+	tags := make(map[string]any)
+	doc.Find("meta[property], meta[name]").Each(func(_ int, s *goquery.Selection) {
+		key := s.AttrOr("property", "")
+		if !strings.HasPrefix(strings.ToLower(key), prefix) {
+			key = s.AttrOr("name", "")
+		}
+		if !strings.HasPrefix(strings.ToLower(key), prefix) {
+			return
+		}
+		key = strings.ToLower(key[len(prefix):])
+		content, ok := s.Attr("content")
+		if !ok || key == "" {
+			return
+		}
+		content = strings.TrimSpace(content)
+		switch existing := tags[key].(type) {
+		case nil:
+			tags[key] = content
+		case string:
+			tags[key] = []string{existing, content}
+		case []string:
+			tags[key] = append(existing, content)
+		}
+	})
+	return tags
+}
+
+// jsonLD parses the JSON-LD script blocks. Blocks that are not valid JSON are skipped.
+func jsonLD(doc *goquery.Document) []any {
+	blocks := make([]any, 0)
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, s *goquery.Selection) {
+		var block any
+		if err := json.Unmarshal([]byte(strings.TrimSpace(s.Text())), &block); err != nil {
+			return
+		}
+		blocks = append(blocks, block)
+	})
+	return blocks
+}
+
+func icons(doc *goquery.Document, base *url.URL) []map[string]string {
+	result := make([]map[string]string, 0)
+	doc.Find("link[rel][href]").Each(func(_ int, s *goquery.Selection) {
+		rel := strings.ToLower(s.AttrOr("rel", ""))
+		if !strings.Contains(rel, "icon") {
+			return
+		}
+		icon := map[string]string{
+			"href": absoluteURL(base, s.AttrOr("href", "")),
+			"rel":  normalizeSpace(rel),
+		}
+		if sizes := s.AttrOr("sizes", ""); sizes != "" {
+			icon["sizes"] = sizes
+		}
+		if mimeType := s.AttrOr("type", ""); mimeType != "" {
+			icon["type"] = mimeType
+		}
+		result = append(result, icon)
+	})
+	return result
+}
+
+// alternates returns the language versions of the page.
+func alternates(doc *goquery.Document, base *url.URL) []map[string]string {
+	result := make([]map[string]string, 0)
+	doc.Find(`link[rel~="alternate"][hreflang][href]`).Each(func(_ int, s *goquery.Selection) {
+		result = append(result, map[string]string{
+			"hreflang": s.AttrOr("hreflang", ""),
+			"href":     absoluteURL(base, s.AttrOr("href", "")),
+		})
+	})
+	return result
+}
+
+func feeds(doc *goquery.Document, base *url.URL) []map[string]string {
+	result := make([]map[string]string, 0)
+	doc.Find(`link[rel~="alternate"][type][href]`).Each(func(_ int, s *goquery.Selection) {
+		mimeType := strings.ToLower(strings.TrimSpace(s.AttrOr("type", "")))
+		if !feedTypes[mimeType] {
+			return
+		}
+		feed := map[string]string{
+			"href": absoluteURL(base, s.AttrOr("href", "")),
+			"type": mimeType,
+		}
+		if title := normalizeSpace(s.AttrOr("title", "")); title != "" {
+			feed["title"] = title
+		}
+		result = append(result, feed)
+	})
+	return result
+}
+
+func absoluteURL(base *url.URL, link string) string {
+	u, err := base.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return link
+	}
+	return u.String()
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
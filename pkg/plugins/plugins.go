@@ -1,6 +1,129 @@
 package plugins
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
 type Plugin interface {
 	Name() string
-	Run(Params map[string]any) (map[string]any, error)
+	// Run runs the plugin. The pages opened by the plugin should be bound to
+	// the context, so that they are closed when it is canceled.
+	Run(ctx context.Context, params map[string]any) (map[string]any, error)
+}
+
+// Coster is an optional interface of the plugins whose runs use more browser
+// capacity than a single page, e.g. a screenshot of N pages costs N.
+// The cost is charged against the rate limit of the client.
+type Coster interface {
+	Cost(params map[string]any) int
+}
+
+// Cost returns the cost of running the plugin with the params, at least 1.
+func Cost(plugin Plugin, params map[string]any) int {
+	coster, ok := plugin.(Coster)
+	if !ok {
+		return 1
+	}
+	return max(coster.Cost(params), 1)
+}
+
+// RunFunc runs the named plugin with the params.
+type RunFunc func(ctx context.Context, plugin string, params map[string]any) (map[string]any, error)
+
+// Nester is an optional interface of the plugins that run other plugins, e.g.
+// the crawler runs a plugin on every crawled page. The manager checks that the
+// client may run the nested plugins and runs them like any other plugin run.
+type Nester interface {
+	// NestedPlugins returns the names of the plugins run by a run with the params.
+	NestedPlugins(params map[string]any) []string
+	// SetRunFunc sets the function the nested plugins are run with.
+	SetRunFunc(run RunFunc)
+}
+
+// Nested returns the names of the plugins run by a run of the plugin with the params.
+func Nested(plugin Plugin, params map[string]any) []string {
+	nester, ok := plugin.(Nester)
+	if !ok {
+		return nil
+	}
+	return nester.NestedPlugins(params)
+}
+
+// Scoper is an optional interface of the plugins that use the data of the API
+// clients, e.g. the stored files. The manager checks that the client has the
+// scopes on top of the scope of the plugin.
+type Scoper interface {
+	// Scopes returns the scopes required by a run with the params.
+	Scopes(params map[string]any) []string
+}
+
+// Scopes returns the scopes required by a run of the plugin with the params,
+// besides the scope of the plugin.
+func Scopes(plugin Plugin, params map[string]any) []string {
+	scoper, ok := plugin.(Scoper)
+	if !ok {
+		return nil
+	}
+	return scoper.Scopes(params)
+}
+
+// ErrTimeLimit is the cause of the cancellation of the runs that exceed their
+// time limit. The plugins cancel their context with it, see TimeLimitError.
+var ErrTimeLimit = errors.New("plugin run exceeded its time limit")
+
+// TimeLimitError marks the error of the run as a timeout if the context was
+// canceled with ErrTimeLimit. The browser returns context.Canceled for the
+// canceled contexts, whatever the cause.
+func TimeLimitError(ctx context.Context, err error) error {
+	if err == nil || errors.Is(err, ErrTimeLimit) || !errors.Is(context.Cause(ctx), ErrTimeLimit) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrTimeLimit, err)
+}
+
+// Config is the configuration section of a plugin. The zero value keeps the
+// defaults of the plugin.
+type Config struct {
+	// Disabled excludes the plugin from the server.
+	Disabled bool `yaml:"disabled"`
+	// Timeout is the maximum time of a run per page, e.g. per screenshot or
+	// per search results page.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func (c Config) Validate() error {
+	if c.Timeout < 0 {
+		return errors.New("'timeout' must not be negative")
+	}
+	return nil
+}
+
+// TimeoutOr returns the configured timeout or the default one if it is not set.
+func (c Config) TimeoutOr(defaultTimeout time.Duration) time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return defaultTimeout
+}
+
+// ParamError is returned by the plugins when the params of a run are invalid.
+// The manager responds to it with the 400 Bad Request status.
+type ParamError struct {
+	Err error
+}
+
+func (e *ParamError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// ParamErrorf formats the error like fmt.Errorf and wraps it in a *ParamError.
+func ParamErrorf(format string, args ...any) error {
+	return &ParamError{Err: fmt.Errorf(format, args...)}
 }
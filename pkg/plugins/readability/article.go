@@ -0,0 +1,290 @@
+package readability
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+var (
+	unlikelyCandidates = regexp.MustCompile(
+		`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|footer|header|menu|modal|` +
+			`nav|pagination|popup|promo|related|remark|rss|share|shoutbox|sidebar|social|sponsor|subscribe`,
+	)
+	maybeCandidates = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveClasses = regexp.MustCompile(
+		`(?i)article|blog|body|content|entry|hentry|h-entry|main|page|post|story|text`,
+	)
+	negativeClasses = regexp.MustCompile(
+		`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|contact|footer|footnote|` +
+			`masthead|media|meta|outbrain|promo|related|share|shoutbox|sidebar|skyscraper|sponsor|social|widget`,
+	)
+	titleSeparators = regexp.MustCompile(`\s+[|\-–—»:]\s+`)
+
+	errNoContent = errors.New("failed to find the main content of the page")
+)
+
+// article is the main content of a page along with its metadata.
+type article struct {
+	Title     string
+	Byline    string
+	Published string
+	Image     string
+	SiteName  string
+	Excerpt   string
+	Content   *html.Node
+}
+
+// parseArticle finds the main content of the document and collects its metadata.
+func parseArticle(doc *goquery.Document, pageURL *url.URL) (*article, error) {
+	a := &article{
+		Title:     findTitle(doc),
+		Byline:    findByline(doc),
+		Published: findPublished(doc),
+		Image:     findImage(doc),
+		SiteName:  metaContent(doc, `meta[property="og:site_name"]`),
+		Excerpt: firstNonEmpty(
+			metaContent(doc, `meta[property="og:description"]`),
+			metaContent(doc, `meta[name="description"]`),
+		),
+	}
+
+	removeUnlikely(doc)
+	candidate := findCandidate(doc)
+	if candidate == nil {
+		return nil, errNoContent
+	}
+	a.Content = sanitize(candidate, pageURL)
+	removeTitleHeading(a.Content, a.Title)
+	if a.Image != "" {
+		a.Image = absoluteURL(pageURL, a.Image)
+	}
+
+	return a, nil
+}
+
+func findTitle(doc *goquery.Document) string {
+	if title := firstNonEmpty(
+		metaContent(doc, `meta[property="og:title"]`),
+		metaContent(doc, `meta[name="twitter:title"]`),
+	); title != "" {
+		return title
+	}
+
+	title := normalizeSpace(doc.Find("title").First().Text())
+	// Strip the site name from titles like "Article | Site".
+	if loc := titleSeparators.FindAllStringIndex(title, -1); len(loc) > 0 {
+		if head := title[:loc[len(loc)-1][0]]; len(strings.Fields(head)) >= 3 {
+			title = head
+		}
+	}
+	if title == "" {
+		title = normalizeSpace(doc.Find("h1").First().Text())
+	}
+	return title
+}
+
+func findByline(doc *goquery.Document) string {
+	if byline := firstNonEmpty(
+		metaContent(doc, `meta[name="author"]`),
+		metaContent(doc, `meta[property="article:author"]`),
+	); byline != "" && !strings.HasPrefix(byline, "http") {
+		return byline
+	}
+	for _, selector := range []string{
+		`[rel="author"]`,
+		`[itemprop="author"]`,
+		`.byline`,
+		`.author`,
+	} {
+		if byline := normalizeSpace(doc.Find(selector).First().Text()); byline != "" {
+			return byline
+		}
+	}
+	return ""
+}
+
+func findPublished(doc *goquery.Document) string {
+	if published := firstNonEmpty(
+		metaContent(doc, `meta[property="article:published_time"]`),
+		metaContent(doc, `meta[itemprop="datePublished"]`),
+		metaContent(doc, `meta[name="date"]`),
+		metaContent(doc, `meta[name="pubdate"]`),
+		metaContent(doc, `meta[name="publish-date"]`),
+	); published != "" {
+		return published
+	}
+	if datetime, ok := doc.Find("time[datetime]").First().Attr("datetime"); ok {
+		return strings.TrimSpace(datetime)
+	}
+	return ""
+}
+
+func findImage(doc *goquery.Document) string {
+	if image := firstNonEmpty(
+		metaContent(doc, `meta[property="og:image"]`),
+		metaContent(doc, `meta[name="twitter:image"]`),
+	); image != "" {
+		return image
+	}
+	if src, ok := doc.Find("article img[src]").First().Attr("src"); ok {
+		return strings.TrimSpace(src)
+	}
+	return ""
+}
+
+// removeTitleHeading drops the first heading of the content when it repeats the title.
+func removeTitleHeading(content *html.Node, title string) {
+	heading := goquery.NewDocumentFromNode(content).Find("h1,h2").First()
+	if heading.Length() > 0 && normalizeSpace(heading.Text()) == title {
+		heading.Remove()
+	}
+}
+
+// removeUnlikely drops elements that are never part of the main content.
+func removeUnlikely(doc *goquery.Document) {
+	doc.Find(strings.Join(droppedTags, ",")).Remove()
+	doc.Find("nav,aside,footer,form,button").Remove()
+	doc.Find("*").Each(func(_ int, s *goquery.Selection) {
+		if s.Is("html,body,article,main") {
+			return
+		}
+		id, _ := s.Attr("id")
+		class, _ := s.Attr("class")
+		role, _ := s.Attr("role")
+		match := class + " " + id
+		if role == "navigation" || role == "complementary" || role == "dialog" ||
+			(unlikelyCandidates.MatchString(match) && !maybeCandidates.MatchString(match)) {
+			s.Remove()
+		}
+	})
+}
+
+// findCandidate scores the paragraphs of the document and returns the
+// element that most likely contains the main content.
+func findCandidate(doc *goquery.Document) *goquery.Selection {
+	scores := make(map[*html.Node]float64)
+	var candidates []*goquery.Selection
+	addScore := func(s *goquery.Selection, score float64) {
+		if s.Length() == 0 {
+			return
+		}
+		node := s.Get(0)
+		if _, ok := scores[node]; !ok {
+			scores[node] = initialScore(s)
+			candidates = append(candidates, s)
+		}
+		scores[node] += score
+	}
+
+	doc.Find("p,pre,td,blockquote,section > div").Each(func(_ int, s *goquery.Selection) {
+		text := normalizeSpace(s.Text())
+		if len(text) < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		addScore(s.Parent(), score)
+		addScore(s.Parent().Parent(), score/2)
+	})
+
+	var (
+		top      *goquery.Selection
+		topScore float64
+	)
+	for _, s := range candidates {
+		score := scores[s.Get(0)] * (1 - linkDensity(s))
+		if top == nil || score > topScore {
+			top, topScore = s, score
+		}
+	}
+	if top == nil {
+		if article := doc.Find("article,main,[role=main]").First(); article.Length() > 0 {
+			return article
+		}
+		return nil
+	}
+
+	// Prefer the enclosing article when the best candidate is a part of it.
+	if article := top.Closest("article"); article.Length() > 0 &&
+		len(normalizeSpace(article.Text())) < 2*len(normalizeSpace(top.Text())) {
+		return article
+	}
+	return top
+}
+
+func initialScore(s *goquery.Selection) float64 {
+	var score float64
+	switch goquery.NodeName(s) {
+	case "article":
+		score = 10
+	case "div", "main", "section":
+		score = 5
+	case "pre", "td", "blockquote":
+		score = 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score = -5
+	}
+
+	for _, attr := range []string{"class", "id"} {
+		value, _ := s.Attr(attr)
+		if value == "" {
+			continue
+		}
+		if negativeClasses.MatchString(value) {
+			score -= 25
+		}
+		if positiveClasses.MatchString(value) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// linkDensity is the share of the element text that is inside links.
+func linkDensity(s *goquery.Selection) float64 {
+	textLength := len(normalizeSpace(s.Text()))
+	if textLength == 0 {
+		return 0
+	}
+	var linkLength int
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linkLength += len(normalizeSpace(a.Text()))
+	})
+	return float64(linkLength) / float64(textLength)
+}
+
+func metaContent(doc *goquery.Document, selector string) string {
+	content, _ := doc.Find(selector).First().Attr("content")
+	return normalizeSpace(content)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func absoluteURL(base *url.URL, ref string) string {
+	if base == nil {
+		return ref
+	}
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,165 @@
+package readability
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/bazuker/browserbro/pkg/fs"
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/plugins/consent"
+	"github.com/bazuker/browserbro/pkg/tracing"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/stealth"
+)
+
+const (
+	pluginName = "readability"
+)
+
+type Readability struct {
+	browser        *rod.Browser
+	fileStore      fs.FileStore
+	maxTimePerPage time.Duration
+}
+
+func New(browser *rod.Browser, fileStore fs.FileStore, cfg plugins.Config) *Readability {
+	return &Readability{
+		browser:        browser,
+		fileStore:      fileStore,
+		maxTimePerPage: cfg.TimeoutOr(15 * time.Second),
+	}
+}
+
+func (p *Readability) Name() string {
+	return pluginName
+}
+
+func (p *Readability) Run(ctx context.Context, params map[string]any) (output map[string]any, err error) {
+	urlString, ok := params["url"].(string)
+	if !ok || urlString == "" {
+		return nil, plugins.ParamErrorf("'url' parameter must be a non-empty string")
+	}
+	var store bool
+	if value, ok := params["store"]; ok {
+		if store, ok = value.(bool); !ok {
+			return nil, plugins.ParamErrorf("'store' parameter must be a boolean")
+		}
+	}
+	waitStable, ok := params["waitStable"].(bool)
+	if !ok {
+		waitStable = true
+	}
+	dismissConsent, err := consent.ParseParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var stealthPage *rod.Page
+	stealthPage, err = stealth.Page(p.browser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stealth page: %w", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	page := stealthPage.Context(ctx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to complete: %v", r)
+		}
+		err = plugins.TimeLimitError(ctx, err)
+		_ = page.Close()
+	}()
+
+	go func() {
+		time.Sleep(p.maxTimePerPage)
+		cancel(plugins.ErrTimeLimit)
+	}()
+
+	err = tracing.Navigate(page, urlString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to navigate to the page '%s': %w", urlString, err)
+	}
+	err = tracing.WaitLoad(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for page to load: %w", err)
+	}
+	if dismissConsent {
+		consent.Dismiss(page)
+	}
+	if waitStable {
+		err = tracing.WaitStable(page, time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("failed to wait for page to stabilize: %w", err)
+		}
+	}
+
+	content, err := page.HTML()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page content: %w", err)
+	}
+	// Resolve relative links against the final URL after redirects.
+	if info, err := page.Info(); err == nil {
+		urlString = info.URL
+	}
+
+	output, err = readArticle(content, urlString)
+	if err != nil {
+		return nil, err
+	}
+
+	if store {
+		filename := helper.NewFileName("readability", "md")
+		markdown := output["markdown"].(string)
+		if err := tracing.PutObject(ctx, p.fileStore, []byte(markdown), filename); err != nil {
+			return nil, fmt.Errorf("failed to save markdown: %w", err)
+		}
+		output["file"] = filename
+	}
+
+	return output, nil
+}
+
+// readArticle extracts the main article of the HTML document.
+func readArticle(content, pageURL string) (map[string]any, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse page content: %w", err)
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page URL: %w", err)
+	}
+
+	a, err := parseArticle(doc, base)
+	if err != nil {
+		return nil, err
+	}
+
+	articleHTML, err := renderHTML(a.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render article: %w", err)
+	}
+	markdown := renderMarkdown(a.Content, base.Host)
+	if a.Title != "" {
+		markdown = "# " + a.Title + "\n\n" + markdown
+	}
+
+	return map[string]any{
+		"url":       pageURL,
+		"title":     a.Title,
+		"byline":    a.Byline,
+		"published": a.Published,
+		"image":     a.Image,
+		"siteName":  a.SiteName,
+		"excerpt":   a.Excerpt,
+		"html":      articleHTML,
+		"text":      renderText(a.Content),
+		"markdown":  markdown,
+	}, nil
+}
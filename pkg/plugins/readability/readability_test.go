@@ -0,0 +1,88 @@
+package readability
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testArticle = `
+<html>
+<head>
+  <title>Go 1.22 is released | The Go Blog</title>
+  <meta name="author" content="Eli Bendersky">
+  <meta name="description" content="Go 1.22 brings loop variable changes.">
+  <meta property="og:image" content="/images/go122.png">
+  <meta property="og:site_name" content="The Go Blog">
+  <script>var tracking = true;</script>
+</head>
+<body>
+  <nav><a href="/">Home</a> <a href="/blog">Blog</a></nav>
+  <div class="sidebar"><p>Subscribe to our newsletter, it is great, really, we promise.</p></div>
+  <article>
+    <h1>Go 1.22 is released</h1>
+    <time datetime="2024-02-06">6 February 2024</time>
+    <div class="post-content">
+      <p>Today the Go team is thrilled to release Go 1.22, which you can get by visiting the
+         <a href="/dl/" onclick="track()">download page</a>.</p>
+      <p>Go 1.22 comes with several important new features and improvements, including changes
+         to loop variables, range over integers, and enhanced routing patterns.</p>
+      <img src="gopher.png" alt="Gopher" onerror="alert(1)">
+      <pre>for i := range 10 {
+	fmt.Println(i)
+}</pre>
+      <div class="share"><a href="https://twitter.com">Share</a></div>
+    </div>
+  </article>
+  <footer><p>Copyright 2024, The Go Authors. All rights reserved, and so on.</p></footer>
+</body>
+</html>`
+
+func Test_readArticle(t *testing.T) {
+	output, err := readArticle(testArticle, "https://go.dev/blog/go1.22")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Go 1.22 is released", output["title"])
+	assert.Equal(t, "Eli Bendersky", output["byline"])
+	assert.Equal(t, "2024-02-06", output["published"])
+	assert.Equal(t, "https://go.dev/images/go122.png", output["image"])
+	assert.Equal(t, "The Go Blog", output["siteName"])
+	assert.Equal(t, "Go 1.22 brings loop variable changes.", output["excerpt"])
+
+	articleHTML := output["html"].(string)
+	assert.Contains(t, articleHTML, `<a href="https://go.dev/dl/">download page</a>`)
+	assert.Contains(t, articleHTML, `<img src="https://go.dev/blog/gopher.png" alt="Gopher"/>`)
+	assert.NotContains(t, articleHTML, "onclick")
+	assert.NotContains(t, articleHTML, "onerror")
+	assert.NotContains(t, articleHTML, "tracking")
+	assert.NotContains(t, articleHTML, "Subscribe")
+	assert.NotContains(t, articleHTML, "Share")
+	assert.NotContains(t, articleHTML, "Copyright")
+
+	text := output["text"].(string)
+	assert.Contains(t, text, "Today the Go team is thrilled to release Go 1.22, "+
+		"which you can get by visiting the download page.\n\n")
+	assert.Contains(t, text, "for i := range 10 {\n\tfmt.Println(i)\n}")
+
+	markdown := output["markdown"].(string)
+	assert.True(t, strings.HasPrefix(markdown, "# Go 1.22 is released\n\n6 February 2024"))
+	assert.Contains(t, markdown, "[download page](https://go.dev/dl/)")
+	assert.Contains(t, markdown, "![Gopher](https://go.dev/blog/gopher.png)")
+}
+
+func Test_readArticle_noContent(t *testing.T) {
+	_, err := readArticle("<html><body></body></html>", "https://example.com")
+	assert.ErrorIs(t, err, errNoContent)
+}
+
+func TestReadability_RunParams(t *testing.T) {
+	p := New(nil, nil, plugins.Config{})
+	_, err := p.Run(context.Background(), map[string]any{"url": "https://go.dev", "store": "true"})
+	assert.EqualError(t, err, "'store' parameter must be a boolean")
+	var paramErr *plugins.ParamError
+	assert.ErrorAs(t, err, &paramErr)
+}
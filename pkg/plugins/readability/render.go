@@ -0,0 +1,90 @@
+package readability
+
+import (
+	"bytes"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+var blockTags = map[string]bool{
+	"article": true, "p": true, "div": true, "br": true, "hr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true,
+	"blockquote": true, "pre": true, "figure": true, "figcaption": true,
+	"table": true, "tr": true,
+}
+
+func renderHTML(node *html.Node) (string, error) {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, node); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// renderText returns the plain text of the node with one paragraph per block element.
+func renderText(node *html.Node) string {
+	var (
+		paragraphs []string
+		current    strings.Builder
+	)
+	flush := func() {
+		if text := normalizeSpace(current.String()); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+		current.Reset()
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			current.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if n.Data == "pre" {
+				flush()
+				var pre strings.Builder
+				collectText(n, &pre)
+				if text := strings.TrimSpace(pre.String()); text != "" {
+					paragraphs = append(paragraphs, text)
+				}
+				return
+			}
+			if n.Data == "td" || n.Data == "th" {
+				current.WriteString(" ")
+			}
+		}
+		block := n.Type == html.ElementNode && blockTags[n.Data]
+		if block {
+			flush()
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if block {
+			flush()
+		}
+	}
+	walk(node)
+	flush()
+
+	return strings.Join(paragraphs, "\n\n")
+}
+
+func collectText(n *html.Node, sb *strings.Builder) {
+	if n.Type == html.TextNode {
+		sb.WriteString(n.Data)
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		collectText(child, sb)
+	}
+}
+
+func renderMarkdown(node *html.Node, domain string) string {
+	converter := md.NewConverter(domain, true, nil)
+	return converter.Convert(goquery.NewDocumentFromNode(node).Selection)
+}
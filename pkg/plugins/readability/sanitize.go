@@ -0,0 +1,160 @@
+package readability
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// droppedTags are removed along with their content.
+var droppedTags = []string{
+	"script", "style", "noscript", "template", "iframe", "object", "embed",
+	"svg", "canvas", "link", "meta", "input", "select", "textarea",
+}
+
+// allowedTags are kept in the sanitized content with their allowed attributes.
+// Other elements are replaced by their children.
+var allowedTags = map[string][]string{
+	"a":          {"href", "title"},
+	"img":        {"src", "alt", "title"},
+	"p":          nil,
+	"br":         nil,
+	"hr":         nil,
+	"h1":         nil,
+	"h2":         nil,
+	"h3":         nil,
+	"h4":         nil,
+	"h5":         nil,
+	"h6":         nil,
+	"ul":         nil,
+	"ol":         nil,
+	"li":         nil,
+	"dl":         nil,
+	"dt":         nil,
+	"dd":         nil,
+	"blockquote": nil,
+	"pre":        nil,
+	"code":       nil,
+	"em":         nil,
+	"i":          nil,
+	"strong":     nil,
+	"b":          nil,
+	"sub":        nil,
+	"sup":        nil,
+	"figure":     nil,
+	"figcaption": nil,
+	"table":      nil,
+	"thead":      nil,
+	"tbody":      nil,
+	"tr":         nil,
+	"th":         {"colspan", "rowspan"},
+	"td":         {"colspan", "rowspan"},
+}
+
+var urlAttributes = map[string]bool{
+	"href": true,
+	"src":  true,
+}
+
+// sanitize returns a copy of the selected element that only contains allowed
+// elements and attributes, with links resolved against the page URL.
+func sanitize(s *goquery.Selection, pageURL *url.URL) *html.Node {
+	root := &html.Node{
+		Type:     html.ElementNode,
+		Data:     "article",
+		DataAtom: atom.Article,
+	}
+	for child := s.Get(0).FirstChild; child != nil; child = child.NextSibling {
+		appendSanitized(root, child, pageURL)
+	}
+	removeEmpty(root)
+	return root
+}
+
+func appendSanitized(parent, node *html.Node, pageURL *url.URL) {
+	switch node.Type {
+	case html.TextNode:
+		parent.AppendChild(&html.Node{Type: html.TextNode, Data: node.Data})
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	if slices.Contains(droppedTags, node.Data) {
+		return
+	}
+	attrs, ok := allowedTags[node.Data]
+	if !ok {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			appendSanitized(parent, child, pageURL)
+		}
+		return
+	}
+
+	clone := &html.Node{
+		Type:     html.ElementNode,
+		Data:     node.Data,
+		DataAtom: node.DataAtom,
+	}
+	for _, attr := range node.Attr {
+		if attr.Namespace != "" || !slices.Contains(attrs, attr.Key) {
+			continue
+		}
+		if urlAttributes[attr.Key] {
+			value := absoluteURL(pageURL, attr.Val)
+			if !safeURL(value) {
+				continue
+			}
+			attr.Val = value
+		}
+		clone.Attr = append(clone.Attr, html.Attribute{Key: attr.Key, Val: attr.Val})
+	}
+	parent.AppendChild(clone)
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		appendSanitized(clone, child, pageURL)
+	}
+}
+
+// removeEmpty drops elements that have neither text nor media inside.
+func removeEmpty(node *html.Node) {
+	for child := node.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.ElementNode {
+			removeEmpty(child)
+			if isEmpty(child) {
+				node.RemoveChild(child)
+			}
+		}
+		child = next
+	}
+}
+
+func isEmpty(node *html.Node) bool {
+	switch node.Data {
+	case "img", "br", "hr", "td", "th":
+		return false
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode || strings.TrimSpace(child.Data) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func safeURL(value string) bool {
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto", "":
+		return true
+	}
+	return false
+}
@@ -2,13 +2,14 @@ package screenshot
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"path/filepath"
 	"time"
 
 	"github.com/bazuker/browserbro/pkg/fs"
 	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/plugins/consent"
+	"github.com/bazuker/browserbro/pkg/tracing"
 	"github.com/go-rod/rod"
 	"github.com/go-rod/stealth"
 )
@@ -23,9 +24,9 @@ type BotCheck struct {
 	fileStore            fs.FileStore
 }
 
-func New(browser *rod.Browser, fileStore fs.FileStore) *BotCheck {
+func New(browser *rod.Browser, fileStore fs.FileStore, cfg plugins.Config) *BotCheck {
 	return &BotCheck{
-		maxTimePerScreenshot: 15 * time.Second,
+		maxTimePerScreenshot: cfg.TimeoutOr(15 * time.Second),
 		browser:              browser,
 		fileStore:            fileStore,
 	}
@@ -35,67 +36,92 @@ func (p *BotCheck) Name() string {
 	return pluginName
 }
 
-func (p *BotCheck) Run(params map[string]any) (output map[string]any, err error) {
+// Cost is the number of pages to capture.
+func (p *BotCheck) Cost(params map[string]any) int {
+	urlsList, _ := params["urls"].([]any)
+	return len(urlsList)
+}
+
+func (p *BotCheck) Run(ctx context.Context, params map[string]any) (output map[string]any, err error) {
 	var stealthPage *rod.Page
 	stealthPage, err = stealth.Page(p.browser)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create stealth page: %w", err)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancelCause(ctx)
 	page := stealthPage.Context(ctx)
 
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("failed to complete: %v", r)
 		}
+		err = plugins.TimeLimitError(ctx, err)
 		_ = page.Close()
 	}()
 
 	urlsList, ok := params["urls"].([]any)
 	if !ok {
-		cancel()
-		return nil, errors.New("'urls' parameter must be an array of string")
+		cancel(nil)
+		return nil, plugins.ParamErrorf("'urls' parameter must be an array of string")
 	}
 	if len(urlsList) == 0 {
-		cancel()
-		return nil, errors.New("empty 'urls' parameter")
+		cancel(nil)
+		return nil, plugins.ParamErrorf("empty 'urls' parameter")
 	}
 	waitStable, ok := params["waitStable"].(bool)
 	if !ok {
 		waitStable = true
 	}
+	dismissConsent, err := consent.ParseParam(params)
+	if err != nil {
+		cancel(nil)
+		return nil, err
+	}
 
 	go func() {
 		time.Sleep(p.maxTimePerScreenshot * time.Duration(len(urlsList)))
-		cancel()
+		cancel(plugins.ErrTimeLimit)
 	}()
 
 	screenshots := make([]string, 0)
 	for _, url := range urlsList {
 		urlString, ok := url.(string)
 		if !ok {
-			return nil, errors.New("'urls' parameter must only contain strings")
+			return nil, plugins.ParamErrorf("'urls' parameter must only contain strings")
 		}
 
-		err = page.Navigate(urlString)
+		err = tracing.Navigate(page, urlString)
 		if err != nil {
 			return nil, fmt.Errorf("failed to navigate to the page '%s': %w", urlString, err)
 		}
 
-		err = page.WaitLoad()
+		err = tracing.WaitLoad(page)
 		if err != nil {
 			return nil, fmt.Errorf("failed to wait for page to load: %w", err)
 		}
+		if dismissConsent {
+			consent.Dismiss(page)
+		}
 		if waitStable {
-			err = page.WaitStable(time.Second)
+			err = tracing.WaitStable(page, time.Second)
 			if err != nil {
 				return nil, fmt.Errorf("failed to wait for page to stabilize: %w", err)
 			}
 		}
 
-		filename := helper.GenerateRandomString(6) + ".screenshot.png"
-		page.MustScreenshotFullPage(filepath.Join(p.fileStore.BasePath(), filename))
+		var data []byte
+		err = tracing.Screenshot(page, func() (err error) {
+			data, err = page.Screenshot(true, nil)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to take screenshot of the page '%s': %w", urlString, err)
+		}
+		filename := helper.NewFileName("screenshot", "png")
+		if err = tracing.PutObject(ctx, p.fileStore, data, filename); err != nil {
+			return nil, fmt.Errorf("failed to save screenshot: %w", err)
+		}
 
 		screenshots = append(screenshots, filename)
 	}
@@ -0,0 +1,354 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/fs"
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/plugins/consent"
+	"github.com/bazuker/browserbro/pkg/tracing"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/go-rod/stealth"
+)
+
+const (
+	pluginName = "script"
+)
+
+type Script struct {
+	browser            *rod.Browser
+	fileStore          fs.FileStore
+	maxTimePerScript   time.Duration
+	defaultStepTimeout time.Duration
+}
+
+// Config is the configuration section of the script plugin.
+type Config struct {
+	// The timeout is the maximum time of the whole script. Default: 60 seconds.
+	plugins.Config `yaml:",inline"`
+	// StepTimeout is the timeout of the steps that do not set one. Default: 10 seconds.
+	StepTimeout time.Duration `yaml:"stepTimeout"`
+}
+
+func (c Config) Validate() error {
+	if c.StepTimeout < 0 {
+		return errors.New("'stepTimeout' must not be negative")
+	}
+	return c.Config.Validate()
+}
+
+func New(browser *rod.Browser, fileStore fs.FileStore, cfg Config) *Script {
+	p := &Script{
+		browser:            browser,
+		fileStore:          fileStore,
+		maxTimePerScript:   cfg.TimeoutOr(60 * time.Second),
+		defaultStepTimeout: 10 * time.Second,
+	}
+	if cfg.StepTimeout > 0 {
+		p.defaultStepTimeout = cfg.StepTimeout
+	}
+	return p
+}
+
+func (p *Script) Name() string {
+	return pluginName
+}
+
+func (p *Script) Run(ctx context.Context, params map[string]any) (output map[string]any, err error) {
+	steps, err := parseSteps(params["steps"])
+	if err != nil {
+		return nil, err
+	}
+	dismissConsent, err := consent.ParseParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var stealthPage *rod.Page
+	stealthPage, err = stealth.Page(p.browser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stealth page: %w", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	page := stealthPage.Context(ctx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to complete: %v", r)
+		}
+		err = plugins.TimeLimitError(ctx, err)
+		_ = page.Close()
+	}()
+
+	go func() {
+		time.Sleep(p.maxTimePerScript)
+		cancel(plugins.ErrTimeLimit)
+	}()
+
+	results := make(map[string]any)
+	for _, s := range steps {
+		stepPage := page.Timeout(s.timeout(p.defaultStepTimeout))
+		result, err := p.runStep(stepPage, s, dismissConsent)
+		stepPage.CancelTimeout()
+		if err != nil {
+			return nil, fmt.Errorf("step '%s' (%s) failed: %w", s.Name, s.Action, err)
+		}
+		if result != nil {
+			results[s.Name] = result
+		}
+	}
+
+	output = make(map[string]any)
+	output["results"] = results
+	if info, err := page.Info(); err == nil {
+		output["url"] = info.URL
+	}
+
+	return output, nil
+}
+
+// runStep executes a single step on the page and returns its output, if any.
+// Consent dialogs are dismissed after every navigation if dismissConsent is set.
+func (p *Script) runStep(page *rod.Page, s step, dismissConsent bool) (any, error) {
+	switch s.Action {
+	case actionNavigate:
+		if err := tracing.Navigate(page, s.URL); err != nil {
+			return nil, fmt.Errorf("failed to navigate to the page '%s': %w", s.URL, err)
+		}
+		if err := tracing.WaitLoad(page); err != nil {
+			return nil, fmt.Errorf("failed to wait for page to load: %w", err)
+		}
+		if dismissConsent {
+			consent.Dismiss(page)
+		}
+	case actionClick:
+		el, err := findElement(page, s)
+		if err != nil {
+			return nil, err
+		}
+		return nil, el.Click(proto.InputMouseButtonLeft, 1)
+	case actionType:
+		el, err := findElement(page, s)
+		if err != nil {
+			return nil, err
+		}
+		return nil, el.Input(s.Text)
+	case actionPress:
+		key := keys[strings.ToLower(s.Key)]
+		if s.Selector != "" || s.XPath != "" {
+			el, err := findElement(page, s)
+			if err != nil {
+				return nil, err
+			}
+			return nil, el.Type(key)
+		}
+		return nil, page.Keyboard.Type(key)
+	case actionWaitFor:
+		return nil, waitFor(page, s)
+	case actionScroll:
+		if s.Selector != "" || s.XPath != "" {
+			el, err := findElement(page, s)
+			if err != nil {
+				return nil, err
+			}
+			return nil, el.ScrollIntoView()
+		}
+		return nil, page.Mouse.Scroll(s.X, s.Y, 1)
+	case actionSelect:
+		el, err := findElement(page, s)
+		if err != nil {
+			return nil, err
+		}
+		return nil, el.Select(s.Values, true, rod.SelectorTypeText)
+	case actionHover:
+		el, err := findElement(page, s)
+		if err != nil {
+			return nil, err
+		}
+		return nil, el.Hover()
+	case actionScreenshot:
+		return p.screenshot(page, s)
+	case actionExtract:
+		return extract(page, s)
+	case actionAssert:
+		return nil, assertPage(page, s)
+	}
+
+	return nil, fmt.Errorf("unsupported action '%s'", s.Action)
+}
+
+func findElement(page *rod.Page, s step) (*rod.Element, error) {
+	var (
+		el  *rod.Element
+		err error
+	)
+	if s.XPath != "" {
+		el, err = page.ElementX(s.XPath)
+	} else {
+		el, err = page.Element(s.Selector)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find element: %w", err)
+	}
+	return el, nil
+}
+
+func findElements(page *rod.Page, s step) (rod.Elements, error) {
+	// Wait for at least one element to appear before collecting all of them.
+	if _, err := findElement(page, s); err != nil {
+		return nil, err
+	}
+	if s.XPath != "" {
+		return page.ElementsX(s.XPath)
+	}
+	return page.Elements(s.Selector)
+}
+
+func waitFor(page *rod.Page, s step) error {
+	if s.Selector != "" || s.XPath != "" {
+		el, err := findElement(page, s)
+		if err != nil {
+			return err
+		}
+		return el.WaitVisible()
+	}
+	if s.Text != "" {
+		return page.Wait(rod.Eval(
+			`t => !!document.body && document.body.innerText.includes(t)`,
+			s.Text,
+		))
+	}
+	page.WaitRequestIdle(500*time.Millisecond, nil, nil, nil)()
+	return page.GetContext().Err()
+}
+
+func (p *Script) screenshot(page *rod.Page, s step) (string, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if s.Selector != "" || s.XPath != "" {
+		el, findErr := findElement(page, s)
+		if findErr != nil {
+			return "", findErr
+		}
+		err = tracing.Screenshot(page, func() error {
+			data, err = el.Screenshot(proto.PageCaptureScreenshotFormatPng, 0)
+			return err
+		})
+	} else {
+		err = tracing.Screenshot(page, func() error {
+			data, err = page.Screenshot(s.FullPage, nil)
+			return err
+		})
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to take screenshot: %w", err)
+	}
+
+	filename := helper.NewFileName("script", "png")
+	if err := tracing.PutObject(page.GetContext(), p.fileStore, data, filename); err != nil {
+		return "", fmt.Errorf("failed to save screenshot: %w", err)
+	}
+
+	return filename, nil
+}
+
+func extract(page *rod.Page, s step) (any, error) {
+	if !s.All {
+		el, err := findElement(page, s)
+		if err != nil {
+			return nil, err
+		}
+		return extractValue(el, s)
+	}
+
+	elements, err := findElements(page, s)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]any, 0, len(elements))
+	for _, el := range elements {
+		value, err := extractValue(el, s)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func extractValue(el *rod.Element, s step) (any, error) {
+	switch s.From {
+	case "attribute":
+		value, err := el.Attribute(s.Attribute)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			return nil, nil
+		}
+		return *value, nil
+	case "html":
+		return el.HTML()
+	default:
+		return el.Text()
+	}
+}
+
+func assertPage(page *rod.Page, s step) error {
+	if s.URLContains != "" {
+		info, err := page.Info()
+		if err != nil {
+			return fmt.Errorf("failed to get page info: %w", err)
+		}
+		if !strings.Contains(info.URL, s.URLContains) {
+			return fmt.Errorf("page URL '%s' does not contain '%s'", info.URL, s.URLContains)
+		}
+	}
+	if s.Selector == "" && s.XPath == "" {
+		return nil
+	}
+
+	if s.Absent {
+		var (
+			has bool
+			err error
+		)
+		if s.XPath != "" {
+			has, _, err = page.HasX(s.XPath)
+		} else {
+			has, _, err = page.Has(s.Selector)
+		}
+		if err != nil {
+			return err
+		}
+		if has {
+			return errors.New("element is present")
+		}
+		return nil
+	}
+
+	el, err := findElement(page, s)
+	if err != nil {
+		return err
+	}
+	if s.Contains == "" {
+		return nil
+	}
+	text, err := el.Text()
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(text, s.Contains) {
+		return fmt.Errorf("element text does not contain '%s'", s.Contains)
+	}
+	return nil
+}
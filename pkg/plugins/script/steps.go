@@ -0,0 +1,197 @@
+package script
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/go-rod/rod/lib/input"
+)
+
+const (
+	actionNavigate   = "navigate"
+	actionClick      = "click"
+	actionType       = "type"
+	actionPress      = "press"
+	actionWaitFor    = "waitFor"
+	actionScroll     = "scroll"
+	actionSelect     = "select"
+	actionHover      = "hover"
+	actionScreenshot = "screenshot"
+	actionExtract    = "extract"
+	actionAssert     = "assert"
+)
+
+// step is a single instruction of a script.
+type step struct {
+	// Name is the key under which the step output is stored.
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	// Timeout is the maximum step duration in seconds.
+	Timeout float64 `json:"timeout"`
+
+	URL      string `json:"url"`
+	Selector string `json:"selector"`
+	XPath    string `json:"xpath"`
+	Text     string `json:"text"`
+	Key      string `json:"key"`
+
+	// Values are the options to pick in a select element.
+	Values []string `json:"values"`
+	// NetworkIdle makes waitFor wait until there are no network requests.
+	NetworkIdle bool `json:"networkIdle"`
+	// X and Y are the scroll offsets in pixels.
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	// FullPage makes a page screenshot capture the whole page.
+	FullPage bool `json:"fullPage"`
+
+	// From is the extraction source: text, attribute or html.
+	From      string `json:"from"`
+	Attribute string `json:"attribute"`
+	// All extracts every matched element instead of the first one.
+	All bool `json:"all"`
+
+	// Contains is the text an assert expects to find.
+	Contains string `json:"contains"`
+	// URLContains is the text an assert expects to find in the page URL.
+	URLContains string `json:"urlContains"`
+	// Absent makes an assert expect the selector to match nothing.
+	Absent bool `json:"absent"`
+}
+
+var keys = map[string]input.Key{
+	"enter":      input.Enter,
+	"tab":        input.Tab,
+	"escape":     input.Escape,
+	"backspace":  input.Backspace,
+	"delete":     input.Delete,
+	"space":      input.Space,
+	"arrowup":    input.ArrowUp,
+	"arrowdown":  input.ArrowDown,
+	"arrowleft":  input.ArrowLeft,
+	"arrowright": input.ArrowRight,
+	"home":       input.Home,
+	"end":        input.End,
+	"pageup":     input.PageUp,
+	"pagedown":   input.PageDown,
+}
+
+func parseSteps(raw any) ([]step, error) {
+	list, ok := raw.([]any)
+	if !ok {
+		return nil, plugins.ParamErrorf("'steps' parameter must be an array of objects")
+	}
+	if len(list) == 0 {
+		return nil, plugins.ParamErrorf("empty 'steps' parameter")
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode steps: %w", err)
+	}
+	var steps []step
+	if err := json.Unmarshal(data, &steps); err != nil {
+		return nil, plugins.ParamErrorf("'steps' parameter is malformed: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i := range steps {
+		s := &steps[i]
+		if s.Name == "" {
+			s.Name = fmt.Sprintf("step%d", i+1)
+		}
+		if names[s.Name] {
+			return nil, plugins.ParamErrorf("duplicate step name '%s'", s.Name)
+		}
+		names[s.Name] = true
+		if s.Timeout < 0 {
+			return nil, plugins.ParamErrorf("step '%s': timeout must not be negative", s.Name)
+		}
+		if err := s.validate(); err != nil {
+			return nil, plugins.ParamErrorf("step '%s': %w", s.Name, err)
+		}
+	}
+
+	return steps, nil
+}
+
+func (s *step) validate() error {
+	hasTarget := s.Selector != "" || s.XPath != ""
+	if s.Selector != "" && s.XPath != "" {
+		return errors.New("only one of 'selector' and 'xpath' can be set")
+	}
+
+	switch s.Action {
+	case actionNavigate:
+		if s.URL == "" {
+			return errors.New("missing 'url'")
+		}
+	case actionClick, actionHover:
+		if !hasTarget {
+			return errors.New("missing 'selector' or 'xpath'")
+		}
+	case actionType:
+		if !hasTarget {
+			return errors.New("missing 'selector' or 'xpath'")
+		}
+		if s.Text == "" {
+			return errors.New("missing 'text'")
+		}
+	case actionPress:
+		if _, ok := keys[strings.ToLower(s.Key)]; !ok {
+			return fmt.Errorf("unsupported key '%s'", s.Key)
+		}
+	case actionWaitFor:
+		if !hasTarget && s.Text == "" && !s.NetworkIdle {
+			return errors.New("one of 'selector', 'xpath', 'text' or 'networkIdle' is required")
+		}
+	case actionScroll:
+		if !hasTarget && s.X == 0 && s.Y == 0 {
+			return errors.New("one of 'selector', 'xpath', 'x' or 'y' is required")
+		}
+	case actionSelect:
+		if !hasTarget {
+			return errors.New("missing 'selector' or 'xpath'")
+		}
+		if len(s.Values) == 0 {
+			return errors.New("missing 'values'")
+		}
+	case actionScreenshot:
+	case actionExtract:
+		if !hasTarget {
+			return errors.New("missing 'selector' or 'xpath'")
+		}
+		switch s.From {
+		case "", "text", "html":
+		case "attribute":
+			if s.Attribute == "" {
+				return errors.New("missing 'attribute'")
+			}
+		default:
+			return fmt.Errorf("invalid extraction source '%s'", s.From)
+		}
+	case actionAssert:
+		if !hasTarget && s.URLContains == "" {
+			return errors.New("one of 'selector', 'xpath' or 'urlContains' is required")
+		}
+		if s.Absent && s.Contains != "" {
+			return errors.New("'absent' cannot be combined with 'contains'")
+		}
+	case "":
+		return errors.New("missing 'action'")
+	default:
+		return fmt.Errorf("unsupported action '%s'", s.Action)
+	}
+
+	return nil
+}
+
+func (s *step) timeout(defaultTimeout time.Duration) time.Duration {
+	if s.Timeout == 0 {
+		return defaultTimeout
+	}
+	return time.Duration(s.Timeout * float64(time.Second))
+}
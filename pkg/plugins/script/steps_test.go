@@ -0,0 +1,89 @@
+package script
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseSteps(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		steps, err := parseSteps([]any{
+			map[string]any{"action": "navigate", "url": "https://example.com"},
+			map[string]any{"action": "type", "selector": "input", "text": "hello"},
+			map[string]any{"action": "press", "key": "Enter"},
+			map[string]any{"action": "waitFor", "networkIdle": true, "timeout": 2.5},
+			map[string]any{"name": "title", "action": "extract", "selector": "h1"},
+		})
+		require.NoError(t, err)
+		require.Len(t, steps, 5)
+		assert.Equal(t, "step1", steps[0].Name)
+		assert.Equal(t, "https://example.com", steps[0].URL)
+		assert.Equal(t, "title", steps[4].Name)
+		assert.Equal(t, 2500*time.Millisecond, steps[3].timeout(time.Second))
+		assert.Equal(t, time.Second, steps[0].timeout(time.Second))
+	})
+
+	t.Run("invalid steps", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			steps any
+			err   string
+		}{
+			{"not an array", "navigate", "'steps' parameter must be an array of objects"},
+			{"empty", []any{}, "empty 'steps' parameter"},
+			{
+				"malformed",
+				[]any{map[string]any{"action": 42}},
+				"'steps' parameter is malformed",
+			},
+			{"missing action", []any{map[string]any{}}, "step 'step1': missing 'action'"},
+			{
+				"unsupported action",
+				[]any{map[string]any{"action": "fly"}},
+				"step 'step1': unsupported action 'fly'",
+			},
+			{
+				"missing url",
+				[]any{map[string]any{"action": "navigate"}},
+				"step 'step1': missing 'url'",
+			},
+			{
+				"unsupported key",
+				[]any{map[string]any{"action": "press", "key": "F13"}},
+				"step 'step1': unsupported key 'F13'",
+			},
+			{
+				"selector and xpath",
+				[]any{map[string]any{"action": "click", "selector": "a", "xpath": "//a"}},
+				"step 'step1': only one of 'selector' and 'xpath' can be set",
+			},
+			{
+				"attribute without name",
+				[]any{map[string]any{"action": "extract", "selector": "a", "from": "attribute"}},
+				"step 'step1': missing 'attribute'",
+			},
+			{
+				"duplicate name",
+				[]any{
+					map[string]any{"name": "a", "action": "hover", "selector": "a"},
+					map[string]any{"name": "a", "action": "hover", "selector": "a"},
+				},
+				"duplicate step name 'a'",
+			},
+			{
+				"negative timeout",
+				[]any{map[string]any{"action": "screenshot", "timeout": -1}},
+				"step 'step1': timeout must not be negative",
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := parseSteps(tt.steps)
+				assert.ErrorContains(t, err, tt.err)
+			})
+		}
+	})
+}
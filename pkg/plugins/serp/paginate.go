@@ -0,0 +1,100 @@
+package serp
+
+import (
+	"fmt"
+
+	"github.com/bazuker/browserbro/pkg/plugins/botwall"
+	"github.com/bazuker/browserbro/pkg/plugins/consent"
+	"github.com/bazuker/browserbro/pkg/tracing"
+	"github.com/go-rod/rod"
+)
+
+// Paginator reads the results of several consecutive results pages.
+type Paginator struct {
+	// Parse reads the results of the current page.
+	Parse func(page *rod.Page) ([]Result, error)
+	// Next opens the next results page and reports whether there was one.
+	Next func(page *rod.Page) (bool, error)
+	// OnPage is an optional hook called for every loaded page with its 0-based index.
+	OnPage func(page *rod.Page, index int) error
+	// DismissConsent enables dismissing consent dialogs on every loaded page.
+	DismissConsent bool
+}
+
+// Collect navigates to the first results page and reads the results of the
+// requested number of pages. The results are normalized and numbered across pages.
+// A *botwall.BlockedError is returned if a results page is a bot wall.
+func (p Paginator) Collect(page *rod.Page, firstURL string, pages int) ([]Result, error) {
+	err := tracing.Navigate(page, firstURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to navigate to the results page: %w", err)
+	}
+
+	results := make([]Result, 0)
+	seen := make(map[string]bool)
+	for i := 0; i < pages; i++ {
+		err = tracing.WaitLoad(page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to wait for page to load: %w", err)
+		}
+		if p.DismissConsent {
+			consent.Dismiss(page)
+		}
+		if err := botwall.Check(page); err != nil {
+			return nil, err
+		}
+		if p.OnPage != nil {
+			if err := p.OnPage(page, i); err != nil {
+				return nil, err
+			}
+		}
+		pageResults, err := p.Parse(page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse search results: %w", err)
+		}
+		results = append(results, Normalize(pageResults, len(results), seen)...)
+
+		if i == pages-1 || p.Next == nil {
+			break
+		}
+		hasNext, err := p.Next(page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the results page %d: %w", i+2, err)
+		}
+		if !hasNext {
+			break
+		}
+	}
+	return results, nil
+}
+
+// NextLink returns a Next function that follows the link matching the selector.
+func NextLink(selector string) func(page *rod.Page) (bool, error) {
+	return func(page *rod.Page) (bool, error) {
+		hasNext, next, err := page.Has(selector)
+		if err != nil || !hasNext {
+			return false, err
+		}
+		nextURL, err := next.Property("href")
+		if err != nil {
+			return false, err
+		}
+		return true, tracing.Navigate(page, nextURL.Str())
+	}
+}
+
+// ParseScript returns a Parse function that evaluates the JS function and
+// decodes the array it returns into results.
+func ParseScript(js string) func(page *rod.Page) ([]Result, error) {
+	return func(page *rod.Page) ([]Result, error) {
+		res, err := page.Eval(js)
+		if err != nil {
+			return nil, err
+		}
+		var results []Result
+		if err := res.Value.Unmarshal(&results); err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+}
@@ -0,0 +1,206 @@
+// Package serp contains the building blocks shared by the search engine
+// plugins: query parameters, pagination and result normalization.
+package serp
+
+import (
+	"encoding/base64"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/bazuker/browserbro/pkg/plugins"
+)
+
+const (
+	MaxPages = 10
+	MaxNum   = 100
+)
+
+// TimeRanges are the supported values of the time range filter.
+var TimeRanges = []string{"hour", "day", "week", "month", "year"}
+
+// Result is a single search engine result.
+type Result struct {
+	Link        string `json:"link"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Position is the absolute 1-based position across all the read pages.
+	Position int `json:"position"`
+
+	// Source and Date are set for news results.
+	Source string `json:"source,omitempty"`
+	Date   string `json:"date,omitempty"`
+	// Thumbnail is set for image results.
+	Thumbnail string `json:"thumbnail,omitempty"`
+}
+
+// Query holds the search parameters understood by every search engine plugin.
+type Query struct {
+	// Text is the search query.
+	Text string
+	// Pages is the number of results pages to read.
+	Pages int
+	// Num is the number of results per page. Zero means the engine default.
+	Num int
+	// Language is the interface language, e.g. "en".
+	Language string
+	// Country is a two-letter country code, e.g. "us".
+	Country string
+	// Safe enables or disables safe search. Nil means the engine default.
+	Safe *bool
+	// TimeRange is one of TimeRanges or empty.
+	TimeRange string
+}
+
+// ParseQuery reads the common search parameters: query, pages, num, hl, gl,
+// safe and timeRange.
+func ParseQuery(params map[string]any) (Query, error) {
+	q := Query{Pages: 1}
+
+	query, ok := params["query"]
+	if !ok {
+		return q, plugins.ParamErrorf("missing 'query' parameter")
+	}
+	if q.Text, ok = query.(string); !ok {
+		return q, plugins.ParamErrorf("'query' parameter must be a string")
+	}
+
+	if value, ok := params["pages"]; ok {
+		pages, ok := value.(float64)
+		if !ok || pages != float64(int(pages)) || pages < 1 || pages > MaxPages {
+			return q, plugins.ParamErrorf("'pages' parameter must be an integer between 1 and %d", MaxPages)
+		}
+		q.Pages = int(pages)
+	}
+	if value, ok := params["num"]; ok {
+		num, ok := value.(float64)
+		if !ok || num != float64(int(num)) || num < 1 || num > MaxNum {
+			return q, plugins.ParamErrorf("'num' parameter must be an integer between 1 and %d", MaxNum)
+		}
+		q.Num = int(num)
+	}
+
+	var err error
+	if q.Language, err = optionalString(params, "hl"); err != nil {
+		return q, err
+	}
+	if q.Country, err = optionalString(params, "gl"); err != nil {
+		return q, err
+	}
+
+	if value, ok := params["safe"]; ok {
+		safe, ok := value.(bool)
+		if !ok {
+			return q, plugins.ParamErrorf("'safe' parameter must be a boolean")
+		}
+		q.Safe = &safe
+	}
+
+	if value, ok := params["timeRange"]; ok {
+		timeRange, ok := value.(string)
+		if !ok {
+			return q, plugins.ParamErrorf("'timeRange' parameter must be a string")
+		}
+		if !slices.Contains(TimeRanges, timeRange) {
+			return q, plugins.ParamErrorf("invalid time range '%s'", timeRange)
+		}
+		q.TimeRange = timeRange
+	}
+
+	return q, nil
+}
+
+func optionalString(params map[string]any, name string) (string, error) {
+	value, ok := params[name]
+	if !ok {
+		return "", nil
+	}
+	s, ok := value.(string)
+	if !ok || s == "" {
+		return "", plugins.ParamErrorf("'%s' parameter must be a non-empty string", name)
+	}
+	return s, nil
+}
+
+// UnwrapURL returns the target of a search engine redirect link or the link
+// itself if it is not a redirect.
+func UnwrapURL(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return link
+	}
+	query := u.Query()
+	host := strings.TrimPrefix(u.Hostname(), "www.")
+
+	switch {
+	// Google: /url?q=<target> or /url?url=<target>
+	case u.Path == "/url" && (host == "" || strings.HasPrefix(host, "google.")):
+		for _, key := range []string{"q", "url"} {
+			if target := query.Get(key); isAbsolute(target) {
+				return target
+			}
+		}
+	// Bing: /ck/a?u=a1<base64url target>
+	case u.Path == "/ck/a" && (host == "" || host == "bing.com"):
+		if target, ok := decodeBingURL(query.Get("u")); ok {
+			return target
+		}
+	// DuckDuckGo: /l/?uddg=<target>
+	case u.Path == "/l/" && (host == "" || strings.HasSuffix(host, "duckduckgo.com")):
+		if target := query.Get("uddg"); isAbsolute(target) {
+			return target
+		}
+	}
+
+	if u.Scheme == "" && u.Host != "" {
+		// Protocol-relative link.
+		u.Scheme = "https"
+		return u.String()
+	}
+	return link
+}
+
+// Normalize cleans up the results and numbers them starting after offset.
+// Redirect links are unwrapped, and results without a link or a title as well
+// as results whose link is in seen are dropped. Links of the kept results are
+// added to seen.
+func Normalize(results []Result, offset int, seen map[string]bool) []Result {
+	normalized := make([]Result, 0, len(results))
+	for _, r := range results {
+		r.Link = UnwrapURL(r.Link)
+		r.Title = normalizeSpace(r.Title)
+		r.Description = normalizeSpace(r.Description)
+		r.Source = normalizeSpace(r.Source)
+		r.Date = normalizeSpace(r.Date)
+		if !isAbsolute(r.Link) || r.Title == "" || seen[r.Link] {
+			continue
+		}
+		seen[r.Link] = true
+		r.Position = offset + len(normalized) + 1
+		normalized = append(normalized, r)
+	}
+	return normalized
+}
+
+// decodeBingURL decodes the "u" parameter of Bing redirect links, which is
+// "a1" followed by the unpadded URL-safe base64 encoded target.
+func decodeBingURL(value string) (string, bool) {
+	encoded, ok := strings.CutPrefix(value, "a1")
+	if !ok {
+		return "", false
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil || !isAbsolute(string(decoded)) {
+		return "", false
+	}
+	return string(decoded), true
+}
+
+func isAbsolute(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,90 @@
+package serp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery(t *testing.T) {
+	q, err := ParseQuery(map[string]any{"query": "golang"})
+	require.NoError(t, err)
+	assert.Equal(t, Query{Text: "golang", Pages: 1}, q)
+
+	safe := false
+	q, err = ParseQuery(map[string]any{
+		"query":     "golang",
+		"pages":     float64(3),
+		"num":       float64(50),
+		"hl":        "de",
+		"gl":        "at",
+		"safe":      false,
+		"timeRange": "week",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Query{
+		Text:      "golang",
+		Pages:     3,
+		Num:       50,
+		Language:  "de",
+		Country:   "at",
+		Safe:      &safe,
+		TimeRange: "week",
+	}, q)
+
+	tests := []struct {
+		params map[string]any
+		err    string
+	}{
+		{map[string]any{}, "missing 'query' parameter"},
+		{map[string]any{"query": 1.0}, "'query' parameter must be a string"},
+		{map[string]any{"query": "go", "pages": float64(11)}, "'pages' parameter must be an integer between 1 and 10"},
+		{map[string]any{"query": "go", "num": 1.5}, "'num' parameter must be an integer between 1 and 100"},
+		{map[string]any{"query": "go", "hl": ""}, "'hl' parameter must be a non-empty string"},
+		{map[string]any{"query": "go", "safe": "on"}, "'safe' parameter must be a boolean"},
+		{map[string]any{"query": "go", "timeRange": "decade"}, "invalid time range 'decade'"},
+		{map[string]any{"query": "go", "timeRange": "100%d"}, "invalid time range '100%d'"},
+	}
+	for _, tt := range tests {
+		_, err := ParseQuery(tt.params)
+		assert.EqualError(t, err, tt.err)
+	}
+}
+
+func TestUnwrapURL(t *testing.T) {
+	tests := []struct {
+		link     string
+		expected string
+	}{
+		{"https://go.dev/doc/", "https://go.dev/doc/"},
+		{"/url?q=https://go.dev/doc/&sa=U", "https://go.dev/doc/"},
+		{"https://www.google.com/url?url=https%3A%2F%2Fgo.dev%2Fdoc%2F", "https://go.dev/doc/"},
+		{"https://www.bing.com/ck/a?!&&p=abc&u=a1aHR0cHM6Ly9nby5kZXYvZG9jLw&ntb=1", "https://go.dev/doc/"},
+		{"https://www.bing.com/ck/a?u=invalid", "https://www.bing.com/ck/a?u=invalid"},
+		{"//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2Fdoc%2F&rut=abc", "https://go.dev/doc/"},
+		{"//go.dev/doc/", "https://go.dev/doc/"},
+		{"https://example.com/url?q=https://go.dev/doc/", "https://example.com/url?q=https://go.dev/doc/"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, UnwrapURL(tt.link), tt.link)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	seen := map[string]bool{"https://go.dev/": true}
+	results := Normalize([]Result{
+		{Link: "https://go.dev/", Title: "Go"},
+		{Link: "/url?q=https://go.dev/doc/", Title: " Go\n Documentation ", Description: "The  docs"},
+		{Link: "https://go.dev/blog/", Title: ""},
+		{Link: "/relative", Title: "Relative"},
+		{Link: "https://go.dev/doc/", Title: "Duplicate"},
+		{Link: "https://pkg.go.dev/", Title: "Packages"},
+	}, 10, seen)
+
+	assert.Equal(t, []Result{
+		{Link: "https://go.dev/doc/", Title: "Go Documentation", Description: "The docs", Position: 11},
+		{Link: "https://pkg.go.dev/", Title: "Packages", Position: 12},
+	}, results)
+	assert.True(t, seen["https://pkg.go.dev/"])
+}
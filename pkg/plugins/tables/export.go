@@ -0,0 +1,155 @@
+package tables
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// toCSV encodes the table with the column names as the first row.
+func toCSV(t table) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(t.Headers); err != nil {
+		return nil, err
+	}
+	if err := w.WriteAll(t.Rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type zipFile struct {
+	name    string
+	content string
+}
+
+// toXLSX encodes the tables as an Office Open XML workbook with a sheet per
+// table. The column names are the first row of every sheet.
+func toXLSX(tables []table) ([]byte, error) {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+
+	var sheets, sheetRels, sheetTypes strings.Builder
+	usedNames := make(map[string]bool)
+	for i, t := range tables {
+		id := i + 1
+		// Sheet names must be unique regardless of the case.
+		name := sheetName(t)
+		if usedNames[strings.ToLower(name)] {
+			name = fmt.Sprintf("Table %d", t.Index+1)
+		}
+		usedNames[strings.ToLower(name)] = true
+		fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escapeXML(name), id, id)
+		fmt.Fprintf(&sheetRels,
+			`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`,
+			id, id)
+		fmt.Fprintf(&sheetTypes,
+			`<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`,
+			id)
+	}
+
+	files := []zipFile{
+		{
+			"[Content_Types].xml",
+			xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+				`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+				`<Default Extension="xml" ContentType="application/xml"/>` +
+				`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+				sheetTypes.String() +
+				`</Types>`,
+		},
+		{
+			"_rels/.rels",
+			xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+				`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+				`</Relationships>`,
+		},
+		{
+			"xl/workbook.xml",
+			xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+				`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+				`<sheets>` + sheets.String() + `</sheets></workbook>`,
+		},
+		{
+			"xl/_rels/workbook.xml.rels",
+			xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+				sheetRels.String() + `</Relationships>`,
+		},
+	}
+	for i, t := range tables {
+		files = append(files, zipFile{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), worksheet(t)})
+	}
+
+	for _, f := range files {
+		w, err := z.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(f.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := z.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// worksheet renders the table as a sheet with inline string cells.
+func worksheet(t table) string {
+	var sb strings.Builder
+	sb.WriteString(xml.Header)
+	sb.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for r, row := range append([][]string{t.Headers}, t.Rows...) {
+		fmt.Fprintf(&sb, `<row r="%d">`, r+1)
+		for c, value := range row {
+			if value == "" {
+				continue
+			}
+			fmt.Fprintf(&sb, `<c r="%s%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`,
+				columnName(c), r+1, escapeXML(value))
+		}
+		sb.WriteString(`</row>`)
+	}
+	sb.WriteString(`</sheetData></worksheet>`)
+	return sb.String()
+}
+
+// sheetName returns the table caption or "Table N" shortened to the 31
+// characters allowed by Excel and without the characters it forbids.
+func sheetName(t table) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return ' '
+		}
+		return r
+	}, t.Caption)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = fmt.Sprintf("Table %d", t.Index+1)
+	}
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	return name
+}
+
+// columnName converts the 0-based column index to the spreadsheet column name, e.g. 27 to "AB".
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
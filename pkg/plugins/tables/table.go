@@ -0,0 +1,190 @@
+package tables
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxSpan limits colspan and rowspan values to keep malformed tables small.
+const maxSpan = 1000
+
+// table is an HTML table with its spanning cells expanded into a grid.
+type table struct {
+	Index   int
+	Caption string
+	Headers []string
+	Rows    [][]string
+}
+
+type cell struct {
+	text   string
+	header bool
+}
+
+type span struct {
+	cell cell
+	rows int
+}
+
+// parseTables reads the tables matching the selector.
+func parseTables(doc *goquery.Document, selector string) []table {
+	tables := make([]table, 0)
+	doc.Find(selector).Each(func(_ int, s *goquery.Selection) {
+		if goquery.NodeName(s) != "table" {
+			return
+		}
+		t := parseTable(s)
+		t.Index = len(tables)
+		tables = append(tables, t)
+	})
+	return tables
+}
+
+func parseTable(s *goquery.Selection) table {
+	grid, headerRows := parseGrid(s)
+	t := table{
+		Caption: normalizeSpace(s.ChildrenFiltered("caption").First().Text()),
+		Headers: headerNames(grid[:headerRows], width(grid)),
+		Rows:    make([][]string, 0, len(grid)-headerRows),
+	}
+	for _, row := range grid[headerRows:] {
+		values := make([]string, len(t.Headers))
+		for i, c := range row {
+			values[i] = c.text
+		}
+		t.Rows = append(t.Rows, values)
+	}
+	return t
+}
+
+// parseGrid returns the rows of the table with the spanning cells copied to
+// every grid position they cover, and the number of leading header rows.
+func parseGrid(s *goquery.Selection) ([][]cell, int) {
+	grid := make([][]cell, 0)
+	spans := make(map[int]span)
+	headerRows := 0
+	inHeader := true
+
+	// Rows of nested tables belong to the nested tables.
+	s.Find("tr").FilterFunction(func(_ int, tr *goquery.Selection) bool {
+		return tr.Closest("table").IsSelection(s)
+	}).Each(func(_ int, tr *goquery.Selection) {
+		row := make([]cell, 0)
+		// fill copies the cells spanning from the rows above into the row.
+		fill := func() {
+			for {
+				sp, ok := spans[len(row)]
+				if !ok {
+					return
+				}
+				row = append(row, sp.cell)
+				if sp.rows--; sp.rows == 0 {
+					delete(spans, len(row)-1)
+				} else {
+					spans[len(row)-1] = sp
+				}
+			}
+		}
+
+		allHeaders := true
+		tr.ChildrenFiltered("td, th").Each(func(_ int, td *goquery.Selection) {
+			fill()
+			c := cell{
+				text:   normalizeSpace(td.Text()),
+				header: goquery.NodeName(td) == "th",
+			}
+			allHeaders = allHeaders && c.header
+			rowspan := spanValue(td, "rowspan")
+			for i := 0; i < spanValue(td, "colspan"); i++ {
+				if rowspan > 1 {
+					spans[len(row)] = span{cell: c, rows: rowspan - 1}
+				}
+				row = append(row, c)
+			}
+		})
+		fill()
+		if len(row) == 0 {
+			return
+		}
+
+		isHeader := tr.ParentFiltered("thead").Length() > 0 || allHeaders
+		if inHeader && isHeader {
+			headerRows++
+		} else {
+			inHeader = false
+		}
+		grid = append(grid, row)
+	})
+
+	// Pad the rows to the table width.
+	w := width(grid)
+	for i := range grid {
+		for len(grid[i]) < w {
+			grid[i] = append(grid[i], cell{})
+		}
+	}
+	return grid, headerRows
+}
+
+// headerNames combines the header rows into unique column names. The texts
+// of the stacked header cells are joined with " / ". Columns without a name
+// are named by their 1-based position.
+func headerNames(headerRows [][]cell, w int) []string {
+	names := make([]string, w)
+	seen := make(map[string]int)
+	for col := 0; col < w; col++ {
+		parts := make([]string, 0, len(headerRows))
+		for _, row := range headerRows {
+			text := row[col].text
+			// Cells spanning several header rows are only named once.
+			if text != "" && (len(parts) == 0 || parts[len(parts)-1] != text) {
+				parts = append(parts, text)
+			}
+		}
+		name := strings.Join(parts, " / ")
+		if name == "" {
+			name = "column" + strconv.Itoa(col+1)
+		}
+		if seen[name]++; seen[name] > 1 {
+			name = fmt.Sprintf("%s_%d", name, seen[name])
+		}
+		names[col] = name
+	}
+	return names
+}
+
+// records returns the rows as objects keyed by the column names.
+func (t table) records() []map[string]string {
+	records := make([]map[string]string, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		record := make(map[string]string, len(row))
+		for i, value := range row {
+			record[t.Headers[i]] = value
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func spanValue(s *goquery.Selection, attr string) int {
+	value, err := strconv.Atoi(strings.TrimSpace(s.AttrOr(attr, "1")))
+	if err != nil || value < 1 {
+		return 1
+	}
+	return min(value, maxSpan)
+}
+
+func width(grid [][]cell) int {
+	w := 0
+	for _, row := range grid {
+		w = max(w, len(row))
+	}
+	return w
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,191 @@
+package tables
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/bazuker/browserbro/pkg/fs"
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/plugins/consent"
+	"github.com/bazuker/browserbro/pkg/tracing"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/stealth"
+)
+
+const (
+	pluginName = "tables"
+)
+
+const (
+	fileFormatCSV  = "csv"
+	fileFormatXLSX = "xlsx"
+)
+
+type Tables struct {
+	browser        *rod.Browser
+	fileStore      fs.FileStore
+	maxTimePerPage time.Duration
+}
+
+func New(browser *rod.Browser, fileStore fs.FileStore, cfg plugins.Config) *Tables {
+	return &Tables{
+		browser:        browser,
+		fileStore:      fileStore,
+		maxTimePerPage: cfg.TimeoutOr(15 * time.Second),
+	}
+}
+
+func (p *Tables) Name() string {
+	return pluginName
+}
+
+func (p *Tables) Run(ctx context.Context, params map[string]any) (output map[string]any, err error) {
+	urlString, ok := params["url"].(string)
+	if !ok || urlString == "" {
+		return nil, plugins.ParamErrorf("'url' parameter must be a non-empty string")
+	}
+	selector := "table"
+	if value, ok := params["selector"]; ok {
+		if selector, ok = value.(string); !ok || selector == "" {
+			return nil, plugins.ParamErrorf("'selector' parameter must be a non-empty string")
+		}
+		// goquery silently matches nothing for invalid selectors.
+		if _, err := cascadia.ParseGroup(selector); err != nil {
+			return nil, plugins.ParamErrorf("'selector' parameter is not a valid CSS selector: %w", err)
+		}
+	}
+	formats, err := parseFileFormats(params)
+	if err != nil {
+		return nil, err
+	}
+	waitStable, ok := params["waitStable"].(bool)
+	if !ok {
+		waitStable = true
+	}
+	dismissConsent, err := consent.ParseParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var stealthPage *rod.Page
+	stealthPage, err = stealth.Page(p.browser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stealth page: %w", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	page := stealthPage.Context(ctx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to complete: %v", r)
+		}
+		err = plugins.TimeLimitError(ctx, err)
+		_ = page.Close()
+	}()
+
+	go func() {
+		time.Sleep(p.maxTimePerPage)
+		cancel(plugins.ErrTimeLimit)
+	}()
+
+	err = tracing.Navigate(page, urlString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to navigate to the page '%s': %w", urlString, err)
+	}
+	err = tracing.WaitLoad(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for page to load: %w", err)
+	}
+	if dismissConsent {
+		consent.Dismiss(page)
+	}
+	if waitStable {
+		err = tracing.WaitStable(page, time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("failed to wait for page to stabilize: %w", err)
+		}
+	}
+
+	content, err := page.HTML()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page content: %w", err)
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse page content: %w", err)
+	}
+	tables := parseTables(doc, selector)
+
+	results := make([]map[string]any, 0, len(tables))
+	for _, t := range tables {
+		result := map[string]any{
+			"index":   t.Index,
+			"caption": t.Caption,
+			"headers": t.Headers,
+			"records": t.records(),
+		}
+		if formats[fileFormatCSV] {
+			data, err := toCSV(t)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode table %d as CSV: %w", t.Index, err)
+			}
+			if result["csv"], err = p.store(ctx, data, fileFormatCSV); err != nil {
+				return nil, err
+			}
+		}
+		results = append(results, result)
+	}
+
+	output = make(map[string]any)
+	output["tables"] = results
+	if formats[fileFormatXLSX] && len(tables) > 0 {
+		data, err := toXLSX(tables)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode tables as XLSX: %w", err)
+		}
+		if output["xlsx"], err = p.store(ctx, data, fileFormatXLSX); err != nil {
+			return nil, err
+		}
+	}
+
+	return output, nil
+}
+
+// store saves the file and returns its name.
+func (p *Tables) store(ctx context.Context, data []byte, format string) (string, error) {
+	filename := helper.NewFileName("tables", format)
+	if err := tracing.PutObject(ctx, p.fileStore, data, filename); err != nil {
+		return "", fmt.Errorf("failed to save %s file: %w", strings.ToUpper(format), err)
+	}
+	return filename, nil
+}
+
+func parseFileFormats(params map[string]any) (map[string]bool, error) {
+	formats := make(map[string]bool)
+	value, ok := params["files"]
+	if !ok {
+		return formats, nil
+	}
+	list, ok := value.([]any)
+	if !ok {
+		return nil, plugins.ParamErrorf("'files' parameter must be an array of strings")
+	}
+	for _, item := range list {
+		format, ok := item.(string)
+		if !ok {
+			return nil, plugins.ParamErrorf("'files' parameter must only contain strings")
+		}
+		format = strings.ToLower(format)
+		if format != fileFormatCSV && format != fileFormatXLSX {
+			return nil, plugins.ParamErrorf("unsupported file format '%s'", format)
+		}
+		formats[format] = true
+	}
+	return formats, nil
+}
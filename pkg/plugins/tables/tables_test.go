@@ -0,0 +1,138 @@
+package tables
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPage = `
+<html><body>
+<table id="releases">
+  <caption>Go releases</caption>
+  <thead>
+    <tr><th rowspan="2">Version</th><th colspan="2">Released</th></tr>
+    <tr><th>Year</th><th>Month</th></tr>
+  </thead>
+  <tbody>
+    <tr><td>1.21</td><td rowspan="2">2023</td><td>August</td></tr>
+    <tr><td>1.22</td><td>February</td></tr>
+    <tr><td colspan="3">Go 2 is <b>not</b> planned</td></tr>
+  </tbody>
+</table>
+<table class="plain">
+  <tr><td>a</td><td>b</td></tr>
+  <tr><td>c</td><td><table><tr><td>nested</td></tr></table></td><td>extra</td></tr>
+</table>
+<div class="table">not a table</div>
+</body></html>`
+
+func parseTestPage(t *testing.T, selector string) []table {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(testPage))
+	require.NoError(t, err)
+	return parseTables(doc, selector)
+}
+
+func TestParseTables(t *testing.T) {
+	tables := parseTestPage(t, "body > table, .table")
+	require.Len(t, tables, 2)
+
+	releases := tables[0]
+	assert.Equal(t, 0, releases.Index)
+	assert.Equal(t, "Go releases", releases.Caption)
+	assert.Equal(t, []string{"Version", "Released / Year", "Released / Month"}, releases.Headers)
+	assert.Equal(t, [][]string{
+		{"1.21", "2023", "August"},
+		{"1.22", "2023", "February"},
+		{"Go 2 is not planned", "Go 2 is not planned", "Go 2 is not planned"},
+	}, releases.Rows)
+	assert.Equal(t, map[string]string{
+		"Version":          "1.22",
+		"Released / Year":  "2023",
+		"Released / Month": "February",
+	}, releases.records()[1])
+
+	plain := tables[1]
+	assert.Equal(t, 1, plain.Index)
+	assert.Equal(t, []string{"column1", "column2", "column3"}, plain.Headers)
+	assert.Equal(t, [][]string{
+		{"a", "b", ""},
+		{"c", "nested", "extra"},
+	}, plain.Rows)
+}
+
+func TestHeaderNames(t *testing.T) {
+	names := headerNames([][]cell{{{text: "Name"}, {text: "Name"}, {text: ""}, {}}}, 4)
+	assert.Equal(t, []string{"Name", "Name_2", "column3", "column4"}, names)
+}
+
+func TestToCSV(t *testing.T) {
+	data, err := toCSV(parseTestPage(t, "#releases")[0])
+	require.NoError(t, err)
+	assert.Equal(t, `Version,Released / Year,Released / Month
+1.21,2023,August
+1.22,2023,February
+Go 2 is not planned,Go 2 is not planned,Go 2 is not planned
+`, string(data))
+}
+
+func TestToXLSX(t *testing.T) {
+	tables := parseTestPage(t, "body > table")
+	tables[1].Caption = "Go releases"
+	data, err := toXLSX(tables)
+	require.NoError(t, err)
+
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		files[f.Name] = string(content)
+	}
+
+	assert.Contains(t, files, "[Content_Types].xml")
+	assert.Contains(t, files, "_rels/.rels")
+	assert.Contains(t, files, "xl/_rels/workbook.xml.rels")
+	assert.Contains(t, files["xl/workbook.xml"], `<sheet name="Go releases" sheetId="1" r:id="rId1"/>`)
+	// Duplicate sheet names fall back to the table number.
+	assert.Contains(t, files["xl/workbook.xml"], `<sheet name="Table 2" sheetId="2" r:id="rId2"/>`)
+	assert.Contains(t, files["xl/worksheets/sheet1.xml"],
+		`<c r="B1" t="inlineStr"><is><t xml:space="preserve">Released / Year</t></is></c>`)
+	assert.Contains(t, files["xl/worksheets/sheet2.xml"],
+		`<c r="C3" t="inlineStr"><is><t xml:space="preserve">extra</t></is></c>`)
+}
+
+func TestSheetName(t *testing.T) {
+	assert.Equal(t, "Table 3", sheetName(table{Index: 2}))
+	assert.Equal(t, "Sales  Q1", sheetName(table{Caption: "Sales: Q1"}))
+	assert.Equal(t, strings.Repeat("a", 31), sheetName(table{Caption: strings.Repeat("a", 40)}))
+}
+
+func TestColumnName(t *testing.T) {
+	assert.Equal(t, "A", columnName(0))
+	assert.Equal(t, "Z", columnName(25))
+	assert.Equal(t, "AA", columnName(26))
+	assert.Equal(t, "AB", columnName(27))
+	assert.Equal(t, "ZZ", columnName(701))
+	assert.Equal(t, "AAA", columnName(702))
+}
+
+func TestParseFileFormats(t *testing.T) {
+	formats, err := parseFileFormats(map[string]any{"files": []any{"CSV", "xlsx"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"csv": true, "xlsx": true}, formats)
+
+	_, err = parseFileFormats(map[string]any{"files": "csv"})
+	assert.EqualError(t, err, "'files' parameter must be an array of strings")
+	_, err = parseFileFormats(map[string]any{"files": []any{"pdf"}})
+	assert.EqualError(t, err, "unsupported file format 'pdf'")
+}
@@ -0,0 +1,114 @@
+package visualdiff
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/fs"
+	"github.com/bazuker/browserbro/pkg/tracing"
+)
+
+var baselineNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// BaselineVersion is a stored version of a baseline screenshot.
+type BaselineVersion struct {
+	Version   int       `json:"version"`
+	File      string    `json:"file"`
+	URL       string    `json:"url,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// baselineManifest lists the versions of a baseline from the oldest to the newest.
+type baselineManifest struct {
+	Name     string            `json:"name"`
+	Versions []BaselineVersion `json:"versions"`
+}
+
+// baselineStore keeps the baseline screenshots and their manifests in the
+// file store. Every version is a separate file, so older versions can still
+// be compared against and downloaded through the files API.
+type baselineStore struct {
+	fileStore fs.FileStore
+	now       func() time.Time
+}
+
+func manifestKey(name string) string {
+	return "visualdiff." + name + ".json"
+}
+
+func versionKey(name string, version int) string {
+	return fmt.Sprintf("visualdiff.%s.v%d.png", name, version)
+}
+
+func (s *baselineStore) manifest(ctx context.Context, name string) (baselineManifest, error) {
+	m := baselineManifest{Name: name, Versions: make([]BaselineVersion, 0)}
+	data, err := tracing.GetObject(ctx, s.fileStore, manifestKey(name))
+	if errors.Is(err, fs.ErrorFileNotFound) {
+		return m, nil
+	}
+	if err != nil {
+		return m, fmt.Errorf("failed to read baseline '%s': %w", name, err)
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("failed to read baseline '%s': %w", name, err)
+	}
+	return m, nil
+}
+
+// get returns the version of the baseline and its image. Version 0 is the
+// latest version. The returned version is nil if the baseline has no versions yet.
+func (s *baselineStore) get(ctx context.Context, name string, version int) (*BaselineVersion, []byte, error) {
+	m, err := s.manifest(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(m.Versions) == 0 {
+		if version != 0 {
+			return nil, nil, fmt.Errorf("baseline '%s' does not exist", name)
+		}
+		return nil, nil, nil
+	}
+
+	v := m.Versions[len(m.Versions)-1]
+	if version != 0 {
+		if version > len(m.Versions) {
+			return nil, nil, fmt.Errorf("baseline '%s' has no version %d", name, version)
+		}
+		v = m.Versions[version-1]
+	}
+	data, err := tracing.GetObject(ctx, s.fileStore, v.File)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read baseline '%s' version %d: %w", name, v.Version, err)
+	}
+	return &v, data, nil
+}
+
+// add stores the image as the new latest version of the baseline.
+func (s *baselineStore) add(ctx context.Context, name, url string, image []byte) (BaselineVersion, error) {
+	m, err := s.manifest(ctx, name)
+	if err != nil {
+		return BaselineVersion{}, err
+	}
+	v := BaselineVersion{
+		Version:   len(m.Versions) + 1,
+		File:      versionKey(name, len(m.Versions)+1),
+		URL:       url,
+		CreatedAt: s.now().UTC(),
+	}
+	if err := tracing.PutObject(ctx, s.fileStore, image, v.File); err != nil {
+		return v, fmt.Errorf("failed to save baseline '%s': %w", name, err)
+	}
+	m.Versions = append(m.Versions, v)
+	data, err := json.Marshal(m)
+	if err != nil {
+		return v, fmt.Errorf("failed to save baseline '%s': %w", name, err)
+	}
+	if err := tracing.PutObject(ctx, s.fileStore, data, manifestKey(name)); err != nil {
+		return v, fmt.Errorf("failed to save baseline '%s': %w", name, err)
+	}
+	return v, nil
+}
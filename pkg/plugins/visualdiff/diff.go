@@ -0,0 +1,177 @@
+package visualdiff
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// regionCellSize is the size of the grid cells mismatched pixels are grouped
+// into when looking for the changed regions.
+const regionCellSize = 16
+
+// maxYIQDelta is the largest possible value of yiqDelta.
+const maxYIQDelta = 35215.0
+
+var (
+	mismatchColor = color.RGBA{R: 255, A: 255}
+	regionColor   = color.RGBA{R: 255, B: 255, A: 255}
+)
+
+// Region is a rectangle containing changes.
+type Region struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Result is the outcome of comparing two images.
+type Result struct {
+	// MismatchedPixels is the number of pixels that differ.
+	MismatchedPixels int
+	// Mismatch is the percentage of the mismatched pixels.
+	Mismatch float64
+	// Regions are the bounding boxes of the changed areas.
+	Regions []Region
+	// Diff is the baseline faded to gray with the mismatched pixels in red
+	// and the regions outlined.
+	Diff *image.RGBA
+}
+
+// compare compares the images pixel by pixel. Two pixels match when their
+// perceived color difference is at most the tolerance between 0 and 1.
+// Images of different sizes are compared over the union of their bounds, the
+// pixels present in only one of the images are mismatched.
+func compare(baseline, current image.Image, tolerance float64) Result {
+	bb := baseline.Bounds()
+	cb := current.Bounds()
+	width := max(bb.Dx(), cb.Dx())
+	height := max(bb.Dy(), cb.Dy())
+
+	diff := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(diff, diff.Bounds(), image.White, image.Point{}, draw.Src)
+
+	cols := (width + regionCellSize - 1) / regionCellSize
+	rows := (height + regionCellSize - 1) / regionCellSize
+	cells := make([]bool, cols*rows)
+
+	maxDelta := maxYIQDelta * tolerance * tolerance
+	mismatched := 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			inBaseline := x < bb.Dx() && y < bb.Dy()
+			inCurrent := x < cb.Dx() && y < cb.Dy()
+			if inBaseline && inCurrent {
+				b := baseline.At(bb.Min.X+x, bb.Min.Y+y)
+				c := current.At(cb.Min.X+x, cb.Min.Y+y)
+				if yiqDelta(b, c) <= maxDelta {
+					diff.Set(x, y, faded(b))
+					continue
+				}
+			}
+			mismatched++
+			diff.Set(x, y, mismatchColor)
+			cells[(y/regionCellSize)*cols+x/regionCellSize] = true
+		}
+	}
+
+	result := Result{
+		MismatchedPixels: mismatched,
+		Regions:          regions(cells, cols, rows, width, height),
+		Diff:             diff,
+	}
+	if total := width * height; total > 0 {
+		result.Mismatch = float64(mismatched) * 100 / float64(total)
+	}
+	for _, r := range result.Regions {
+		outline(diff, r)
+	}
+	return result
+}
+
+// regions groups the adjacent grid cells with mismatched pixels and returns
+// the bounding boxes of the groups.
+func regions(cells []bool, cols, rows, width, height int) []Region {
+	result := make([]Region, 0)
+	visited := make([]bool, len(cells))
+	for start := range cells {
+		if !cells[start] || visited[start] {
+			continue
+		}
+		minCol, minRow := start%cols, start/cols
+		maxCol, maxRow := minCol, minRow
+		stack := []int{start}
+		visited[start] = true
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			col, row := i%cols, i/cols
+			minCol, maxCol = min(minCol, col), max(maxCol, col)
+			minRow, maxRow = min(minRow, row), max(maxRow, row)
+			for _, n := range [][2]int{{col - 1, row}, {col + 1, row}, {col, row - 1}, {col, row + 1}} {
+				if n[0] < 0 || n[0] >= cols || n[1] < 0 || n[1] >= rows {
+					continue
+				}
+				j := n[1]*cols + n[0]
+				if cells[j] && !visited[j] {
+					visited[j] = true
+					stack = append(stack, j)
+				}
+			}
+		}
+		x, y := minCol*regionCellSize, minRow*regionCellSize
+		result = append(result, Region{
+			X:      x,
+			Y:      y,
+			Width:  min((maxCol+1)*regionCellSize, width) - x,
+			Height: min((maxRow+1)*regionCellSize, height) - y,
+		})
+	}
+	return result
+}
+
+// outline draws the border of the region.
+func outline(img *image.RGBA, r Region) {
+	for x := r.X; x < r.X+r.Width; x++ {
+		img.Set(x, r.Y, regionColor)
+		img.Set(x, r.Y+r.Height-1, regionColor)
+	}
+	for y := r.Y; y < r.Y+r.Height; y++ {
+		img.Set(r.X, y, regionColor)
+		img.Set(r.X+r.Width-1, y, regionColor)
+	}
+}
+
+// yiqDelta returns the squared perceived difference of the colors in the YIQ
+// color space, blended over white.
+func yiqDelta(a, b color.Color) float64 {
+	ar, ag, ab := blend(a)
+	br, bg, bb := blend(b)
+	y := rgbToY(ar, ag, ab) - rgbToY(br, bg, bb)
+	i := rgbToI(ar, ag, ab) - rgbToI(br, bg, bb)
+	q := rgbToQ(ar, ag, ab) - rgbToQ(br, bg, bb)
+	return 0.5053*y*y + 0.299*i*i + 0.1957*q*q
+}
+
+// blend returns the 8-bit color components blended over white.
+func blend(c color.Color) (float64, float64, float64) {
+	r, g, b, a := c.RGBA()
+	alpha := float64(a) / 0xffff
+	component := func(v uint32) float64 {
+		// The components are alpha-premultiplied.
+		return float64(v)/0xffff*255 + 255*(1-alpha)
+	}
+	return component(r), component(g), component(b)
+}
+
+func rgbToY(r, g, b float64) float64 { return r*0.29889531 + g*0.58662247 + b*0.11448223 }
+func rgbToI(r, g, b float64) float64 { return r*0.59597799 - g*0.27417610 - b*0.32180189 }
+func rgbToQ(r, g, b float64) float64 { return r*0.21147017 - g*0.52261711 + b*0.31114694 }
+
+// faded returns the pale gray version of the color used for the unchanged pixels.
+func faded(c color.Color) color.Color {
+	r, g, b := blend(c)
+	y := 255 - (255-rgbToY(r, g, b))*0.1
+	return color.Gray{Y: uint8(y)}
+}
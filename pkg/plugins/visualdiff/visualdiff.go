@@ -0,0 +1,342 @@
+package visualdiff
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/png"
+	"math"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/fs"
+	"github.com/bazuker/browserbro/pkg/manager/auth"
+	"github.com/bazuker/browserbro/pkg/manager/helper"
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/bazuker/browserbro/pkg/plugins/consent"
+	"github.com/bazuker/browserbro/pkg/tracing"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/go-rod/stealth"
+)
+
+const (
+	pluginName = "visualdiff"
+)
+
+type VisualDiff struct {
+	browser        *rod.Browser
+	fileStore      fs.FileStore
+	baselines      *baselineStore
+	maxTimePerPage time.Duration
+}
+
+func New(browser *rod.Browser, fileStore fs.FileStore, cfg plugins.Config) *VisualDiff {
+	return &VisualDiff{
+		browser:        browser,
+		fileStore:      fileStore,
+		baselines:      &baselineStore{fileStore: fileStore, now: time.Now},
+		maxTimePerPage: cfg.TimeoutOr(30 * time.Second),
+	}
+}
+
+func (p *VisualDiff) Name() string {
+	return pluginName
+}
+
+// Scopes requires the scope to read the files when two stored files are compared.
+func (p *VisualDiff) Scopes(params map[string]any) []string {
+	if _, ok := params["files"]; ok {
+		return []string{auth.ScopeFilesRead}
+	}
+	return nil
+}
+
+// options are the parameters of a comparison.
+type options struct {
+	// url and baseline are set when a page is compared against a stored baseline.
+	url      string
+	baseline string
+	version  int
+	update   bool
+	// files are the file IDs of the baseline and the current image when two
+	// stored images are compared.
+	files []string
+
+	threshold      float64
+	tolerance      float64
+	fullPage       bool
+	width          int
+	height         int
+	waitStable     bool
+	dismissConsent bool
+}
+
+func (p *VisualDiff) Run(ctx context.Context, params map[string]any) (output map[string]any, err error) {
+	opts, err := parseOptions(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(opts.files) > 0 {
+		baselineImage, err := tracing.GetObject(ctx, p.fileStore, opts.files[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file '%s': %w", opts.files[0], err)
+		}
+		currentImage, err := tracing.GetObject(ctx, p.fileStore, opts.files[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file '%s': %w", opts.files[1], err)
+		}
+		output, err = p.compare(ctx, baselineImage, currentImage, opts)
+		if err != nil {
+			return nil, err
+		}
+		output["baseline"] = map[string]any{"file": opts.files[0]}
+		output["current"] = opts.files[1]
+		return output, nil
+	}
+
+	currentImage, err := p.capture(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	baseline, baselineImage, err := p.baselines.get(ctx, opts.baseline, opts.version)
+	if err != nil {
+		return nil, err
+	}
+	if baseline == nil {
+		// The first capture becomes the baseline.
+		created, err := p.baselines.add(ctx, opts.baseline, opts.url, currentImage)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{
+			"passed":   true,
+			"created":  true,
+			"baseline": created,
+		}, nil
+	}
+
+	output, err = p.compare(ctx, baselineImage, currentImage, opts)
+	if err != nil {
+		return nil, err
+	}
+	output["baseline"] = baseline
+	if output["current"], err = p.store(ctx, currentImage, "current"); err != nil {
+		return nil, err
+	}
+	if opts.update {
+		updated, err := p.baselines.add(ctx, opts.baseline, opts.url, currentImage)
+		if err != nil {
+			return nil, err
+		}
+		output["updatedBaseline"] = updated
+	}
+
+	return output, nil
+}
+
+// capture takes a screenshot of the page.
+func (p *VisualDiff) capture(ctx context.Context, opts options) (screenshot []byte, err error) {
+	var stealthPage *rod.Page
+	stealthPage, err = stealth.Page(p.browser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stealth page: %w", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	page := stealthPage.Context(ctx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to complete: %v", r)
+		}
+		err = plugins.TimeLimitError(ctx, err)
+		_ = page.Close()
+	}()
+
+	go func() {
+		time.Sleep(p.maxTimePerPage)
+		cancel(plugins.ErrTimeLimit)
+	}()
+
+	err = page.SetViewport(&proto.EmulationSetDeviceMetricsOverride{
+		Width:             opts.width,
+		Height:            opts.height,
+		DeviceScaleFactor: 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set viewport: %w", err)
+	}
+	err = tracing.Navigate(page, opts.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to navigate to the page '%s': %w", opts.url, err)
+	}
+	err = tracing.WaitLoad(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for page to load: %w", err)
+	}
+	if opts.dismissConsent {
+		consent.Dismiss(page)
+	}
+	if opts.waitStable {
+		err = tracing.WaitStable(page, time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("failed to wait for page to stabilize: %w", err)
+		}
+	}
+
+	err = tracing.Screenshot(page, func() error {
+		screenshot, err = page.Screenshot(opts.fullPage, &proto.PageCaptureScreenshot{
+			Format: proto.PageCaptureScreenshotFormatPng,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to take screenshot: %w", err)
+	}
+	return screenshot, nil
+}
+
+// compare compares the PNG images and saves the diff image.
+func (p *VisualDiff) compare(ctx context.Context, baselineImage, currentImage []byte, opts options) (map[string]any, error) {
+	baseline, err := png.Decode(bytes.NewReader(baselineImage))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode baseline image: %w", err)
+	}
+	current, err := png.Decode(bytes.NewReader(currentImage))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode current image: %w", err)
+	}
+
+	result := compare(baseline, current, opts.tolerance)
+
+	var diff bytes.Buffer
+	if err := png.Encode(&diff, result.Diff); err != nil {
+		return nil, fmt.Errorf("failed to encode diff image: %w", err)
+	}
+	diffFile, err := p.store(ctx, diff.Bytes(), "diff")
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"passed":           result.Mismatch <= opts.threshold,
+		"mismatch":         math.Round(result.Mismatch*10000) / 10000,
+		"mismatchedPixels": result.MismatchedPixels,
+		"threshold":        opts.threshold,
+		"regions":          result.Regions,
+		"sizeChanged":      baseline.Bounds().Size() != current.Bounds().Size(),
+		"diff":             diffFile,
+	}, nil
+}
+
+// store saves the image and returns its file name.
+func (p *VisualDiff) store(ctx context.Context, data []byte, kind string) (string, error) {
+	filename := helper.NewFileName("visualdiff", kind, "png")
+	if err := tracing.PutObject(ctx, p.fileStore, data, filename); err != nil {
+		return "", fmt.Errorf("failed to save %s image: %w", kind, err)
+	}
+	return filename, nil
+}
+
+func parseOptions(params map[string]any) (options, error) {
+	opts := options{
+		tolerance:  0.1,
+		fullPage:   true,
+		width:      1280,
+		height:     800,
+		waitStable: true,
+	}
+
+	_, hasURL := params["url"]
+	_, hasFiles := params["files"]
+	switch {
+	case hasURL && hasFiles:
+		return opts, plugins.ParamErrorf("'url' and 'files' parameters cannot be used together")
+	case hasFiles:
+		files, ok := params["files"].([]any)
+		if !ok || len(files) != 2 {
+			return opts, plugins.ParamErrorf("'files' parameter must be an array of two file IDs")
+		}
+		for _, file := range files {
+			id, ok := file.(string)
+			if !ok || !helper.IsFileName(id) {
+				return opts, plugins.ParamErrorf("'files' parameter must be an array of two file IDs")
+			}
+			opts.files = append(opts.files, id)
+		}
+	case hasURL:
+		var ok bool
+		if opts.url, ok = params["url"].(string); !ok || opts.url == "" {
+			return opts, plugins.ParamErrorf("'url' parameter must be a non-empty string")
+		}
+		if opts.baseline, ok = params["baseline"].(string); !ok || !baselineNamePattern.MatchString(opts.baseline) {
+			return opts, plugins.ParamErrorf("'baseline' parameter must be a name of up to 64 letters, digits, '-' and '_'")
+		}
+		version, err := parseInteger(params, "version", 0, 1, math.MaxInt32)
+		if err != nil {
+			return opts, err
+		}
+		opts.version = version
+		if value, ok := params["updateBaseline"]; ok {
+			if opts.update, ok = value.(bool); !ok {
+				return opts, plugins.ParamErrorf("'updateBaseline' parameter must be a boolean")
+			}
+		}
+	default:
+		return opts, plugins.ParamErrorf("either 'url' or 'files' parameter is required")
+	}
+
+	var err error
+	if opts.threshold, err = parseNumber(params, "threshold", 0, 0, 100); err != nil {
+		return opts, err
+	}
+	if opts.tolerance, err = parseNumber(params, "tolerance", opts.tolerance, 0, 1); err != nil {
+		return opts, err
+	}
+	if opts.width, err = parseInteger(params, "width", opts.width, 1, 10000); err != nil {
+		return opts, err
+	}
+	if opts.height, err = parseInteger(params, "height", opts.height, 1, 10000); err != nil {
+		return opts, err
+	}
+	if value, ok := params["fullPage"]; ok {
+		if opts.fullPage, ok = value.(bool); !ok {
+			return opts, plugins.ParamErrorf("'fullPage' parameter must be a boolean")
+		}
+	}
+	if value, ok := params["waitStable"]; ok {
+		if opts.waitStable, ok = value.(bool); !ok {
+			return opts, plugins.ParamErrorf("'waitStable' parameter must be a boolean")
+		}
+	}
+	if opts.dismissConsent, err = consent.ParseParam(params); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+func parseNumber(params map[string]any, name string, defaultValue, minValue, maxValue float64) (float64, error) {
+	value, ok := params[name]
+	if !ok {
+		return defaultValue, nil
+	}
+	number, ok := value.(float64)
+	if !ok || number < minValue || number > maxValue {
+		return 0, plugins.ParamErrorf("'%s' parameter must be a number between %v and %v", name, minValue, maxValue)
+	}
+	return number, nil
+}
+
+func parseInteger(params map[string]any, name string, defaultValue, minValue, maxValue int) (int, error) {
+	value, ok := params[name]
+	if !ok {
+		return defaultValue, nil
+	}
+	number, ok := value.(float64)
+	if !ok || number != math.Trunc(number) || number < float64(minValue) || number > float64(maxValue) {
+		return 0, plugins.ParamErrorf("'%s' parameter must be an integer between %d and %d", name, minValue, maxValue)
+	}
+	return int(number), nil
+}
@@ -0,0 +1,173 @@
+package visualdiff
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/bazuker/browserbro/pkg/fs/local"
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testImage(width, height int, changes ...image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	for _, r := range changes {
+		draw.Draw(img, r, image.NewUniform(color.RGBA{B: 200, A: 255}), image.Point{}, draw.Src)
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestCompare(t *testing.T) {
+	t.Run("identical images", func(t *testing.T) {
+		result := compare(testImage(64, 64), testImage(64, 64), 0.1)
+		assert.Zero(t, result.MismatchedPixels)
+		assert.Zero(t, result.Mismatch)
+		assert.Empty(t, result.Regions)
+	})
+
+	t.Run("changed regions", func(t *testing.T) {
+		current := testImage(64, 64, image.Rect(2, 2, 10, 10), image.Rect(40, 40, 50, 60))
+		result := compare(testImage(64, 64), current, 0.1)
+		assert.Equal(t, 64+200, result.MismatchedPixels)
+		assert.InDelta(t, 6.4453, result.Mismatch, 0.0001)
+		assert.Equal(t, []Region{
+			{X: 0, Y: 0, Width: 16, Height: 16},
+			{X: 32, Y: 32, Width: 32, Height: 32},
+		}, result.Regions)
+		assert.Equal(t, mismatchColor, result.Diff.At(5, 5))
+		assert.Equal(t, regionColor, result.Diff.At(0, 0))
+		assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, result.Diff.At(20, 5))
+	})
+
+	t.Run("tolerance", func(t *testing.T) {
+		current := testImage(8, 8)
+		current.Set(1, 1, color.RGBA{R: 250, G: 250, B: 250, A: 255})
+		assert.Zero(t, compare(testImage(8, 8), current, 0.1).MismatchedPixels)
+		assert.Equal(t, 1, compare(testImage(8, 8), current, 0).MismatchedPixels)
+	})
+
+	t.Run("different sizes", func(t *testing.T) {
+		result := compare(testImage(10, 10), testImage(10, 20), 0.1)
+		assert.Equal(t, 100, result.MismatchedPixels)
+		assert.Equal(t, 50.0, result.Mismatch)
+		assert.Equal(t, image.Rect(0, 0, 10, 20), result.Diff.Bounds())
+	})
+}
+
+func TestBaselineStore(t *testing.T) {
+	fileStore, err := local.New(local.Config{BasePath: t.TempDir()})
+	require.NoError(t, err)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := &baselineStore{fileStore: fileStore, now: func() time.Time { return now }}
+
+	v, data, err := store.get(context.Background(), "home", 0)
+	require.NoError(t, err)
+	assert.Nil(t, v)
+	assert.Nil(t, data)
+	_, _, err = store.get(context.Background(), "home", 1)
+	assert.EqualError(t, err, "baseline 'home' does not exist")
+
+	first, err := store.add(context.Background(), "home", "https://go.dev/", []byte("v1"))
+	require.NoError(t, err)
+	assert.Equal(t, BaselineVersion{Version: 1, File: "visualdiff.home.v1.png", URL: "https://go.dev/", CreatedAt: now}, first)
+	second, err := store.add(context.Background(), "home", "https://go.dev/", []byte("v2"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, second.Version)
+
+	v, data, err = store.get(context.Background(), "home", 0)
+	require.NoError(t, err)
+	assert.Equal(t, second, *v)
+	assert.Equal(t, []byte("v2"), data)
+
+	v, data, err = store.get(context.Background(), "home", 1)
+	require.NoError(t, err)
+	assert.Equal(t, first, *v)
+	assert.Equal(t, []byte("v1"), data)
+
+	_, _, err = store.get(context.Background(), "home", 3)
+	assert.EqualError(t, err, "baseline 'home' has no version 3")
+}
+
+func TestVisualDiff_Run_files(t *testing.T) {
+	fileStore, err := local.New(local.Config{BasePath: t.TempDir()})
+	require.NoError(t, err)
+	require.NoError(t, fileStore.PutObject(encodePNG(t, testImage(32, 32)), "Xk3_a9Qz.screenshot.png"))
+	require.NoError(t, fileStore.PutObject(encodePNG(t, testImage(32, 32, image.Rect(0, 0, 4, 4))), "Yk3_a9Qz.screenshot.png"))
+	p := New(nil, fileStore, plugins.Config{})
+
+	output, err := p.Run(context.Background(), map[string]any{
+		"files":     []any{"Xk3_a9Qz.screenshot.png", "Yk3_a9Qz.screenshot.png"},
+		"threshold": 1.0,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, false, output["passed"])
+	assert.Equal(t, 1.5625, output["mismatch"])
+	assert.Equal(t, 16, output["mismatchedPixels"])
+	assert.Equal(t, false, output["sizeChanged"])
+	assert.Equal(t, []Region{{X: 0, Y: 0, Width: 16, Height: 16}}, output["regions"])
+	assert.Equal(t, map[string]any{"file": "Xk3_a9Qz.screenshot.png"}, output["baseline"])
+
+	diff, err := fileStore.GetObject(output["diff"].(string))
+	require.NoError(t, err)
+	_, err = png.Decode(bytes.NewReader(diff))
+	require.NoError(t, err)
+
+	_, err = p.Run(context.Background(), map[string]any{"files": []any{"Xk3_a9Qz.screenshot.png", "Zk3_a9Qz.screenshot.png"}})
+	assert.ErrorContains(t, err, "failed to read file 'Zk3_a9Qz.screenshot.png'")
+}
+
+func TestParseOptions(t *testing.T) {
+	opts, err := parseOptions(map[string]any{"url": "https://go.dev/", "baseline": "home"})
+	require.NoError(t, err)
+	assert.Equal(t, options{
+		url:        "https://go.dev/",
+		baseline:   "home",
+		tolerance:  0.1,
+		fullPage:   true,
+		width:      1280,
+		height:     800,
+		waitStable: true,
+	}, opts)
+
+	files := []any{"Xk3_a9Qz.screenshot.png", "Yk3_a9Qz.screenshot.png"}
+	tests := []struct {
+		params map[string]any
+		err    string
+	}{
+		{map[string]any{}, "either 'url' or 'files' parameter is required"},
+		{map[string]any{"url": "https://go.dev/", "files": files}, "'url' and 'files' parameters cannot be used together"},
+		{map[string]any{"files": []any{"a"}}, "'files' parameter must be an array of two file IDs"},
+		{map[string]any{"files": []any{"Xk3_a9Qz.screenshot.png", "../Yk3_a9Qz.screenshot.png"}}, "'files' parameter must be an array of two file IDs"},
+		{map[string]any{"files": []any{"Xk3_a9Qz.screenshot.png", ".state.pipelines.json"}}, "'files' parameter must be an array of two file IDs"},
+		{map[string]any{"url": "https://go.dev/"}, "'baseline' parameter must be a name of up to 64 letters, digits, '-' and '_'"},
+		{map[string]any{"url": "https://go.dev/", "baseline": "../etc"}, "'baseline' parameter must be a name of up to 64 letters, digits, '-' and '_'"},
+		{map[string]any{"url": "https://go.dev/", "baseline": "home", "version": 1.5}, "'version' parameter must be an integer between 1 and 2147483647"},
+		{map[string]any{"files": files, "threshold": 101.0}, "'threshold' parameter must be a number between 0 and 100"},
+		{map[string]any{"files": files, "tolerance": -1.0}, "'tolerance' parameter must be a number between 0 and 1"},
+		{map[string]any{"files": files, "fullPage": "yes"}, "'fullPage' parameter must be a boolean"},
+	}
+	for _, tt := range tests {
+		_, err := parseOptions(tt.params)
+		assert.EqualError(t, err, tt.err)
+	}
+}
+
+func TestVisualDiff_Scopes(t *testing.T) {
+	p := New(nil, nil, plugins.Config{})
+	assert.Equal(t, []string{"files:read"}, p.Scopes(map[string]any{"files": []any{}}))
+	assert.Empty(t, p.Scopes(map[string]any{"url": "https://go.dev/"}))
+}
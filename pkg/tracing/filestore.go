@@ -0,0 +1,24 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/bazuker/browserbro/pkg/fs"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PutObject saves the object to the file store in a "filestore.put" span.
+func PutObject(ctx context.Context, store fs.FileStore, object []byte, key string) error {
+	return Step(ctx, "filestore.put", func() error {
+		return store.PutObject(object, key)
+	}, attribute.String("filestore.key", key), attribute.Int("filestore.size", len(object)))
+}
+
+// GetObject reads the object from the file store in a "filestore.get" span.
+func GetObject(ctx context.Context, store fs.FileStore, key string) (object []byte, err error) {
+	err = Step(ctx, "filestore.get", func() error {
+		object, err = store.GetObject(key)
+		return err
+	}, attribute.String("filestore.key", key))
+	return object, err
+}
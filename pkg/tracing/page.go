@@ -0,0 +1,35 @@
+package tracing
+
+import (
+	"time"
+
+	"github.com/go-rod/rod"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// The page steps are children of the span in the context of the page, which
+// the plugins derive from the context of the run.
+
+// Navigate opens the URL in the page in a "page.navigate" span.
+func Navigate(page *rod.Page, url string) error {
+	return Step(page.GetContext(), "page.navigate", func() error {
+		return page.Navigate(url)
+	}, attribute.String("url.full", url))
+}
+
+// WaitLoad waits for the page to load in a "page.wait_load" span.
+func WaitLoad(page *rod.Page) error {
+	return Step(page.GetContext(), "page.wait_load", page.WaitLoad)
+}
+
+// WaitStable waits for the page to stabilize in a "page.wait_stable" span.
+func WaitStable(page *rod.Page, d time.Duration) error {
+	return Step(page.GetContext(), "page.wait_stable", func() error {
+		return page.WaitStable(d)
+	})
+}
+
+// Screenshot takes a screenshot with fn in a "page.screenshot" span.
+func Screenshot(page *rod.Page, fn func() error) error {
+	return Step(page.GetContext(), "page.screenshot", fn)
+}
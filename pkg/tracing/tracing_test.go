@@ -0,0 +1,118 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bazuker/browserbro/pkg/fs/mock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// record installs a tracer provider that records the ended spans.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	shutdown, err := Setup(context.Background(), Config{})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), Config{Exporter: "jaeger"})
+	assert.EqualError(t, err, "unknown tracing exporter 'jaeger'")
+
+	var out bytes.Buffer
+	shutdown, err = Setup(context.Background(), Config{Exporter: ExporterStdout, Writer: &out})
+	require.NoError(t, err)
+	_, span := Start(context.Background(), "test span")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+	assert.Contains(t, out.String(), `"Name":"test span"`)
+	assert.Contains(t, out.String(), `"Value":"browserbro"`)
+}
+
+func TestStep(t *testing.T) {
+	recorder := record(t)
+	ctx, parent := Start(context.Background(), "parent")
+
+	require.NoError(t, Step(ctx, "ok", func() error { return nil }, attribute.String("key", "value")))
+	assert.EqualError(t, Step(ctx, "failed", func() error { return errors.New("boom") }), "boom")
+	assert.PanicsWithValue(t, "crash", func() {
+		_ = Step(ctx, "panicked", func() error { panic("crash") })
+	})
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	for _, span := range spans[:3] {
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+	assert.Equal(t, "ok", spans[0].Name())
+	assert.Equal(t, []attribute.KeyValue{attribute.String("key", "value")}, spans[0].Attributes())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "boom", spans[1].Status().Description)
+	assert.Equal(t, codes.Error, spans[2].Status().Code)
+}
+
+func TestPutObject(t *testing.T) {
+	recorder := record(t)
+	store := &mock.FileStore{
+		PutObjectFn: func(object []byte, key string) error { return nil },
+	}
+	require.NoError(t, PutObject(context.Background(), store, []byte("data"), "a.txt"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "filestore.put", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("filestore.key", "a.txt"))
+	assert.Contains(t, spans[0].Attributes(), attribute.Int("filestore.size", 4))
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := record(t)
+	previous := otel.GetTextMapPropagator()
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+	_, err := Setup(context.Background(), Config{})
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.Use(Middleware())
+	r.POST("/api/v1/plugins/:name", func(c *gin.Context) {
+		_, span := Start(c.Request.Context(), "plugin")
+		span.End()
+		c.Status(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/plugins/test", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	plugin, server := spans[0], spans[1]
+	assert.Equal(t, "POST /api/v1/plugins/:name", server.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, server.SpanContext().SpanID(), plugin.Parent().SpanID())
+	assert.Contains(t, server.Attributes(), attribute.Int("http.response.status_code", 500))
+	assert.Equal(t, codes.Error, server.Status().Code)
+}
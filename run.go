@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/bazuker/browserbro/pkg/cli"
+	"github.com/bazuker/browserbro/pkg/config"
+	localFS "github.com/bazuker/browserbro/pkg/fs/local"
+	"github.com/bazuker/browserbro/pkg/manager"
+	"github.com/bazuker/browserbro/pkg/plugins"
+	"github.com/go-rod/rod"
+)
+
+// paramsFlags are the flags of the plugin params shared by run and call.
+type paramsFlags struct {
+	params cli.Params
+	json   string
+}
+
+func registerParamsFlags(fs *flag.FlagSet) *paramsFlags {
+	p := &paramsFlags{params: make(cli.Params)}
+	fs.Var(p.params, "param",
+		"a plugin param as key=value, the value is decoded as JSON if valid, e.g. pages=2 (repeatable)")
+	fs.StringVar(&p.json, "params", "",
+		"the plugin params as a JSON object, @path reads them from a file and @- from the standard input")
+	return p
+}
+
+// Params returns the params of the JSON object with the key=value params over them.
+func (p *paramsFlags) Params() (cli.Params, error) {
+	if p.json == "" {
+		return p.params, nil
+	}
+	params, err := cli.ReadParams(p.json)
+	if err != nil {
+		return nil, err
+	}
+	return params.Merge(p.params), nil
+}
+
+// pluginArg splits the plugin name from the flags of the command.
+func pluginArg(fs *flag.FlagSet, args []string) (string, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help" || args[0] == "-help") {
+			fs.Usage()
+			os.Exit(0)
+		}
+		return "", nil, errors.New("plugin name is required")
+	}
+	return args[0], args[1:], nil
+}
+
+// run runs a plugin directly against the browser service and writes its
+// output as JSON to the standard output. The files of the plugin are written
+// to the output directory.
+func run(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fs.Usage = commandUsage(fs, "run <plugin> [flags]",
+		"Runs a plugin against the browser without the API server and prints its output as JSON.\n"+
+			"The configuration flags, environment variables and config file of the server apply.")
+	flags := config.RegisterFlags(fs)
+	paramsFlags := registerParamsFlags(fs)
+	outDir := fs.String("out", ".", "the directory to write the files of the plugin to")
+
+	pluginName, args, err := pluginArg(fs, args)
+	if err != nil {
+		return err
+	}
+	_ = fs.Parse(args)
+	params, err := paramsFlags.Params()
+	if err != nil {
+		return err
+	}
+	cfg, ok, err := loadConfig(flags)
+	if err != nil || !ok {
+		return err
+	}
+
+	fileStore, err := localFS.New(localFS.Config{BasePath: *outDir})
+	if err != nil {
+		return fmt.Errorf("failed to initialize file store: %w", err)
+	}
+	browser := rod.New()
+	var plugin plugins.Plugin
+	for _, p := range initPlugins(browser, fileStore, cfg.Plugins) {
+		if p.Name() == pluginName {
+			plugin = p
+			break
+		}
+	}
+	if plugin == nil {
+		return fmt.Errorf("plugin '%s' does not exist or is disabled", pluginName)
+	}
+
+	managerCfg := cfg.Manager()
+	managerCfg.Browser = browser
+	// The monitor would outlive the run.
+	managerCfg.BrowserMonitorEnabled = false
+	closeBrowser, err := manager.ConnectBrowser(managerCfg)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = closeBrowser() }()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	output, err := plugin.Run(ctx, params)
+	if err != nil {
+		return err
+	}
+	return cli.WriteJSON(os.Stdout, pluginName, output)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/bazuker/browserbro/pkg/config"
+	localFS "github.com/bazuker/browserbro/pkg/fs/local"
+	"github.com/bazuker/browserbro/pkg/manager"
+	"github.com/bazuker/browserbro/pkg/manager/metrics"
+	"github.com/bazuker/browserbro/pkg/tracing"
+	"github.com/gin-gonic/gin"
+	"github.com/go-rod/rod"
+	"github.com/rs/zerolog/log"
+)
+
+// serve runs the API server until it receives a termination signal.
+func serve(args []string) {
+	gin.SetMode(gin.ReleaseMode)
+
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.Usage = commandUsage(fs, "serve [flags]", "Runs the API server.")
+	flags := config.RegisterFlags(fs)
+	_ = fs.Parse(args)
+	cfg, ok, err := loadConfig(flags)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to load configuration")
+		return
+	}
+	if !ok {
+		return
+	}
+
+	localStore, err := localFS.New(localFS.Config{
+		BasePath: cfg.FileStore.BasePath,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to initialize file store")
+		return
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter: cfg.Tracing.Exporter,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to initialize tracing")
+		return
+	}
+
+	serverMetrics := metrics.New()
+	fileStore := serverMetrics.FileStore(localStore)
+
+	apiKeys, err := cfg.Auth.Keys()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to load API keys")
+		return
+	}
+
+	browser := rod.New()
+	allPlugins := initPlugins(browser, fileStore, cfg.Plugins)
+	managerCfg := cfg.Manager()
+	managerCfg.FileStore = fileStore
+	managerCfg.Browser = browser
+	managerCfg.Plugins = allPlugins
+	managerCfg.APIKeys = apiKeys
+	managerCfg.Metrics = serverMetrics
+	m, err := manager.New(managerCfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to initialize manager")
+		return
+	}
+
+	log.Info().Msg("running API server on " + cfg.Server.Address)
+
+	if err := m.Run(); err != nil {
+		log.Fatal().Err(err).Msg("error running the API server")
+	}
+
+	quit := make(chan os.Signal, 1)
+	// Signal notification for Interrupt (Ctrl+C) and SIGTERM (Termination signal from the OS)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Info().Msg("shutting down the API server")
+	if err := m.Stop(); err != nil {
+		log.Error().Err(err).Msg("error stopping the API server")
+	}
+	if err := shutdownTracing(context.Background()); err != nil {
+		log.Error().Err(err).Msg("failed to flush traces")
+	}
+}